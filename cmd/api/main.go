@@ -19,6 +19,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// narrativeCacheStatsInterval es cada cuanto se registran los aciertos/fallos del cache narrativo.
+const narrativeCacheStatsInterval = 15 * time.Minute
+
 func main() {
 	ctx := context.Background()
 
@@ -47,14 +50,45 @@ func main() {
 	traitRepo := repository.NewPgTraitRepository(pool)
 	characterRepo := repository.NewPgCharacterRepository(pool)
 	memoryRepo := repository.NewPgMemoryRepository(pool)
-	llmClient := llm.NewHTTPClient(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModel, logger)
-	analysisSvc := service.NewAnalysisService(llmClient, traitRepo, profileRepo, logger)
+	relationshipEventRepo := repository.NewPgRelationshipEventRepository(pool)
+	characterAliasRepo := repository.NewPgCharacterAliasRepository(pool)
+	factRepo := repository.NewPgFactRepository(pool)
+	providerCfg := cfg.LLMProviderConfig()
+	llmRouter, err := llm.NewRouter(providerCfg, cfg.LLMRoles(), logger)
+	if err != nil {
+		logger.Fatal("llm client", zap.Error(err))
+	}
+	embeddingSpec := service.EmbeddingSpec{Model: providerCfg.EmbeddingModelName(), Dimension: cfg.EmbeddingDimensions}
+	memoryRepo.SetEmbeddingModel(embeddingSpec.Model)
+	staleEmbeddings, err := service.CheckEmbeddingStore(ctx, memoryRepo, embeddingSpec)
+	if err != nil {
+		logger.Fatal("embedding store", zap.Error(err))
+	}
+	if staleEmbeddings > 0 {
+		logger.Warn("memories embedded with another model, run cmd/reembed", zap.Int("memories", staleEmbeddings), zap.String("model", embeddingSpec.Model))
+	}
+	analysisSvc := service.NewAnalysisService(llmRouter.For(llm.RoleAnalysis), traitRepo, profileRepo, logger)
+	factSvc := service.NewFactService(factRepo, logger)
+	analysisSvc.SetFactService(factSvc)
+	analysisSvc.SetSessionRepository(sessionRepo)
 	contextSvc := service.NewBasicContextService(messageRepo)
-	narrativeSvc := service.NewNarrativeService(characterRepo, memoryRepo, llmClient)
+	narrativeSvc := service.NewNarrativeService(characterRepo, memoryRepo, llmRouter.Base())
+	narrativeSvc.SetDedupThreshold(cfg.MemoryDedupThreshold)
+	narrativeSvc.SetEvocationClient(llmRouter.For(llm.RoleEvocation))
+	narrativeSvc.SetJudgeClient(llmRouter.For(llm.RoleJudge))
 	promptBuilder := service.ClonePromptBuilder{}
 	responseParser := service.LLMResponseParser{}
 	reactionEngine := service.ReactionEngine{}
-	cloneSvc := service.NewCloneService(llmClient, messageRepo, profileRepo, traitRepo, contextSvc, narrativeSvc, analysisSvc, promptBuilder, responseParser, reactionEngine)
+	cloneSvc := service.NewCloneService(llmRouter.For(llm.RoleReply), messageRepo, profileRepo, traitRepo, contextSvc, narrativeSvc, analysisSvc, promptBuilder, responseParser, reactionEngine)
+	cloneSvc.SetRelationshipService(service.NewRelationshipService(characterRepo, sessionRepo, relationshipEventRepo))
+	cloneSvc.SetSessionRepository(sessionRepo)
+	cloneSvc.SetFactService(factSvc)
+	sessionSvc := service.NewSessionService(sessionRepo, logger)
+	consolidationSvc := service.NewConsolidationService(llmRouter.For(llm.RoleConsolidation), messageRepo, memoryRepo, sessionRepo, logger)
+	consolidationSvc.SetFactService(factSvc)
+	consolidationSvc.SetProfileRepository(profileRepo)
+	sessionSvc.OnSessionEnd(consolidationSvc.Hook())
+	go sessionSvc.RunIdleSweeper(ctx, time.Duration(cfg.SessionIdleMinutes)*time.Minute, time.Minute)
 	emailSender := email.NewDisabledSender("email sender not configured")
 	if cfg.SMTPHost != "" {
 		sender, err := email.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPass, cfg.SMTPFrom, cfg.SMTPFromName, cfg.SMTPUseTLS)
@@ -65,9 +99,12 @@ func main() {
 		}
 	}
 	var (
-		otpLimiter   service.OTPRateLimiter
-		tokenStore   service.RefreshTokenStore
+		otpLimiter  service.OTPRateLimiter
+		tokenStore  service.RefreshTokenStore
 		redisClient *redis.Client
+
+		narrativeCache    service.NarrativeCache
+		narrativeCacheTTL = time.Duration(cfg.NarrativeCacheTTLMinutes) * time.Minute
 	)
 	if cfg.RedisAddr != "" {
 		redisClient = redis.NewClient(&redis.Options{
@@ -81,9 +118,33 @@ func main() {
 		} else {
 			otpLimiter = service.NewRedisOTPRateLimiter(redisClient, 10*time.Minute, 3)
 			tokenStore = service.NewRedisRefreshTokenStore(redisClient)
+			narrativeCache = service.NewRedisNarrativeCache(redisClient, narrativeCacheTTL)
 		}
 		cancel()
 	}
+	if narrativeCache == nil {
+		narrativeCache = service.NewMemoryNarrativeCache(cfg.NarrativeCacheSize, narrativeCacheTTL)
+	}
+	narrativeSvc.SetCache(narrativeCache)
+	go service.LogNarrativeCacheStats(ctx, narrativeCache, logger, narrativeCacheStatsInterval)
+
+	decayHalfLives := make(map[string]time.Duration, len(cfg.MemoryDecayHalfLives))
+	for category, days := range cfg.MemoryDecayHalfLives {
+		decayHalfLives[category] = time.Duration(days) * 24 * time.Hour
+	}
+	decaySvc := service.NewMemoryDecayService(memoryRepo, service.MemoryDecayConfig{
+		DefaultHalfLife: time.Duration(cfg.MemoryDecayHalfLifeDays) * 24 * time.Hour,
+		HalfLives:       decayHalfLives,
+		ArchiveBelow:    cfg.MemoryDecayArchiveBelow,
+	}, logger)
+	decaySvc.SetCache(narrativeCache)
+	go decaySvc.Run(ctx, time.Duration(cfg.MemoryDecayIntervalMinutes)*time.Minute)
+	reflectionSvc := service.NewReflectionService(llmRouter.For(llm.RoleConsolidation), memoryRepo, memoryRepo, characterRepo, service.ReflectionConfig{
+		Window:      time.Duration(cfg.ReflectionWindowDays) * 24 * time.Hour,
+		MinMemories: cfg.ReflectionMinMemories,
+	}, logger)
+	reflectionSvc.SetCache(narrativeCache)
+	go reflectionSvc.Run(ctx, time.Duration(cfg.ReflectionIntervalMinutes)*time.Minute)
 	jwtSvc := service.NewJWTServiceWithStore(
 		cfg.JWTSecret,
 		time.Duration(cfg.JWTAccessTTLMinutes)*time.Minute,
@@ -97,8 +158,10 @@ func main() {
 	userSvc := service.NewUserService(logger, userRepo, emailSender, otpLimiter)
 	userHandler := apihttp.NewUserHandler(logger, userSvc, jwtSvc)
 	cloneHandler := apihttp.NewCloneHandler(logger, profileRepo, traitRepo)
-	chatHandler := apihttp.NewChatHandler(logger, sessionRepo, messageRepo, analysisSvc, cloneSvc)
-	router := apihttp.NewRouter(logger, userHandler, chatHandler, cloneHandler)
+	chatHandler := apihttp.NewChatHandler(logger, sessionRepo, messageRepo, profileRepo, characterRepo, sessionSvc, analysisSvc, cloneSvc)
+	characterHandler := apihttp.NewCharacterHandler(logger, profileRepo, characterRepo, relationshipEventRepo, characterAliasRepo)
+	memoryHandler := apihttp.NewMemoryHandler(logger, profileRepo, characterRepo, memoryRepo, narrativeSvc)
+	router := apihttp.NewRouter(logger, userHandler, chatHandler, cloneHandler, characterHandler, memoryHandler, jwtSvc)
 
 	server := &http.Server{
 		Addr:              ":" + cfg.HTTPPort,
@@ -52,16 +52,51 @@ func main() {
 	traitRepo := repository.NewPgTraitRepository(pool)
 	characterRepo := repository.NewPgCharacterRepository(pool)
 	memoryRepo := repository.NewPgMemoryRepository(pool)
+	relationshipEventRepo := repository.NewPgRelationshipEventRepository(pool)
+	factRepo := repository.NewPgFactRepository(pool)
 
-	llmClient := llm.NewHTTPClient(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModel, logger)
-	analysisSvc := service.NewAnalysisService(llmClient, traitRepo, profileRepo, logger)
-	testSvc := service.NewTestService(llmClient, analysisSvc, logger)
+	providerCfg := cfg.LLMProviderConfig()
+	llmRouter, err := llm.NewRouter(providerCfg, cfg.LLMRoles(), logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	embeddingSpec := service.EmbeddingSpec{Model: providerCfg.EmbeddingModelName(), Dimension: cfg.EmbeddingDimensions}
+	memoryRepo.SetEmbeddingModel(embeddingSpec.Model)
+	staleEmbeddings, err := service.CheckEmbeddingStore(ctx, memoryRepo, embeddingSpec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if staleEmbeddings > 0 {
+		fmt.Printf("Aviso: %d memorias tienen embeddings de otro modelo; corre go run ./cmd/reembed\n", staleEmbeddings)
+	}
+	analysisSvc := service.NewAnalysisService(llmRouter.For(llm.RoleAnalysis), traitRepo, profileRepo, logger)
+	factSvc := service.NewFactService(factRepo, logger)
+	analysisSvc.SetFactService(factSvc)
+	testSvc := service.NewTestService(llmRouter.For(llm.RoleAnalysis), analysisSvc, logger)
 	contextSvc := service.NewBasicContextService(messageRepo)
-	narrativeSvc := service.NewNarrativeService(characterRepo, memoryRepo, llmClient)
+	narrativeSvc := service.NewNarrativeService(characterRepo, memoryRepo, llmRouter.Base())
+	narrativeSvc.SetDedupThreshold(cfg.MemoryDedupThreshold)
+	narrativeSvc.SetEvocationClient(llmRouter.For(llm.RoleEvocation))
+	narrativeSvc.SetJudgeClient(llmRouter.For(llm.RoleJudge))
 	promptBuilder := service.ClonePromptBuilder{}
 	responseParser := service.LLMResponseParser{}
 	reactionEngine := service.ReactionEngine{}
-	cloneSvc := service.NewCloneService(llmClient, messageRepo, profileRepo, traitRepo, contextSvc, narrativeSvc, analysisSvc, promptBuilder, responseParser, reactionEngine)
+	profileHydrator := service.NewProfileHydrator(profileRepo, traitRepo)
+	cloneSvc := service.NewCloneService(llmRouter.For(llm.RoleReply), messageRepo, profileRepo, traitRepo, contextSvc, narrativeSvc, analysisSvc, promptBuilder, responseParser, reactionEngine)
+	cloneSvc.SetRelationshipService(service.NewRelationshipService(characterRepo, sessionRepo, relationshipEventRepo))
+	cloneSvc.SetSessionRepository(sessionRepo)
+	cloneSvc.SetFactService(factSvc)
+	sessionSvc := service.NewSessionService(sessionRepo, logger)
+	consolidationSvc := service.NewConsolidationService(llmRouter.For(llm.RoleConsolidation), messageRepo, memoryRepo, sessionRepo, logger)
+	consolidationSvc.SetFactService(factSvc)
+	consolidationSvc.SetProfileRepository(profileRepo)
+	narrativeCache := service.NewMemoryNarrativeCache(cfg.NarrativeCacheSize, time.Duration(cfg.NarrativeCacheTTLMinutes)*time.Minute)
+	narrativeSvc.SetCache(narrativeCache)
+	// En la CLI el proceso puede terminar al salir del chat: consolidamos en linea en vez de en background.
+	sessionSvc.OnSessionEnd(func(ctx context.Context, session domain.Session, _ string) error {
+		_, err := consolidationSvc.ConsolidateSession(ctx, session)
+		return err
+	})
 
 	user, err := ensureUser(ctx, pool, userRepo, "cli_test@example.com")
 	if err != nil {
@@ -70,7 +105,7 @@ func main() {
 
 	for {
 		fmt.Println("===== Director Mode =====")
-		profiles, err := listProfiles(ctx, pool, user.ID)
+		profiles, err := profileRepo.ListByUserID(ctx, user.ID)
 		if err != nil {
 			log.Fatalf("listar perfiles: %v", err)
 		}
@@ -108,7 +143,14 @@ func main() {
 			selected = profiles[idx-1]
 		}
 
-		if selected.Big5.Openness == 0 && selected.Big5.Neuroticism == 0 {
+		hydrated, traits, err := profileHydrator.GetByID(ctx, selected.ID)
+		if err != nil {
+			log.Printf("hidratar perfil: %v", err)
+		} else {
+			selected = hydrated
+		}
+
+		if !service.HydrateBig5(&selected, traits) {
 			fmt.Println("\n--- Inicialización de Personalidad ---")
 			fmt.Println("Parece que este perfil no tiene rasgos OCEAN iniciales.")
 			fmt.Println("[T] Responder Test de Personalidad (Recomendado para un mejor comportamiento)")
@@ -120,14 +162,14 @@ func main() {
 
 			if selection == "T" {
 				runPersonalityTest(ctx, testSvc, selected.UserID, reader, logger)
-				// Usar GetByID para recargar el perfil recién actualizado.
-				if refreshed, err := profileRepo.GetByID(ctx, selected.ID); err == nil {
+				// Recargar el perfil recién actualizado con sus rasgos OCEAN.
+				if refreshed, _, err := profileHydrator.GetByID(ctx, selected.ID); err == nil {
 					selected = refreshed
 				}
 			}
 		}
 
-		if err := runActionsMenu(ctx, reader, selected, user, sessionRepo, messageRepo, traitRepo, characterRepo, narrativeSvc, cloneSvc); err != nil {
+		if err := runActionsMenu(ctx, reader, selected, user, sessionRepo, sessionSvc, messageRepo, traitRepo, characterRepo, narrativeSvc, cloneSvc); err != nil {
 			log.Printf("error en menu: %v", err)
 		}
 	}
@@ -139,6 +181,7 @@ func runActionsMenu(
 	profile domain.CloneProfile,
 	user domain.User,
 	sessionRepo repository.SessionRepository,
+	sessionSvc *service.SessionService,
 	messageRepo repository.MessageRepository,
 	traitRepo repository.TraitRepository,
 	characterRepo repository.CharacterRepository,
@@ -146,7 +189,6 @@ func runActionsMenu(
 	cloneSvc *service.CloneService,
 ) error {
 	_ = traitRepo
-	_ = characterRepo
 	for {
 		fmt.Printf("\n--- Trabajando con: %s ---\n", strings.ToUpper(profile.Name))
 		fmt.Println("[1] Chatear")
@@ -160,7 +202,7 @@ func runActionsMenu(
 		line = strings.TrimSpace(line)
 		switch line {
 		case "1":
-			if err := chatFlow(ctx, reader, profile, user, sessionRepo, messageRepo, cloneSvc); err != nil {
+			if err := chatFlow(ctx, reader, profile, user, sessionRepo, sessionSvc, messageRepo, cloneSvc); err != nil {
 				fmt.Printf("Error en chat: %v\n", err)
 			}
 		case "2":
@@ -170,7 +212,7 @@ func runActionsMenu(
 				fmt.Println("Vinculo/personaje creado.")
 			}
 		case "3":
-			if err := seedMemoryFlow(ctx, reader, profile, narrativeSvc); err != nil {
+			if err := seedMemoryFlow(ctx, reader, profile, characterRepo, narrativeSvc); err != nil {
 				fmt.Printf("Error sembrando escenario: %v\n", err)
 			} else {
 				fmt.Println("Escenario implantado. El clon ahora recordara esto al iniciar el chat.")
@@ -185,13 +227,14 @@ func runActionsMenu(
 	}
 }
 
-func chatFlow(ctx context.Context, reader *bufio.Reader, profile domain.CloneProfile, user domain.User, sessionRepo repository.SessionRepository, messageRepo repository.MessageRepository, cloneSvc *service.CloneService) error {
+func chatFlow(ctx context.Context, reader *bufio.Reader, profile domain.CloneProfile, user domain.User, sessionRepo repository.SessionRepository, sessionSvc *service.SessionService, messageRepo repository.MessageRepository, cloneSvc *service.CloneService) error {
 	session := domain.Session{
-		ID:        uuid.NewString(),
-		UserID:    user.ID,
-		Token:     uuid.NewString(),
-		ExpiresAt: time.Now().UTC().Add(24 * time.Hour),
-		CreatedAt: time.Now().UTC(),
+		ID:             uuid.NewString(),
+		UserID:         user.ID,
+		CloneProfileID: profile.ID,
+		Token:          uuid.NewString(),
+		ExpiresAt:      time.Now().UTC().Add(24 * time.Hour),
+		CreatedAt:      time.Now().UTC(),
 	}
 	if err := sessionRepo.Create(ctx, session); err != nil {
 		return fmt.Errorf("crear sesion: %w", err)
@@ -210,6 +253,9 @@ func chatFlow(ctx context.Context, reader *bufio.Reader, profile domain.ClonePro
 		}
 		if strings.EqualFold(text, "salir") || strings.EqualFold(text, "exit") {
 			fmt.Println("Saliendo del chat...")
+			if err := sessionSvc.Close(ctx, &session); err != nil {
+				log.Printf("cerrar sesion: %v", err)
+			}
 			return nil
 		}
 
@@ -261,7 +307,7 @@ func addCharacterFlow(ctx context.Context, reader *bufio.Reader, profile domain.
 	})
 }
 
-func seedMemoryFlow(ctx context.Context, reader *bufio.Reader, profile domain.CloneProfile, narrativeSvc *service.NarrativeService) error {
+func seedMemoryFlow(ctx context.Context, reader *bufio.Reader, profile domain.CloneProfile, characterRepo repository.CharacterRepository, narrativeSvc *service.NarrativeService) error {
 	fmt.Print("Describe el escenario/recuerdo: ")
 	content, _ := reader.ReadString('\n')
 	content = strings.TrimSpace(content)
@@ -300,11 +346,24 @@ func seedMemoryFlow(ctx context.Context, reader *bufio.Reader, profile domain.Cl
 		return fmt.Errorf("parse profile id: %w", err)
 	}
 
+	fmt.Print("Personaje relacionado (nombre, Enter = ninguno): ")
+	charName, _ := reader.ReadString('\n')
+	charName = strings.TrimSpace(charName)
+	var relatedID *uuid.UUID
+	if charName != "" {
+		char, err := characterRepo.FindByName(ctx, profileUUID, charName)
+		if err != nil || char == nil {
+			fmt.Printf("No encontre a %q; el recuerdo queda sin personaje.\n", charName)
+		} else {
+			relatedID = &char.ID
+		}
+	}
+
 	emotionalIntensity := emotionalWeight * 10
 	if emotionalIntensity < 1 {
 		emotionalIntensity = 10
 	}
-	return narrativeSvc.InjectMemory(ctx, profileUUID, content, importance, emotionalWeight, emotionalIntensity, sentimentLabel)
+	return narrativeSvc.InjectMemory(ctx, profileUUID, relatedID, content, importance, emotionalWeight, emotionalIntensity, sentimentLabel)
 }
 
 func readIntDefault(reader *bufio.Reader, prompt string, def int) int {
@@ -347,30 +406,6 @@ func ensureUser(ctx context.Context, pool *pgxpool.Pool, repo repository.UserRep
 	return u, nil
 }
 
-func listProfiles(ctx context.Context, pool *pgxpool.Pool, userID string) ([]domain.CloneProfile, error) {
-	const query = `
-		SELECT id, user_id, name, bio, created_at
-		FROM clone_profiles
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
-	rows, err := pool.Query(ctx, query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var profiles []domain.CloneProfile
-	for rows.Next() {
-		var p domain.CloneProfile
-		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Bio, &p.CreatedAt); err != nil {
-			return nil, err
-		}
-		profiles = append(profiles, p)
-	}
-	return profiles, rows.Err()
-}
-
 func createProfileFlow(ctx context.Context, reader *bufio.Reader, repo repository.ProfileRepository, userID string) (*domain.CloneProfile, error) {
 	fmt.Print("Nombre del clon: ")
 	name, _ := reader.ReadString('\n')
@@ -380,11 +415,12 @@ func createProfileFlow(ctx context.Context, reader *bufio.Reader, repo repositor
 	bio = strings.TrimSpace(bio)
 
 	profile := domain.CloneProfile{
-		ID:        uuid.NewString(),
-		UserID:    userID,
-		Name:      name,
-		Bio:       bio,
-		CreatedAt: time.Now().UTC(),
+		ID:             uuid.NewString(),
+		UserID:         userID,
+		Name:           name,
+		Bio:            bio,
+		TraitStability: domain.DefaultTraitStability,
+		CreatedAt:      time.Now().UTC(),
 	}
 	if err := repo.Create(ctx, profile); err != nil {
 		return nil, err
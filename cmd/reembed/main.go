@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/config"
+	"clone-llm/internal/db"
+	"clone-llm/internal/llm"
+	"clone-llm/internal/repository"
+	"clone-llm/internal/service"
+)
+
+// reembed regenera los embeddings de todas las narrative_memories con EMBEDDING_MODEL y
+// EMBEDDING_DIMENSIONS. Se corre con la configuracion nueva; despues hay que reiniciar la API
+// con esa misma configuracion.
+func main() {
+	batch := flag.Int("batch", 100, "memorias por lote")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("warning: loading .env: %v", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	pool, err := db.NewPool(ctx, cfg)
+	if err != nil {
+		logger.Fatal("db connect", zap.Error(err))
+	}
+	defer pool.Close()
+
+	providerCfg := cfg.LLMProviderConfig()
+	llmClient, err := llm.NewClient(providerCfg, logger)
+	if err != nil {
+		logger.Fatal("llm client", zap.Error(err))
+	}
+	memoryRepo := repository.NewPgMemoryRepository(pool)
+	spec := service.EmbeddingSpec{Model: providerCfg.EmbeddingModelName(), Dimension: cfg.EmbeddingDimensions}
+
+	logger.Info("reembedding memories", zap.String("model", spec.Model), zap.Int("dimension", spec.Dimension))
+	res, err := service.NewReembedService(llmClient, memoryRepo, spec, *batch, logger).Run(ctx)
+	if err != nil {
+		logger.Fatal("reembed failed", zap.Error(err), zap.Int("reembedded", res.Reembedded))
+	}
+	logger.Info("reembed done", zap.Int("reembedded", res.Reembedded), zap.Int("passes", res.Passes))
+}
@@ -1,14 +1,52 @@
 package config
 
-import "github.com/caarlos0/env/v10"
+import (
+	"time"
+
+	"github.com/caarlos0/env/v10"
+
+	"clone-llm/internal/llm"
+)
 
 // Config centraliza la configuración del servicio.
 type Config struct {
 	HTTPPort    string `env:"HTTP_PORT" envDefault:"8080"`
 	DatabaseURL string `env:"DATABASE_URL,required"`
-	LLMAPIKey   string `env:"LLM_API_KEY,required"`
-	LLMBaseURL  string `env:"LLM_BASE_URL" envDefault:"https://api.openai.com/v1"`
-	LLMModel    string `env:"LLM_MODEL" envDefault:"gpt-5.1"`
+	// Proveedor de generacion: openai (API compatible con OpenAI), anthropic (Messages API),
+	// ollama o llamacpp (locales, sin API key).
+	LLMProvider string `env:"LLM_PROVIDER" envDefault:"openai"`
+	// Obligatoria para openai y anthropic; NewClient la valida.
+	LLMAPIKey string `env:"LLM_API_KEY"`
+	// Vacio usa la URL por defecto del proveedor.
+	LLMBaseURL      string `env:"LLM_BASE_URL"`
+	LLMModel        string `env:"LLM_MODEL" envDefault:"gpt-5.1"`
+	LLMMaxTokens    int    `env:"LLM_MAX_TOKENS" envDefault:"4096"`
+	LLMSystemPrompt string `env:"LLM_SYSTEM_PROMPT"`
+	// Temperatura de generacion; negativa usa la del modelo.
+	LLMTemperature float64 `env:"LLM_TEMPERATURE" envDefault:"-1"`
+	// Modelos por rol (LLM_REPLY_MODEL, LLM_JUDGE_PROVIDER, ...): lo que no se define hereda de LLM_*.
+	LLMReply         LLMRoleConfig `envPrefix:"LLM_REPLY_"`
+	LLMAnalysis      LLMRoleConfig `envPrefix:"LLM_ANALYSIS_"`
+	LLMEvocation     LLMRoleConfig `envPrefix:"LLM_EVOCATION_"`
+	LLMJudge         LLMRoleConfig `envPrefix:"LLM_JUDGE_"`
+	LLMConsolidation LLMRoleConfig `envPrefix:"LLM_CONSOLIDATION_"`
+	// Resiliencia de las llamadas al LLM: deadline de cada intento, reintentos con backoff exponencial
+	// (429, 5xx, timeouts) y circuit breaker que corta tras LLM_BREAKER_THRESHOLD llamadas fallidas seguidas.
+	LLMTimeoutSeconds         int `env:"LLM_TIMEOUT_SECONDS" envDefault:"120"`
+	LLMMaxRetries             int `env:"LLM_MAX_RETRIES" envDefault:"2"`
+	LLMRetryBaseMillis        int `env:"LLM_RETRY_BASE_MS" envDefault:"500"`
+	LLMRetryMaxMillis         int `env:"LLM_RETRY_MAX_MS" envDefault:"10000"`
+	LLMBreakerThreshold       int `env:"LLM_BREAKER_THRESHOLD" envDefault:"5"`
+	LLMBreakerCooldownSeconds int `env:"LLM_BREAKER_COOLDOWN_SECONDS" envDefault:"30"`
+	// Embeddings para proveedores sin endpoint propio (anthropic). La clave es obligatoria si el host
+	// difiere del de generacion: LLM_API_KEY nunca se manda a otro proveedor.
+	EmbeddingBaseURL string `env:"EMBEDDING_BASE_URL" envDefault:"https://api.openai.com/v1"`
+	EmbeddingAPIKey  string `env:"EMBEDDING_API_KEY"`
+	// Modelo de embeddings (vacio: text-embedding-3-small, o nomic-embed-text con ollama; obligatorio con
+	// llamacpp) y dimension de narrative_memories.embedding. Cambiarlos exige regenerar las memorias
+	// con cmd/reembed.
+	EmbeddingModel      string `env:"EMBEDDING_MODEL"`
+	EmbeddingDimensions int    `env:"EMBEDDING_DIMENSIONS" envDefault:"1536"`
 	SMTPHost    string `env:"SMTP_HOST"`
 	SMTPPort    int    `env:"SMTP_PORT" envDefault:"587"`
 	SMTPUser    string `env:"SMTP_USER"`
@@ -22,6 +60,90 @@ type Config struct {
 	JWTSecret   string `env:"JWT_SECRET"`
 	JWTAccessTTLMinutes  int `env:"JWT_ACCESS_TTL_MINUTES" envDefault:"15"`
 	JWTRefreshTTLMinutes int `env:"JWT_REFRESH_TTL_MINUTES" envDefault:"43200"`
+	// Minutos sin mensajes tras los que una sesion abierta se cierra y se consolida en memorias. Es el
+	// unico camino por el que se consolidan las sesiones que nadie cierra a mano; una sesion cerrada ya no
+	// acepta mensajes (409) y el cliente tiene que abrir otra. 0 lo desactiva.
+	SessionIdleMinutes int `env:"SESSION_IDLE_MINUTES" envDefault:"60"`
+	// Cache de evocacion/juez: entradas del LRU en proceso (sin Redis) y vida de cada entrada.
+	NarrativeCacheSize       int `env:"NARRATIVE_CACHE_SIZE" envDefault:"1000"`
+	NarrativeCacheTTLMinutes int `env:"NARRATIVE_CACHE_TTL_MINUTES" envDefault:"60"`
+	// Olvido de memorias: cada cuanto corre el job (0 lo desactiva), vida media por defecto y por
+	// categoria emocional ("ALEGRIA:60,NEUTRAL:14", en dias) e importancia minima antes de archivar.
+	MemoryDecayIntervalMinutes int            `env:"MEMORY_DECAY_INTERVAL_MINUTES" envDefault:"360"`
+	MemoryDecayHalfLifeDays    int            `env:"MEMORY_DECAY_HALF_LIFE_DAYS" envDefault:"30"`
+	MemoryDecayHalfLives       map[string]int `env:"MEMORY_DECAY_HALF_LIVES" envDefault:"NEUTRAL:14"`
+	MemoryDecayArchiveBelow    int            `env:"MEMORY_DECAY_ARCHIVE_BELOW" envDefault:"2"`
+	// Similitud coseno (0-1) a partir de la cual un recuerdo repetido se fusiona con el existente (0 desactiva).
+	MemoryDedupThreshold float64 `env:"MEMORY_DEDUP_THRESHOLD" envDefault:"0.92"`
+	// Reflexion: cada cuanto corre el job (0 lo desactiva), ventana de memorias recientes (dias)
+	// y memorias minimas de un personaje para pedir conclusiones.
+	ReflectionIntervalMinutes int `env:"REFLECTION_INTERVAL_MINUTES" envDefault:"720"`
+	ReflectionWindowDays      int `env:"REFLECTION_WINDOW_DAYS" envDefault:"7"`
+	ReflectionMinMemories     int `env:"REFLECTION_MIN_MEMORIES" envDefault:"5"`
+}
+
+// LLMRoleConfig sobreescribe proveedor, modelo o temperatura de LLM_* para un rol.
+type LLMRoleConfig struct {
+	Provider    string  `env:"PROVIDER"`
+	BaseURL     string  `env:"BASE_URL"`
+	APIKey      string  `env:"API_KEY"`
+	Model       string  `env:"MODEL"`
+	Temperature float64 `env:"TEMPERATURE" envDefault:"-1"`
+}
+
+func (r LLMRoleConfig) roleConfig() llm.RoleConfig {
+	return llm.RoleConfig{
+		Provider:    r.Provider,
+		BaseURL:     r.BaseURL,
+		APIKey:      r.APIKey,
+		Model:       r.Model,
+		Temperature: temperature(r.Temperature),
+	}
+}
+
+// LLMRoles devuelve las sobreescrituras por rol para llm.NewRouter.
+func (c *Config) LLMRoles() map[llm.Role]llm.RoleConfig {
+	return map[llm.Role]llm.RoleConfig{
+		llm.RoleReply:         c.LLMReply.roleConfig(),
+		llm.RoleAnalysis:      c.LLMAnalysis.roleConfig(),
+		llm.RoleEvocation:     c.LLMEvocation.roleConfig(),
+		llm.RoleJudge:         c.LLMJudge.roleConfig(),
+		llm.RoleConsolidation: c.LLMConsolidation.roleConfig(),
+	}
+}
+
+// temperature traduce el centinela negativo de las variables de entorno a "sin definir".
+func temperature(t float64) *float64 {
+	if t < 0 {
+		return nil
+	}
+	return &t
+}
+
+// LLMProviderConfig arma la configuracion del cliente LLM a partir de las variables de entorno.
+func (c *Config) LLMProviderConfig() llm.ProviderConfig {
+	return llm.ProviderConfig{
+		Provider:         c.LLMProvider,
+		BaseURL:          c.LLMBaseURL,
+		APIKey:           c.LLMAPIKey,
+		Model:            c.LLMModel,
+		MaxTokens:        c.LLMMaxTokens,
+		SystemPrompt:     c.LLMSystemPrompt,
+		Temperature:      temperature(c.LLMTemperature),
+		EmbeddingBaseURL: c.EmbeddingBaseURL,
+		EmbeddingAPIKey:  c.EmbeddingAPIKey,
+		EmbeddingModel:   c.EmbeddingModel,
+		// Los embeddings mas cortos se completan hasta la dimension de la columna.
+		EmbeddingDimension: c.EmbeddingDimensions,
+		Resilience: &llm.ResilienceConfig{
+			MaxRetries:       c.LLMMaxRetries,
+			BaseDelay:        time.Duration(c.LLMRetryBaseMillis) * time.Millisecond,
+			MaxDelay:         time.Duration(c.LLMRetryMaxMillis) * time.Millisecond,
+			CallTimeout:      time.Duration(c.LLMTimeoutSeconds) * time.Second,
+			BreakerThreshold: c.LLMBreakerThreshold,
+			BreakerCooldown:  time.Duration(c.LLMBreakerCooldownSeconds) * time.Second,
+		},
+	}
 }
 
 // LoadConfig carga la configuración desde variables de entorno.
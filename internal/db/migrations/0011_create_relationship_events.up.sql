@@ -0,0 +1,19 @@
+-- Historial de cambios en los vectores de vinculo de cada personaje
+CREATE TABLE relationship_events (
+    id UUID PRIMARY KEY,
+    character_id UUID NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+    clone_profile_id UUID NOT NULL REFERENCES clone_profiles(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    cause VARCHAR(32) NOT NULL,
+    trust_before INT NOT NULL,
+    intimacy_before INT NOT NULL,
+    respect_before INT NOT NULL,
+    trust_after INT NOT NULL,
+    intimacy_after INT NOT NULL,
+    respect_after INT NOT NULL,
+    bond_status VARCHAR,
+    dynamic TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_relationship_events_character ON relationship_events (character_id, created_at DESC);
+CREATE INDEX idx_relationship_events_message ON relationship_events (message_id);
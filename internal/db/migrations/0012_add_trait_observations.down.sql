@@ -0,0 +1,7 @@
+ALTER TABLE clone_profiles
+    DROP COLUMN IF EXISTS trait_stability;
+
+ALTER TABLE traits
+    DROP COLUMN IF EXISTS observation_count;
+
+DROP TABLE IF EXISTS trait_observations;
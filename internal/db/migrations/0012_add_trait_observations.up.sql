@@ -0,0 +1,19 @@
+-- Observaciones crudas del analizador; traits pasa a guardar la estimacion acumulada
+CREATE TABLE trait_observations (
+    id UUID PRIMARY KEY,
+    profile_id UUID NOT NULL REFERENCES clone_profiles(id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    trait TEXT NOT NULL,
+    value INT NOT NULL,
+    confidence DECIMAL,
+    estimate_after INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_trait_observations_profile_trait ON trait_observations (profile_id, category, trait, created_at DESC);
+
+ALTER TABLE traits
+    ADD COLUMN IF NOT EXISTS observation_count INT NOT NULL DEFAULT 1;
+
+-- Estabilidad de personalidad por perfil (0 = sigue la ultima observacion, 1 = promedio de todas las observaciones)
+ALTER TABLE clone_profiles
+    ADD COLUMN IF NOT EXISTS trait_stability DECIMAL NOT NULL DEFAULT 0.8;
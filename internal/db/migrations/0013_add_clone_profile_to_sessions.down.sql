@@ -0,0 +1,6 @@
+-- 0013_add_clone_profile_to_sessions.down.sql
+
+DROP INDEX IF EXISTS idx_sessions_clone_profile_id;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS clone_profile_id;
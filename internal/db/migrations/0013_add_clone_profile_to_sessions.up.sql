@@ -0,0 +1,8 @@
+-- 0013_add_clone_profile_to_sessions.up.sql
+
+-- Cada sesion queda ligada al clon con el que se conversa.
+-- Nullable para no romper sesiones previas (se resuelven por user_id).
+ALTER TABLE sessions
+    ADD COLUMN clone_profile_id UUID REFERENCES clone_profiles(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_sessions_clone_profile_id ON sessions(clone_profile_id);
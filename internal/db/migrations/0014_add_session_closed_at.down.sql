@@ -0,0 +1,4 @@
+-- 0014_add_session_closed_at.down.sql
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS closed_at;
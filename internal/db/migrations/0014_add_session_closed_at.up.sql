@@ -0,0 +1,5 @@
+-- 0014_add_session_closed_at.up.sql
+
+-- Marca de cierre explicito (o por expiracion detectada) de la sesion.
+ALTER TABLE sessions
+    ADD COLUMN closed_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- 0015_add_memory_provenance.down.sql
+
+DROP INDEX IF EXISTS idx_narrative_memories_source_session;
+
+ALTER TABLE narrative_memories
+    DROP COLUMN IF EXISTS source_session_id,
+    DROP COLUMN IF EXISTS memory_kind;
@@ -0,0 +1,12 @@
+-- 0015_add_memory_provenance.up.sql
+
+-- Tipo de memoria: episodica (lo que paso) o semantica (un hecho suelto).
+-- Las memorias previas son episodicas.
+ALTER TABLE narrative_memories
+    ADD COLUMN memory_kind VARCHAR NOT NULL DEFAULT 'episodic';
+
+-- Sesion de la que salio la memoria al consolidar (procedencia). Nullable para memorias inyectadas a mano.
+ALTER TABLE narrative_memories
+    ADD COLUMN source_session_id UUID REFERENCES sessions(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_narrative_memories_source_session ON narrative_memories (source_session_id);
@@ -0,0 +1,4 @@
+-- 0016_add_session_speaking_as.down.sql
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS speaking_as_character_id;
@@ -0,0 +1,6 @@
+-- 0016_add_session_speaking_as.up.sql
+
+-- Personaje que el usuario encarna en la sesion ("hablando como"). Si se fija, los recuerdos
+-- del turno se ligan a ese personaje cuando el mensaje no nombra a otro.
+ALTER TABLE sessions
+    ADD COLUMN speaking_as_character_id UUID REFERENCES characters(id) ON DELETE SET NULL;
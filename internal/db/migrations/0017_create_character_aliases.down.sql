@@ -0,0 +1,3 @@
+-- 0017_create_character_aliases.down.sql
+
+DROP TABLE IF EXISTS character_aliases;
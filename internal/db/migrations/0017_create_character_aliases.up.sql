@@ -0,0 +1,12 @@
+-- 0017_create_character_aliases.up.sql
+
+-- Apodos y formas alternativas con las que el usuario nombra a un personaje ("mi vieja", "Anita").
+-- normalized guarda la forma plegada (minusculas, sin tildes) usada para detectar y evitar duplicados.
+CREATE TABLE character_aliases (
+    id UUID PRIMARY KEY,
+    character_id UUID NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL,
+    normalized VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_character_aliases_unique ON character_aliases (character_id, normalized);
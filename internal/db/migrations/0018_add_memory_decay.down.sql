@@ -0,0 +1,10 @@
+-- 0018_add_memory_decay.down.sql
+
+DROP INDEX IF EXISTS idx_narrative_memories_active;
+
+ALTER TABLE narrative_memories
+    DROP COLUMN IF EXISTS decay_anchor_at,
+    DROP COLUMN IF EXISTS decay_base_weight,
+    DROP COLUMN IF EXISTS decay_base_importance,
+    DROP COLUMN IF EXISTS archived_at,
+    DROP COLUMN IF EXISTS is_core;
@@ -0,0 +1,18 @@
+-- 0018_add_memory_decay.up.sql
+
+-- Memorias nucleares: el job de olvido nunca las degrada ni las archiva.
+ALTER TABLE narrative_memories
+    ADD COLUMN is_core BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Memorias archivadas por olvido: quedan fuera de la recuperacion pero no se borran.
+ALTER TABLE narrative_memories
+    ADD COLUMN archived_at TIMESTAMPTZ;
+
+-- Estado del decaimiento: valores de partida y momento desde el que corre la vida media.
+-- NULL significa "todavia sin decaer" (se parte de importance/emotional_weight y created_at).
+ALTER TABLE narrative_memories
+    ADD COLUMN decay_base_importance INT,
+    ADD COLUMN decay_base_weight INT,
+    ADD COLUMN decay_anchor_at TIMESTAMPTZ;
+
+CREATE INDEX idx_narrative_memories_active ON narrative_memories (clone_profile_id) WHERE archived_at IS NULL;
@@ -0,0 +1,5 @@
+-- 0019_add_memory_recall.down.sql
+
+ALTER TABLE narrative_memories
+    DROP COLUMN IF EXISTS last_recalled_at,
+    DROP COLUMN IF EXISTS recall_count;
@@ -0,0 +1,7 @@
+-- 0019_add_memory_recall.up.sql
+
+-- Refuerzo por evocacion: cuantas veces entro la memoria al contexto de un turno y cuando fue la ultima.
+-- Alimenta el score de recuperacion y frena el decaimiento de las memorias que se recuerdan seguido.
+ALTER TABLE narrative_memories
+    ADD COLUMN recall_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN last_recalled_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- 0020_add_memory_occurrences.down.sql
+
+ALTER TABLE narrative_memories
+    DROP COLUMN IF EXISTS occurrence_count;
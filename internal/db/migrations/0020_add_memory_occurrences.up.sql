@@ -0,0 +1,6 @@
+-- 0020_add_memory_occurrences.up.sql
+
+-- Veces que se registro la misma memoria: InjectMemory fusiona casi-duplicados en la fila existente
+-- en lugar de crear una nueva por cada repeticion.
+ALTER TABLE narrative_memories
+    ADD COLUMN occurrence_count INT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- 0021_create_facts.down.sql
+
+DROP TABLE IF EXISTS facts;
@@ -0,0 +1,24 @@
+-- 0021_create_facts.up.sql
+
+-- Hechos semanticos que el clon sabe del usuario y su entorno ("usuario | tiene un perro llamado | Toby"),
+-- separados de las memorias episodicas. subject_key/predicate_key guardan la forma plegada
+-- (minusculas, sin tildes) usada para detectar hechos que se contradicen.
+-- contradicted_by apunta al hecho mas nuevo que reemplazo a este; los activos lo tienen en NULL.
+CREATE TABLE facts (
+    id UUID PRIMARY KEY,
+    clone_profile_id UUID NOT NULL REFERENCES clone_profiles(id) ON DELETE CASCADE,
+    subject VARCHAR(200) NOT NULL,
+    predicate VARCHAR(200) NOT NULL,
+    object TEXT NOT NULL,
+    subject_key VARCHAR(200) NOT NULL,
+    predicate_key VARCHAR(200) NOT NULL,
+    source_message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    source_session_id UUID REFERENCES sessions(id) ON DELETE SET NULL,
+    confidence REAL NOT NULL DEFAULT 0.5,
+    contradicted_by UUID REFERENCES facts(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_facts_active_key ON facts (clone_profile_id, subject_key, predicate_key)
+    WHERE contradicted_by IS NULL;
@@ -0,0 +1,7 @@
+-- 0022_add_memory_reflections.down.sql
+
+DROP INDEX IF EXISTS idx_narrative_memories_reflection_pending;
+
+ALTER TABLE narrative_memories
+    DROP COLUMN IF EXISTS reflected_at,
+    DROP COLUMN IF EXISTS source_memory_ids;
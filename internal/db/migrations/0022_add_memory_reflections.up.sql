@@ -0,0 +1,14 @@
+-- 0022_add_memory_reflections.up.sql
+
+-- Reflexiones: memorias sintetizadas por el LLM a partir de varias memorias de un personaje
+-- (memory_kind = 'reflection'). source_memory_ids apunta a las memorias que la originaron.
+ALTER TABLE narrative_memories
+    ADD COLUMN source_memory_ids UUID[] NOT NULL DEFAULT '{}';
+
+-- Cuando el job de reflexion ya proceso la memoria (NULL = pendiente).
+ALTER TABLE narrative_memories
+    ADD COLUMN reflected_at TIMESTAMPTZ;
+
+CREATE INDEX idx_narrative_memories_reflection_pending
+    ON narrative_memories (clone_profile_id, related_character_id, created_at)
+    WHERE reflected_at IS NULL AND archived_at IS NULL;
@@ -0,0 +1,6 @@
+-- 0023_add_embedding_model.down.sql
+
+ALTER TABLE narrative_memories
+    DROP COLUMN IF EXISTS embedding_next_model,
+    DROP COLUMN IF EXISTS embedding_next,
+    DROP COLUMN IF EXISTS embedding_model;
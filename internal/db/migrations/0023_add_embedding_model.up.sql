@@ -0,0 +1,11 @@
+-- 0023_add_embedding_model.up.sql
+
+-- Modelo que genero el embedding de cada memoria. Vectores de modelos distintos no son comparables:
+-- al cambiar EMBEDDING_MODEL hay que regenerarlos con cmd/reembed. '' = desconocido.
+ALTER TABLE narrative_memories
+    ADD COLUMN embedding_model VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Hasta ahora el cliente usaba siempre text-embedding-3-small.
+UPDATE narrative_memories
+SET embedding_model = 'text-embedding-3-small'
+WHERE embedding IS NOT NULL;
@@ -0,0 +1,4 @@
+-- 0024_add_session_consolidated_at.down.sql
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS consolidated_at;
@@ -0,0 +1,11 @@
+-- 0024_add_session_consolidated_at.up.sql
+
+-- Marca de consolidacion de la sesion. ConsolidationService la reclama con un UPDATE condicional
+-- antes de resumir, asi dos hooks concurrentes no guardan el resumen dos veces.
+ALTER TABLE sessions
+    ADD COLUMN consolidated_at TIMESTAMPTZ;
+
+-- Las sesiones que ya tienen memorias consolidadas no se vuelven a resumir.
+UPDATE sessions s
+SET consolidated_at = NOW()
+WHERE EXISTS (SELECT 1 FROM narrative_memories m WHERE m.source_session_id = s.id);
@@ -2,14 +2,18 @@ package domain
 
 import "time"
 
+// DefaultTraitStability es la estabilidad de personalidad usada cuando el perfil no define una.
+const DefaultTraitStability = 0.8
+
 type CloneProfile struct {
-	ID          string      `json:"id"`
-	UserID      string      `json:"user_id"`
-	Name        string      `json:"name"`
-	Bio         string      `json:"bio,omitempty"`
-	Big5        Big5Profile `json:"big5"`
-	CurrentGoal *Goal       `json:"current_goal,omitempty"`
-	CreatedAt   time.Time   `json:"created_at"`
+	ID             string      `json:"id"`
+	UserID         string      `json:"user_id"`
+	Name           string      `json:"name"`
+	Bio            string      `json:"bio,omitempty"`
+	Big5           Big5Profile `json:"big5"`
+	TraitStability float64     `json:"trait_stability"` // 0.0 (sigue la ultima observacion) a 1.0 (promedio de todas)
+	CurrentGoal    *Goal       `json:"current_goal,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
 }
 
 type Big5Profile struct {
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fact es un hecho semantico que el clon sabe del usuario o su entorno, como tripleta
+// sujeto-predicado-objeto ("usuario", "tiene un perro llamado", "Toby").
+// Un hecho contradicho por uno mas nuevo queda como historial y deja de entrar al prompt.
+type Fact struct {
+	ID              uuid.UUID  `json:"id"`
+	CloneProfileID  uuid.UUID  `json:"clone_profile_id"`
+	Subject         string     `json:"subject"`
+	Predicate       string     `json:"predicate"`
+	Object          string     `json:"object"`
+	SubjectKey      string     `json:"-"`                           // Forma plegada de Subject para detectar conflictos
+	PredicateKey    string     `json:"-"`                           // Forma plegada de Predicate para detectar conflictos
+	SourceMessageID *string    `json:"source_message_id,omitempty"` // Mensaje del que salio (analisis por turno)
+	SourceSessionID *string    `json:"source_session_id,omitempty"` // Sesion consolidada de la que salio
+	Confidence      float64    `json:"confidence"`                  // 0-1
+	ContradictedBy  *uuid.UUID `json:"contradicted_by,omitempty"`   // Hecho que lo reemplazo
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ExtractedFact es un hecho tal como lo devuelve el LLM al analizar un mensaje o consolidar una sesion.
+// SingleValued indica que el predicado admite un solo objeto a la vez ("vive en"), a diferencia de
+// los que se acumulan ("tiene un perro llamado"); solo esos contradicen a los hechos previos.
+type ExtractedFact struct {
+	Subject      string  `json:"subject"`
+	Predicate    string  `json:"predicate"`
+	Object       string  `json:"object"`
+	Confidence   float64 `json:"confidence"`
+	SingleValued bool    `json:"single_valued"`
+}
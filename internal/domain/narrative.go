@@ -17,21 +17,47 @@ type Character struct {
 	Archetype      string              `json:"archetype"`
 	BondStatus     string              `json:"bond_status"`
 	Relationship   RelationshipVectors `json:"relationship"`
+	Aliases        []string            `json:"aliases,omitempty"` // Apodos cargados con ListByProfileID para la deteccion
 	CreatedAt      time.Time           `json:"created_at"`
 	UpdatedAt      time.Time           `json:"updated_at"`
 }
 
+// CharacterAlias es un nombre alternativo de un personaje (apodo, parentesco, diminutivo).
+type CharacterAlias struct {
+	ID          uuid.UUID `json:"id"`
+	CharacterID uuid.UUID `json:"character_id"`
+	Alias       string    `json:"alias"`
+	Normalized  string    `json:"-"` // Forma plegada (minusculas, sin tildes) para comparar
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Tipos de memoria narrativa.
+const (
+	MemoryKindEpisodic   = "episodic"   // Algo que paso (por defecto)
+	MemoryKindSemantic   = "semantic"   // Un hecho concreto extraido de una conversacion
+	MemoryKindReflection = "reflection" // Una conclusion sintetizada a partir de otras memorias
+)
+
 type NarrativeMemory struct {
 	ID                 uuid.UUID       `json:"id"`
 	CloneProfileID     uuid.UUID       `json:"clone_profile_id"`
 	RelatedCharacterID *uuid.UUID      `json:"related_character_id,omitempty"`
 	Content            string          `json:"content"`
 	Embedding          pgvector.Vector `json:"embedding"`
+	EmbeddingModel     string          `json:"embedding_model,omitempty"` // Modelo que genero Embedding
 	Importance         int             `json:"importance"`
 	EmotionalWeight    int             `json:"emotional_weight"` // 1-10 escala de carga emocional
 	EmotionalIntensity int             `json:"emotional_intensity"`
 	EmotionCategory    string          `json:"emotion_category"`
-	SentimentLabel     string          `json:"sentiment_label"` // Ira, Alegria, Miedo, etc.
+	SentimentLabel     string          `json:"sentiment_label"`             // Ira, Alegria, Miedo, etc.
+	Kind               string          `json:"kind"`                        // episodic | semantic | reflection
+	SourceSessionID    *string         `json:"source_session_id,omitempty"` // Sesion consolidada que origino la memoria
+	SourceMemoryIDs    []uuid.UUID     `json:"source_memory_ids,omitempty"` // Memorias de las que sale una reflexion
+	IsCore             bool            `json:"is_core"`                     // Nuclear: nunca decae ni se archiva
+	ArchivedAt         *time.Time      `json:"archived_at,omitempty"`       // Olvidada por el job de decaimiento
+	RecallCount        int             `json:"recall_count"`                // Veces que entro al contexto de un turno
+	LastRecalledAt     *time.Time      `json:"last_recalled_at,omitempty"`
+	OccurrenceCount    int             `json:"occurrence_count"` // Repeticiones fusionadas en esta memoria
 	HappenedAt         time.Time       `json:"happened_at"`
 	CreatedAt          time.Time       `json:"created_at"`
 	UpdatedAt          time.Time       `json:"updated_at"`
@@ -73,4 +99,6 @@ type NarrativeOutput struct {
 	Summary        string   `json:"summary"`         // Resumen narrativo de lo sucedido (3ra persona)
 	ExtractedFacts []string `json:"extracted_facts"` // Lista de datos duros nuevos o hechos relevantes
 	EmotionalShift string   `json:"emotional_shift"` // Cambio en la dinamica
+	// Facts son los mismos datos como tripletas estructuradas para el almacen de hechos.
+	Facts []ExtractedFact `json:"facts,omitempty"`
 }
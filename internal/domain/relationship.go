@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Causas posibles de un cambio en los vectores de vinculo.
+const (
+	RelationshipCauseLLMDelta   = "llm_delta"
+	RelationshipCauseManualEdit = "manual_edit"
+)
+
+// RelationshipEvent registra un cambio en los vectores de un personaje (timeline del vinculo).
+type RelationshipEvent struct {
+	ID             uuid.UUID           `json:"id"`
+	CharacterID    uuid.UUID           `json:"character_id"`
+	CloneProfileID uuid.UUID           `json:"clone_profile_id"`
+	MessageID      string              `json:"message_id,omitempty"`
+	Cause          string              `json:"cause"`
+	Before         RelationshipVectors `json:"before"`
+	After          RelationshipVectors `json:"after"`
+	BondStatus     string              `json:"bond_status,omitempty"`
+	Dynamic        string              `json:"dynamic"` // Resultado de deriveBondDynamics sobre los vectores finales
+	CreatedAt      time.Time           `json:"created_at"`
+}
@@ -2,11 +2,36 @@ package domain
 
 import "time"
 
+// Estados derivados de una sesion (no se persisten, se calculan con Status).
+const (
+	SessionStatusActive  = "active"
+	SessionStatusClosed  = "closed"
+	SessionStatusExpired = "expired"
+)
+
 type Session struct {
-	ID           string              `json:"id"`
-	UserID       string              `json:"user_id"`
-	Token        string              `json:"token"`
-	ExpiresAt    time.Time           `json:"expires_at"`
-	Relationship RelationshipVectors `json:"relationship"`
-	CreatedAt    time.Time           `json:"created_at"`
+	ID                    string              `json:"id"`
+	UserID                string              `json:"user_id"`
+	CloneProfileID        string              `json:"clone_profile_id,omitempty"`         // Clon con el que se conversa (vacio en sesiones legacy)
+	SpeakingAsCharacterID string              `json:"speaking_as_character_id,omitempty"` // Personaje que encarna el usuario (opcional)
+	Token                 string              `json:"token"`
+	ExpiresAt             time.Time           `json:"expires_at"`
+	ClosedAt              *time.Time          `json:"closed_at,omitempty"`
+	Relationship          RelationshipVectors `json:"relationship"`
+	CreatedAt             time.Time           `json:"created_at"`
+}
+
+// Status devuelve el estado de la sesion en el instante now. Un cierre explicito tiene prioridad sobre la
+// expiracion, salvo que haya ocurrido ya vencida: esa sesion se cerro por expirar y sigue reportandose expired.
+func (s Session) Status(now time.Time) string {
+	if s.ClosedAt != nil {
+		if !s.ExpiresAt.IsZero() && !s.ClosedAt.Before(s.ExpiresAt) {
+			return SessionStatusExpired
+		}
+		return SessionStatusClosed
+	}
+	if !s.ExpiresAt.IsZero() && !now.Before(s.ExpiresAt) {
+		return SessionStatusExpired
+	}
+	return SessionStatusActive
 }
@@ -9,12 +9,25 @@ const (
 )
 
 type Trait struct {
-	ID         string    `json:"id"`
-	ProfileID  string    `json:"profile_id"`
-	Category   string    `json:"category"`
-	Trait      string    `json:"trait"`
-	Value      int       `json:"value"`
-	Confidence *float64  `json:"confidence,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID               string    `json:"id"`
+	ProfileID        string    `json:"profile_id"`
+	Category         string    `json:"category"`
+	Trait            string    `json:"trait"`
+	Value            int       `json:"value"`
+	Confidence       *float64  `json:"confidence,omitempty"`
+	ObservationCount int       `json:"observation_count"` // Observaciones acumuladas en la estimacion
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TraitObservation es una lectura puntual del analizador sobre un rasgo.
+type TraitObservation struct {
+	ID            string    `json:"id"`
+	ProfileID     string    `json:"profile_id"`
+	Category      string    `json:"category"`
+	Trait         string    `json:"trait"`
+	Value         int       `json:"value"`
+	Confidence    *float64  `json:"confidence,omitempty"`
+	EstimateAfter int       `json:"estimate_after"`
+	CreatedAt     time.Time `json:"created_at"`
 }
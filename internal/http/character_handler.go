@@ -0,0 +1,481 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+	"clone-llm/internal/service"
+)
+
+// CharacterHandler mantiene dependencias para endpoints de personajes/vinculos del clon.
+type CharacterHandler struct {
+	logger     *zap.Logger
+	profiles   repository.ProfileRepository
+	characters repository.CharacterRepository
+	events     repository.RelationshipEventRepository
+	aliases    repository.CharacterAliasRepository
+	// relationships registra en el timeline las ediciones manuales de vectores.
+	relationships *service.RelationshipService
+}
+
+// NewCharacterHandler crea una instancia de CharacterHandler con dependencias necesarias.
+func NewCharacterHandler(
+	logger *zap.Logger,
+	profiles repository.ProfileRepository,
+	characters repository.CharacterRepository,
+	events repository.RelationshipEventRepository,
+	aliases repository.CharacterAliasRepository,
+) *CharacterHandler {
+	return &CharacterHandler{
+		logger:     logger,
+		profiles:   profiles,
+		characters: characters,
+		events:     events,
+		aliases:    aliases,
+
+		relationships: service.NewRelationshipService(characters, nil, events),
+	}
+}
+
+// GetTimeline maneja GET /clones/:id/characters/:charID/timeline y devuelve la evolucion del vinculo.
+func (h *CharacterHandler) GetTimeline(c *gin.Context) {
+	character, ok := h.loadCharacter(c)
+	if !ok {
+		return
+	}
+
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = v
+	}
+
+	events, err := h.events.ListByCharacter(c.Request.Context(), character.ID, limit)
+	if err != nil {
+		h.logger.Error("list relationship events failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch timeline"})
+		return
+	}
+	if events == nil {
+		events = []domain.RelationshipEvent{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"character": character,
+		"events":    events,
+	})
+}
+
+const (
+	// maxCharacterFieldLength acota nombre, relacion, arquetipo y estado del vinculo.
+	maxCharacterFieldLength  = 120
+	defaultRelationshipValue = 50
+)
+
+// characterRequest es el cuerpo de creacion y edicion de personajes. Los campos nil no se tocan en PATCH.
+type characterRequest struct {
+	Name       *string `json:"name"`
+	Relation   *string `json:"relation"`
+	Archetype  *string `json:"archetype"`
+	BondStatus *string `json:"bond_status"`
+	Trust      *int    `json:"trust"`
+	Intimacy   *int    `json:"intimacy"`
+	Respect    *int    `json:"respect"`
+}
+
+// validate revisa largos y rangos 0-100. Devuelve el mensaje de error o "" si es valido.
+func (r characterRequest) validate() string {
+	texts := []struct {
+		field string
+		value *string
+	}{{"name", r.Name}, {"relation", r.Relation}, {"archetype", r.Archetype}, {"bond_status", r.BondStatus}}
+	for _, t := range texts {
+		if t.value != nil && len(strings.TrimSpace(*t.value)) > maxCharacterFieldLength {
+			return t.field + " is too long"
+		}
+	}
+	vectors := []struct {
+		field string
+		value *int
+	}{{"trust", r.Trust}, {"intimacy", r.Intimacy}, {"respect", r.Respect}}
+	for _, v := range vectors {
+		if v.value != nil && (*v.value < 0 || *v.value > 100) {
+			return v.field + " must be between 0 and 100"
+		}
+	}
+	return ""
+}
+
+// apply copia al personaje los campos presentes en el request.
+func (r characterRequest) apply(character *domain.Character) {
+	if r.Name != nil {
+		character.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.Relation != nil {
+		character.Relation = strings.TrimSpace(*r.Relation)
+	}
+	if r.Archetype != nil {
+		character.Archetype = strings.TrimSpace(*r.Archetype)
+	}
+	if r.BondStatus != nil {
+		character.BondStatus = strings.TrimSpace(*r.BondStatus)
+	}
+	if r.Trust != nil {
+		character.Relationship.Trust = *r.Trust
+	}
+	if r.Intimacy != nil {
+		character.Relationship.Intimacy = *r.Intimacy
+	}
+	if r.Respect != nil {
+		character.Relationship.Respect = *r.Respect
+	}
+}
+
+// ListCharacters maneja GET /clones/:id/characters.
+func (h *CharacterHandler) ListCharacters(c *gin.Context) {
+	profileID, ok := loadOwnedProfile(c, h.logger, h.profiles)
+	if !ok {
+		return
+	}
+
+	chars, err := h.characters.ListByProfileID(c.Request.Context(), profileID)
+	if err != nil {
+		h.logger.Error("list characters failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list characters"})
+		return
+	}
+	if chars == nil {
+		chars = []domain.Character{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"characters": chars})
+}
+
+// CreateCharacter maneja POST /clones/:id/characters. name y relation son obligatorios;
+// los vectores ausentes arrancan en 50.
+func (h *CharacterHandler) CreateCharacter(c *gin.Context) {
+	profileID, ok := loadOwnedProfile(c, h.logger, h.profiles)
+	if !ok {
+		return
+	}
+
+	var req characterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" || req.Relation == nil || strings.TrimSpace(*req.Relation) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and relation are required"})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	existing, err := h.characters.FindByName(c.Request.Context(), profileID, strings.TrimSpace(*req.Name))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Error("find character failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create character"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "character already exists"})
+		return
+	}
+
+	now := time.Now().UTC()
+	character := domain.Character{
+		ID:             uuid.New(),
+		CloneProfileID: profileID,
+		Relationship: domain.RelationshipVectors{
+			Trust:    defaultRelationshipValue,
+			Intimacy: defaultRelationshipValue,
+			Respect:  defaultRelationshipValue,
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	req.apply(&character)
+
+	if err := h.characters.Create(c.Request.Context(), character); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "character already exists"})
+			return
+		}
+		h.logger.Error("create character failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create character"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"character": character})
+}
+
+// GetCharacter maneja GET /clones/:id/characters/:charID e incluye sus apodos.
+func (h *CharacterHandler) GetCharacter(c *gin.Context) {
+	character, ok := h.loadCharacter(c)
+	if !ok {
+		return
+	}
+
+	aliases, err := h.aliases.ListByCharacter(c.Request.Context(), character.ID)
+	if err != nil {
+		h.logger.Error("list character aliases failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch aliases"})
+		return
+	}
+	for _, a := range aliases {
+		character.Aliases = append(character.Aliases, a.Alias)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"character": character})
+}
+
+// UpdateCharacter maneja PATCH /clones/:id/characters/:charID. Un cambio de vectores queda
+// en el timeline del vinculo con causa manual_edit.
+func (h *CharacterHandler) UpdateCharacter(c *gin.Context) {
+	character, ok := h.loadCharacter(c)
+	if !ok {
+		return
+	}
+
+	var req characterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.Name != nil {
+		// El nombre es la clave de FindByName y de la deteccion: se cambia agregando apodos, no aca.
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be changed"})
+		return
+	}
+	if req.Relation != nil && strings.TrimSpace(*req.Relation) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "relation cannot be empty"})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	before := character.Relationship
+	req.apply(character)
+	character.UpdatedAt = time.Now().UTC()
+
+	if err := h.characters.Update(c.Request.Context(), *character); err != nil {
+		h.logger.Error("update character failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update character"})
+		return
+	}
+	if character.Relationship != before {
+		if err := h.relationships.RecordEvent(c.Request.Context(), *character, before, domain.RelationshipCauseManualEdit, ""); err != nil {
+			// El timeline es auxiliar: la edicion ya quedo guardada.
+			h.logger.Warn("record manual edit failed", zap.Error(err), zap.String("character_id", character.ID.String()))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"character": character})
+}
+
+// DeleteCharacter maneja DELETE /clones/:id/characters/:charID.
+func (h *CharacterHandler) DeleteCharacter(c *gin.Context) {
+	character, ok := h.loadCharacter(c)
+	if !ok {
+		return
+	}
+
+	if err := h.characters.Delete(c.Request.Context(), character.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "character not found"})
+			return
+		}
+		h.logger.Error("delete character failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete character"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// maxAliasLength coincide con el VARCHAR(100) de character_aliases.
+const maxAliasLength = 100
+
+// ListAliases maneja GET /clones/:id/characters/:charID/aliases.
+func (h *CharacterHandler) ListAliases(c *gin.Context) {
+	character, ok := h.loadCharacter(c)
+	if !ok {
+		return
+	}
+
+	aliases, err := h.aliases.ListByCharacter(c.Request.Context(), character.ID)
+	if err != nil {
+		h.logger.Error("list character aliases failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch aliases"})
+		return
+	}
+	if aliases == nil {
+		aliases = []domain.CharacterAlias{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"aliases": aliases})
+}
+
+// CreateAlias maneja POST /clones/:id/characters/:charID/aliases. Un apodo equivalente
+// (mismas letras ignorando mayusculas y tildes) al nombre o a otro apodo devuelve 409.
+func (h *CharacterHandler) CreateAlias(c *gin.Context) {
+	character, ok := h.loadCharacter(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Alias string `json:"alias" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	alias := strings.TrimSpace(req.Alias)
+	normalized := service.NormalizeCharacterName(alias)
+	if normalized == "" || len(alias) > maxAliasLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alias"})
+		return
+	}
+
+	existing, err := h.aliases.ListByCharacter(c.Request.Context(), character.ID)
+	if err != nil {
+		h.logger.Error("list character aliases failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch aliases"})
+		return
+	}
+	if normalized == service.NormalizeCharacterName(character.Name) {
+		c.JSON(http.StatusConflict, gin.H{"error": "alias matches the character name"})
+		return
+	}
+	for _, a := range existing {
+		if a.Normalized == normalized {
+			c.JSON(http.StatusConflict, gin.H{"error": "alias already exists"})
+			return
+		}
+	}
+
+	created := domain.CharacterAlias{
+		ID:          uuid.New(),
+		CharacterID: character.ID,
+		Alias:       alias,
+		Normalized:  normalized,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := h.aliases.Create(c.Request.Context(), created); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "alias already exists"})
+			return
+		}
+		h.logger.Error("create character alias failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create alias"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"alias": created})
+}
+
+// DeleteAlias maneja DELETE /clones/:id/characters/:charID/aliases/:aliasID.
+func (h *CharacterHandler) DeleteAlias(c *gin.Context) {
+	character, ok := h.loadCharacter(c)
+	if !ok {
+		return
+	}
+	aliasID, err := uuid.Parse(c.Param("aliasID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alias id"})
+		return
+	}
+
+	if err := h.aliases.Delete(c.Request.Context(), character.ID, aliasID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "alias not found"})
+			return
+		}
+		h.logger.Error("delete character alias failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete alias"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// loadOwnedProfile resuelve :id y valida que el clon sea del usuario autenticado; lo comparten
+// los handlers que cuelgan de /clones/:id. Escribe la respuesta de error y devuelve false si algo falla.
+func loadOwnedProfile(c *gin.Context, logger *zap.Logger, profiles repository.ProfileRepository) (uuid.UUID, bool) {
+	profileID, _, ok := loadOwnedCloneProfile(c, logger, profiles)
+	return profileID, ok
+}
+
+// loadOwnedCloneProfile es loadOwnedProfile para los handlers que necesitan el perfil completo.
+func loadOwnedCloneProfile(c *gin.Context, logger *zap.Logger, profiles repository.ProfileRepository) (uuid.UUID, domain.CloneProfile, bool) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return uuid.Nil, domain.CloneProfile{}, false
+	}
+
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid profile id"})
+		return uuid.Nil, domain.CloneProfile{}, false
+	}
+
+	profile, err := profiles.GetByID(c.Request.Context(), profileID.String())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
+			return uuid.Nil, domain.CloneProfile{}, false
+		}
+		logger.Error("get profile failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch profile"})
+		return uuid.Nil, domain.CloneProfile{}, false
+	}
+	if !ensureOwner(c, profile.UserID, userID) {
+		return uuid.Nil, domain.CloneProfile{}, false
+	}
+	return profileID, profile, true
+}
+
+// loadCharacter resuelve :id y :charID y valida que el clon sea del usuario autenticado
+// y que el personaje pertenezca al clon. Escribe la respuesta de error y devuelve false si algo falla.
+func (h *CharacterHandler) loadCharacter(c *gin.Context) (*domain.Character, bool) {
+	profileID, ok := loadOwnedProfile(c, h.logger, h.profiles)
+	if !ok {
+		return nil, false
+	}
+	charID, err := uuid.Parse(c.Param("charID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid character id"})
+		return nil, false
+	}
+
+	character, err := h.characters.GetByID(c.Request.Context(), charID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Error("get character failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch character"})
+		return nil, false
+	}
+	if character == nil || character.CloneProfileID != profileID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "character not found"})
+		return nil, false
+	}
+	return character, true
+}
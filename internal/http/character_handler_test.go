@@ -0,0 +1,394 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+	"clone-llm/internal/service"
+)
+
+type mockProfileRepo struct {
+	profiles map[string]domain.CloneProfile
+}
+
+func (m *mockProfileRepo) Create(_ context.Context, profile domain.CloneProfile) error {
+	if m.profiles == nil {
+		m.profiles = make(map[string]domain.CloneProfile)
+	}
+	m.profiles[profile.ID] = profile
+	return nil
+}
+
+func (m *mockProfileRepo) GetByID(_ context.Context, id string) (domain.CloneProfile, error) {
+	p, ok := m.profiles[id]
+	if !ok {
+		return domain.CloneProfile{}, pgx.ErrNoRows
+	}
+	return p, nil
+}
+
+func (m *mockProfileRepo) GetByUserID(_ context.Context, userID string) (domain.CloneProfile, error) {
+	for _, p := range m.profiles {
+		if p.UserID == userID {
+			return p, nil
+		}
+	}
+	return domain.CloneProfile{}, pgx.ErrNoRows
+}
+
+func (m *mockProfileRepo) ListByUserID(_ context.Context, userID string) ([]domain.CloneProfile, error) {
+	var out []domain.CloneProfile
+	for _, p := range m.profiles {
+		if p.UserID == userID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockProfileRepo) UpdateTraitStability(_ context.Context, id string, stability float64) error {
+	p, ok := m.profiles[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	p.TraitStability = stability
+	m.profiles[id] = p
+	return nil
+}
+
+type mockCharacterRepo struct {
+	chars     map[uuid.UUID]domain.Character
+	createErr error
+}
+
+func (m *mockCharacterRepo) Create(_ context.Context, c domain.Character) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	if m.chars == nil {
+		m.chars = make(map[uuid.UUID]domain.Character)
+	}
+	m.chars[c.ID] = c
+	return nil
+}
+
+func (m *mockCharacterRepo) Update(_ context.Context, c domain.Character) error {
+	m.chars[c.ID] = c
+	return nil
+}
+
+func (m *mockCharacterRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Character, error) {
+	c, ok := m.chars[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &c, nil
+}
+
+func (m *mockCharacterRepo) ListByProfileID(_ context.Context, profileID uuid.UUID) ([]domain.Character, error) {
+	var out []domain.Character
+	for _, c := range m.chars {
+		if c.CloneProfileID == profileID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockCharacterRepo) FindByName(_ context.Context, profileID uuid.UUID, name string) (*domain.Character, error) {
+	for _, c := range m.chars {
+		if c.CloneProfileID == profileID && strings.EqualFold(c.Name, name) {
+			return &c, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockCharacterRepo) Delete(_ context.Context, id uuid.UUID) error {
+	if _, ok := m.chars[id]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(m.chars, id)
+	return nil
+}
+
+type mockRelationshipEventRepo struct {
+	events []domain.RelationshipEvent
+}
+
+func (m *mockRelationshipEventRepo) Create(_ context.Context, e domain.RelationshipEvent) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *mockRelationshipEventRepo) ListByCharacter(_ context.Context, characterID uuid.UUID, _ int) ([]domain.RelationshipEvent, error) {
+	var out []domain.RelationshipEvent
+	for _, e := range m.events {
+		if e.CharacterID == characterID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+type mockCharacterAliasRepo struct {
+	aliases   []domain.CharacterAlias
+	createErr error
+}
+
+func (m *mockCharacterAliasRepo) Create(_ context.Context, a domain.CharacterAlias) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	m.aliases = append(m.aliases, a)
+	return nil
+}
+
+func (m *mockCharacterAliasRepo) ListByCharacter(_ context.Context, characterID uuid.UUID) ([]domain.CharacterAlias, error) {
+	var out []domain.CharacterAlias
+	for _, a := range m.aliases {
+		if a.CharacterID == characterID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockCharacterAliasRepo) Delete(_ context.Context, characterID, aliasID uuid.UUID) error {
+	for i, a := range m.aliases {
+		if a.ID == aliasID && a.CharacterID == characterID {
+			m.aliases = append(m.aliases[:i], m.aliases[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func setupCharacterRouter(h *CharacterHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(authClaimsKey, service.Claims{UserID: "owner-1"})
+		c.Next()
+	})
+	r.GET("/clones/:id/characters", h.ListCharacters)
+	r.POST("/clones/:id/characters", h.CreateCharacter)
+	r.GET("/clones/:id/characters/:charID", h.GetCharacter)
+	r.PATCH("/clones/:id/characters/:charID", h.UpdateCharacter)
+	r.DELETE("/clones/:id/characters/:charID", h.DeleteCharacter)
+	r.GET("/clones/:id/characters/:charID/timeline", h.GetTimeline)
+	r.GET("/clones/:id/characters/:charID/aliases", h.ListAliases)
+	r.POST("/clones/:id/characters/:charID/aliases", h.CreateAlias)
+	r.DELETE("/clones/:id/characters/:charID/aliases/:aliasID", h.DeleteAlias)
+	return r
+}
+
+func TestCharacterHandlerGetTimeline_Success(t *testing.T) {
+	profileID := uuid.New()
+	charID := uuid.New()
+	profiles := &mockProfileRepo{profiles: map[string]domain.CloneProfile{profileID.String(): {ID: profileID.String(), UserID: "owner-1"}}}
+	chars := &mockCharacterRepo{chars: map[uuid.UUID]domain.Character{charID: {ID: charID, CloneProfileID: profileID, Name: "Juan"}}}
+	events := &mockRelationshipEventRepo{events: []domain.RelationshipEvent{{
+		ID:          uuid.New(),
+		CharacterID: charID,
+		Cause:       domain.RelationshipCauseLLMDelta,
+		Before:      domain.RelationshipVectors{Trust: 50, Intimacy: 70, Respect: 50},
+		After:       domain.RelationshipVectors{Trust: 40, Intimacy: 72, Respect: 50},
+		Dynamic:     "MODO: CELOS PATOLOGICOS",
+		CreatedAt:   time.Now().UTC(),
+	}}}
+	r := setupCharacterRouter(NewCharacterHandler(zap.NewNop(), profiles, chars, events, &mockCharacterAliasRepo{}))
+
+	rec := performRequest(r, http.MethodGet, "/clones/"+profileID.String()+"/characters/"+charID.String()+"/timeline", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Events []domain.RelationshipEvent `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Events) != 1 || body.Events[0].After.Trust != 40 {
+		t.Fatalf("unexpected events: %+v", body.Events)
+	}
+}
+
+func TestCharacterHandlerGetTimeline_CharacterFromAnotherProfile(t *testing.T) {
+	profileID := uuid.New()
+	charID := uuid.New()
+	profiles := &mockProfileRepo{profiles: map[string]domain.CloneProfile{profileID.String(): {ID: profileID.String(), UserID: "owner-1"}}}
+	chars := &mockCharacterRepo{chars: map[uuid.UUID]domain.Character{charID: {ID: charID, CloneProfileID: uuid.New()}}}
+	r := setupCharacterRouter(NewCharacterHandler(zap.NewNop(), profiles, chars, &mockRelationshipEventRepo{}, &mockCharacterAliasRepo{}))
+
+	rec := performRequest(r, http.MethodGet, "/clones/"+profileID.String()+"/characters/"+charID.String()+"/timeline", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+
+	rec = performRequest(r, http.MethodGet, "/clones/not-a-uuid/characters/"+charID.String()+"/timeline", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestCharacterHandlerGetTimeline_ForeignProfileForbidden(t *testing.T) {
+	profileID := uuid.New()
+	charID := uuid.New()
+	profiles := &mockProfileRepo{profiles: map[string]domain.CloneProfile{profileID.String(): {ID: profileID.String(), UserID: "intruso"}}}
+	chars := &mockCharacterRepo{chars: map[uuid.UUID]domain.Character{charID: {ID: charID, CloneProfileID: profileID}}}
+	r := setupCharacterRouter(NewCharacterHandler(zap.NewNop(), profiles, chars, &mockRelationshipEventRepo{}, &mockCharacterAliasRepo{}))
+
+	rec := performRequest(r, http.MethodGet, "/clones/"+profileID.String()+"/characters/"+charID.String()+"/timeline", nil)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestCharacterHandlerAliasesCRUD(t *testing.T) {
+	profileID := uuid.New()
+	charID := uuid.New()
+	profiles := &mockProfileRepo{profiles: map[string]domain.CloneProfile{profileID.String(): {ID: profileID.String(), UserID: "owner-1"}}}
+	chars := &mockCharacterRepo{chars: map[uuid.UUID]domain.Character{charID: {ID: charID, CloneProfileID: profileID, Name: "Mamá"}}}
+	aliases := &mockCharacterAliasRepo{}
+	r := setupCharacterRouter(NewCharacterHandler(zap.NewNop(), profiles, chars, &mockRelationshipEventRepo{}, aliases))
+	base := "/clones/" + profileID.String() + "/characters/" + charID.String() + "/aliases"
+
+	rec := performRequest(r, http.MethodPost, base, map[string]string{"alias": "  Mi Vieja "})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Alias domain.CharacterAlias `json:"alias"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if created.Alias.Alias != "Mi Vieja" || aliases.aliases[0].Normalized != "mi vieja" {
+		t.Fatalf("unexpected alias: %+v", aliases.aliases)
+	}
+
+	if rec := performRequest(r, http.MethodPost, base, map[string]string{"alias": "mi viéja"}); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for equivalent alias, got %d", rec.Code)
+	}
+	if rec := performRequest(r, http.MethodPost, base, map[string]string{"alias": "MAMA"}); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for alias equal to the name, got %d", rec.Code)
+	}
+	if rec := performRequest(r, http.MethodPost, base, map[string]string{"alias": " ¡! "}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for empty alias, got %d", rec.Code)
+	}
+
+	if rec := performRequest(r, http.MethodGet, base, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 on list, got %d", rec.Code)
+	}
+	if rec := performRequest(r, http.MethodDelete, base+"/"+created.Alias.ID.String(), nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204 on delete, got %d", rec.Code)
+	}
+	if rec := performRequest(r, http.MethodDelete, base+"/"+created.Alias.ID.String(), nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 on second delete, got %d", rec.Code)
+	}
+}
+
+func TestCharacterHandler_ConcurrentDuplicateIsConflict(t *testing.T) {
+	profileID := uuid.New()
+	charID := uuid.New()
+	profiles := &mockProfileRepo{profiles: map[string]domain.CloneProfile{profileID.String(): {ID: profileID.String(), UserID: "owner-1"}}}
+	// La validacion previa pasa, pero otro request inserto el mismo registro antes del INSERT.
+	chars := &mockCharacterRepo{chars: map[uuid.UUID]domain.Character{charID: {ID: charID, CloneProfileID: profileID, Name: "Ana"}}}
+	aliases := &mockCharacterAliasRepo{createErr: repository.ErrAlreadyExists}
+	r := setupCharacterRouter(NewCharacterHandler(zap.NewNop(), profiles, chars, &mockRelationshipEventRepo{}, aliases))
+
+	rec := performRequest(r, http.MethodPost, "/clones/"+profileID.String()+"/characters/"+charID.String()+"/aliases", map[string]string{"alias": "Anita"})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for a racing alias, got %d", rec.Code)
+	}
+
+	chars.createErr = repository.ErrAlreadyExists
+	rec = performRequest(r, http.MethodPost, "/clones/"+profileID.String()+"/characters", map[string]string{"name": "Beto", "relation": "jefe"})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for a racing character, got %d", rec.Code)
+	}
+}
+
+func TestCharacterHandlerCRUD(t *testing.T) {
+	profileID := uuid.New()
+	profiles := &mockProfileRepo{profiles: map[string]domain.CloneProfile{profileID.String(): {ID: profileID.String(), UserID: "owner-1"}}}
+	chars := &mockCharacterRepo{}
+	events := &mockRelationshipEventRepo{}
+	r := setupCharacterRouter(NewCharacterHandler(zap.NewNop(), profiles, chars, events, &mockCharacterAliasRepo{}))
+	base := "/clones/" + profileID.String() + "/characters"
+
+	if rec := performRequest(r, http.MethodPost, base, map[string]any{"name": "Juan", "relation": "amigo", "trust": 101}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for out-of-range trust, got %d", rec.Code)
+	}
+	if rec := performRequest(r, http.MethodPost, base, map[string]any{"name": "Juan"}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without relation, got %d", rec.Code)
+	}
+
+	rec := performRequest(r, http.MethodPost, base, map[string]any{"name": " Juan ", "relation": "amigo", "trust": 70})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Character domain.Character `json:"character"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := domain.RelationshipVectors{Trust: 70, Intimacy: 50, Respect: 50}
+	if created.Character.Name != "Juan" || created.Character.Relationship != want || created.Character.CloneProfileID != profileID {
+		t.Fatalf("unexpected character: %+v", created.Character)
+	}
+	if rec := performRequest(r, http.MethodPost, base, map[string]any{"name": "juan", "relation": "primo"}); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for duplicated name, got %d", rec.Code)
+	}
+
+	item := base + "/" + created.Character.ID.String()
+	if rec := performRequest(r, http.MethodPatch, item, map[string]any{"respect": -1}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for out-of-range respect, got %d", rec.Code)
+	}
+	rec = performRequest(r, http.MethodPatch, item, map[string]any{"bond_status": "distante", "intimacy": 20})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	stored := chars.chars[created.Character.ID]
+	if stored.BondStatus != "distante" || stored.Relationship.Intimacy != 20 || stored.Relation != "amigo" {
+		t.Fatalf("unexpected stored character: %+v", stored)
+	}
+	if len(events.events) != 1 || events.events[0].Cause != domain.RelationshipCauseManualEdit || events.events[0].Before != want {
+		t.Fatalf("expected one manual_edit event, got %+v", events.events)
+	}
+
+	if rec := performRequest(r, http.MethodPatch, item, map[string]any{"archetype": "mentor"}); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if len(events.events) != 1 {
+		t.Fatalf("edits without vector changes must not add events, got %d", len(events.events))
+	}
+
+	if rec := performRequest(r, http.MethodGet, item, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 on get, got %d", rec.Code)
+	}
+	if rec := performRequest(r, http.MethodDelete, item, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204 on delete, got %d", rec.Code)
+	}
+	if rec := performRequest(r, http.MethodGet, item, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 after delete, got %d", rec.Code)
+	}
+
+	rec = performRequest(r, http.MethodGet, base, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"characters":[]`) {
+		t.Fatalf("expected empty list, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -2,14 +2,20 @@ package http
 
 import (
 	"context"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 
 	"clone-llm/internal/domain"
+	"clone-llm/internal/llm"
 	"clone-llm/internal/repository"
 	"clone-llm/internal/service"
 )
@@ -19,6 +25,9 @@ type ChatHandler struct {
 	logger       *zap.Logger
 	sessions     repository.SessionRepository
 	messages     repository.MessageRepository
+	profiles     repository.ProfileRepository
+	characters   repository.CharacterRepository
+	sessionServ  *service.SessionService
 	analysisServ *service.AnalysisService
 	cloneServ    *service.CloneService
 }
@@ -28,6 +37,9 @@ func NewChatHandler(
 	logger *zap.Logger,
 	sessions repository.SessionRepository,
 	messages repository.MessageRepository,
+	profiles repository.ProfileRepository,
+	characters repository.CharacterRepository,
+	sessionServ *service.SessionService,
 	analysisServ *service.AnalysisService,
 	cloneServ *service.CloneService,
 ) *ChatHandler {
@@ -35,28 +47,57 @@ func NewChatHandler(
 		logger:       logger,
 		sessions:     sessions,
 		messages:     messages,
+		profiles:     profiles,
+		characters:   characters,
+		sessionServ:  sessionServ,
 		analysisServ: analysisServ,
 		cloneServ:    cloneServ,
 	}
 }
 
-// CreateSession maneja POST /session.
+// CreateSession maneja POST /session para el usuario autenticado.
+// profile_id liga la sesion a uno de sus clones; sin el, el chat usa el clon por defecto del usuario.
 func (h *ChatHandler) CreateSession(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
 	var req struct {
-		UserID string `json:"user_id" binding:"required"`
+		UserID    string `json:"user_id"`
+		ProfileID string `json:"profile_id"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		h.logger.Warn("invalid create session request", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
+	if req.UserID != "" && !ensureOwner(c, req.UserID, userID) {
+		return
+	}
+	if req.ProfileID != "" {
+		profile, err := h.profiles.GetByID(c.Request.Context(), req.ProfileID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
+				return
+			}
+			h.logger.Error("get profile failed", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch profile"})
+			return
+		}
+		if !ensureOwner(c, profile.UserID, userID) {
+			return
+		}
+	}
 
 	session := domain.Session{
-		ID:        uuid.NewString(),
-		UserID:    req.UserID,
-		Token:     uuid.NewString(),
-		ExpiresAt: time.Now().UTC().Add(24 * time.Hour),
-		CreatedAt: time.Now().UTC(),
+		ID:             uuid.NewString(),
+		UserID:         userID,
+		CloneProfileID: req.ProfileID,
+		Token:          uuid.NewString(),
+		ExpiresAt:      time.Now().UTC().Add(24 * time.Hour),
+		CreatedAt:      time.Now().UTC(),
 	}
 
 	if err := h.sessions.Create(c.Request.Context(), session); err != nil {
@@ -68,10 +109,15 @@ func (h *ChatHandler) CreateSession(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"session": session})
 }
 
-// PostMessage maneja POST /message.
+// PostMessage maneja POST /message. El remitente es el usuario del token.
 func (h *ChatHandler) PostMessage(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
 	var req struct {
-		UserID    string `json:"user_id" binding:"required"`
+		UserID    string `json:"user_id"`
 		SessionID string `json:"session_id"`
 		Content   string `json:"content" binding:"required"`
 		Role      string `json:"role" binding:"required"`
@@ -81,37 +127,27 @@ func (h *ChatHandler) PostMessage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
-
-	msg := domain.Message{
-		ID:        uuid.NewString(),
-		UserID:    req.UserID,
-		SessionID: req.SessionID,
-		Content:   req.Content,
-		Role:      req.Role,
-		CreatedAt: time.Now().UTC(),
+	if !h.authorizeMessage(c, userID, req.UserID, req.SessionID) {
+		return
 	}
+	req.UserID = userID
 
-	if err := h.messages.Create(c.Request.Context(), msg); err != nil {
+	msg, err := h.storeUserMessage(c.Request.Context(), req.UserID, req.SessionID, req.Content, req.Role)
+	if err != nil {
 		h.logger.Error("create message failed", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not post message"})
 		return
 	}
 
-	// Lanzamos el analisis de manera asincrona para no bloquear al usuario.
-	go func(userID, content string) {
-		h.logger.Info("analysis started", zap.String("user_id", userID))
-		if err := h.analysisServ.AnalyzeAndPersist(context.Background(), userID, content); err != nil {
-			h.logger.Warn("analysis failed", zap.Error(err), zap.String("user_id", userID))
-			return
-		}
-		h.logger.Info("analysis finished", zap.String("user_id", userID))
-	}(req.UserID, req.Content)
-
 	cloneMsg, _, err := h.cloneServ.Chat(c.Request.Context(), req.UserID, req.SessionID, req.Content)
 	if err != nil {
 		h.logger.Error("clone response failed", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":        "could not generate clone response",
+		status, errMsg, retryAfter := cloneErrorResponse(err)
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		c.JSON(status, gin.H{
+			"error":        errMsg,
 			"user_message": msg,
 		})
 		return
@@ -122,3 +158,303 @@ func (h *ChatHandler) PostMessage(c *gin.Context) {
 		"clone_message": cloneMsg,
 	})
 }
+
+// StreamMessage maneja GET/POST /message/stream y responde por Server-Sent Events.
+// Emite eventos "token" con fragmentos de public_response y un evento final "done" con el
+// mensaje persistido y el debug de la interaccion. GET acepta los campos como query params.
+func (h *ChatHandler) StreamMessage(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID    string `json:"user_id" form:"user_id"`
+		SessionID string `json:"session_id" form:"session_id"`
+		Content   string `json:"content" form:"content" binding:"required"`
+		Role      string `json:"role" form:"role"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		h.logger.Warn("invalid stream message request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if !h.authorizeMessage(c, userID, req.UserID, req.SessionID) {
+		return
+	}
+	req.UserID = userID
+	if req.Role == "" {
+		req.Role = "user"
+	}
+
+	msg, err := h.storeUserMessage(c.Request.Context(), req.UserID, req.SessionID, req.Content, req.Role)
+	if err != nil {
+		h.logger.Error("create message failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not post message"})
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	cloneMsg, debug, err := h.cloneServ.ChatStream(c.Request.Context(), req.UserID, req.SessionID, req.Content, func(token string) error {
+		c.SSEvent("token", gin.H{"text": token})
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	})
+	if err != nil {
+		h.logger.Error("clone stream failed", zap.Error(err))
+		// El status 200 ya se envio: el evento informa el status que hubiera correspondido.
+		status, errMsg, retryAfter := cloneErrorResponse(err)
+		event := gin.H{
+			"error":        errMsg,
+			"status":       status,
+			"user_message": msg,
+		}
+		if retryAfter > 0 {
+			event["retry_after"] = retryAfter
+		}
+		c.SSEvent("error", event)
+		c.Writer.Flush()
+		return
+	}
+
+	c.SSEvent("done", gin.H{
+		"user_message":      msg,
+		"clone_message":     cloneMsg,
+		"interaction_debug": debug,
+	})
+	c.Writer.Flush()
+}
+
+// ListSessions maneja GET /sessions y devuelve las sesiones del usuario con su estado.
+func (h *ChatHandler) ListSessions(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessions.ListByUserID(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("list sessions failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list sessions"})
+		return
+	}
+
+	now := time.Now().UTC()
+	views := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, sessionView{Session: s, Status: s.Status(now)})
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": views})
+}
+
+// GetSession maneja GET /sessions/:id y devuelve la sesion con su historial para retomarla.
+func (h *ChatHandler) GetSession(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	session, ok := h.loadOwnedSession(c, c.Param("id"), userID)
+	if !ok {
+		return
+	}
+
+	// Si vencio mientras nadie la usaba, la cerramos ahora para que corran los hooks de fin.
+	if err := h.sessionServ.EnsureActive(c.Request.Context(), session); err != nil &&
+		!errors.Is(err, service.ErrSessionClosed) && !errors.Is(err, service.ErrSessionExpired) {
+		h.logger.Error("check session failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch session"})
+		return
+	}
+
+	messages, err := h.messages.ListBySessionID(c.Request.Context(), session.ID)
+	if err != nil {
+		h.logger.Error("list session messages failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch messages"})
+		return
+	}
+	if messages == nil {
+		messages = []domain.Message{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session":  sessionView{Session: *session, Status: session.Status(time.Now().UTC())},
+		"messages": messages,
+	})
+}
+
+// CloseSession maneja POST /sessions/:id/close y dispara los hooks de fin de sesion.
+func (h *ChatHandler) CloseSession(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	session, ok := h.loadOwnedSession(c, c.Param("id"), userID)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionServ.Close(c.Request.Context(), session); err != nil {
+		if errors.Is(err, service.ErrSessionClosed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "session already closed"})
+			return
+		}
+		h.logger.Error("close session failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not close session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"session": sessionView{Session: *session, Status: session.Status(time.Now().UTC())}})
+}
+
+// SetSpeakingAs maneja PUT /sessions/:id/speaking-as: fija (o limpia con character_id vacio)
+// el personaje del clon que encarna el usuario en la sesion.
+func (h *ChatHandler) SetSpeakingAs(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	session, ok := h.loadOwnedSession(c, c.Param("id"), userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CharacterID string `json:"character_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if req.CharacterID != "" {
+		charID, err := uuid.Parse(req.CharacterID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid character id"})
+			return
+		}
+		if session.CloneProfileID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session is not bound to a clone"})
+			return
+		}
+		character, err := h.characters.GetByID(c.Request.Context(), charID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			h.logger.Error("get character failed", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch character"})
+			return
+		}
+		if character == nil || character.CloneProfileID.String() != session.CloneProfileID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "character not found"})
+			return
+		}
+	}
+
+	if err := h.sessions.SetSpeakingAs(c.Request.Context(), session.ID, req.CharacterID); err != nil {
+		h.logger.Error("set speaking as failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update session"})
+		return
+	}
+	session.SpeakingAsCharacterID = req.CharacterID
+
+	c.JSON(http.StatusOK, gin.H{"session": sessionView{Session: *session, Status: session.Status(time.Now().UTC())}})
+}
+
+// sessionView agrega el estado calculado a la sesion en las respuestas.
+type sessionView struct {
+	domain.Session
+	Status string `json:"status"`
+}
+
+// authorizeMessage valida que el user_id enviado (si vino) y la sesion pertenezcan al usuario autenticado
+// y que la sesion siga activa. Escribe la respuesta de error y devuelve false si algo falla.
+func (h *ChatHandler) authorizeMessage(c *gin.Context, userID, bodyUserID, sessionID string) bool {
+	if bodyUserID != "" && !ensureOwner(c, bodyUserID, userID) {
+		return false
+	}
+	if sessionID == "" {
+		return true
+	}
+
+	session, ok := h.loadOwnedSession(c, sessionID, userID)
+	if !ok {
+		return false
+	}
+
+	switch err := h.sessionServ.EnsureActive(c.Request.Context(), session); {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrSessionClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "session closed"})
+	case errors.Is(err, service.ErrSessionExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "session expired"})
+	default:
+		h.logger.Error("check session failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not validate session"})
+	}
+	return false
+}
+
+// loadOwnedSession busca la sesion y valida que sea del usuario. Escribe 404/403/500 y devuelve false si falla.
+func (h *ChatHandler) loadOwnedSession(c *gin.Context, sessionID, userID string) (*domain.Session, bool) {
+	session, err := h.sessions.GetByID(c.Request.Context(), sessionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return nil, false
+		}
+		h.logger.Error("get session failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch session"})
+		return nil, false
+	}
+	if !ensureOwner(c, session.UserID, userID) {
+		return nil, false
+	}
+	return &session, true
+}
+
+// storeUserMessage persiste el mensaje del usuario y dispara el analisis de rasgos en segundo plano.
+func (h *ChatHandler) storeUserMessage(ctx context.Context, userID, sessionID, content, role string) (domain.Message, error) {
+	msg := domain.Message{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		SessionID: sessionID,
+		Content:   content,
+		Role:      role,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := h.messages.Create(ctx, msg); err != nil {
+		return domain.Message{}, err
+	}
+
+	// Lanzamos el analisis de manera asincrona para no bloquear al usuario.
+	go func(msg domain.Message) {
+		h.logger.Info("analysis started", zap.String("user_id", msg.UserID))
+		if err := h.analysisServ.AnalyzeMessageAndPersist(context.Background(), msg); err != nil {
+			h.logger.Warn("analysis failed", zap.Error(err), zap.String("user_id", msg.UserID))
+			return
+		}
+		h.logger.Info("analysis finished", zap.String("user_id", msg.UserID))
+	}(msg)
+
+	return msg, nil
+}
+
+// cloneErrorResponse traduce los errores tipados del proveedor LLM a status HTTP, mensaje y
+// segundos de Retry-After (0 si no aplica). El resto es un 500.
+func cloneErrorResponse(err error) (int, string, int) {
+	retryAfter := int(math.Ceil(llm.RetryAfter(err).Seconds()))
+	switch {
+	case errors.Is(err, llm.ErrRateLimited):
+		return http.StatusTooManyRequests, "llm rate limited, retry later", retryAfter
+	case errors.Is(err, llm.ErrContextTooLong):
+		return http.StatusRequestEntityTooLarge, "conversation too long for the llm context", 0
+	case errors.Is(err, llm.ErrProviderDown):
+		return http.StatusServiceUnavailable, "llm provider unavailable, retry later", retryAfter
+	}
+	return http.StatusInternalServerError, "could not generate clone response", 0
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"clone-llm/internal/llm"
+)
+
+func TestCloneErrorResponse(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter int
+	}{
+		{"rate limited", fmt.Errorf("llm generate: %w", &llm.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 1500 * time.Millisecond}), http.StatusTooManyRequests, 2},
+		{"context too long", fmt.Errorf("llm generate: %w", &llm.APIError{StatusCode: http.StatusBadRequest, Message: "prompt is too long: 210000 tokens"}), http.StatusRequestEntityTooLarge, 0},
+		{"provider down", fmt.Errorf("llm generate stream: %w", llm.ErrProviderDown), http.StatusServiceUnavailable, 0},
+		{"other", errors.New("persist clone message: boom"), http.StatusInternalServerError, 0},
+	}
+	for _, tc := range cases {
+		status, msg, retryAfter := cloneErrorResponse(tc.err)
+		if status != tc.status || retryAfter != tc.retryAfter || msg == "" {
+			t.Fatalf("%s: got status=%d retry_after=%d msg=%q", tc.name, status, retryAfter, msg)
+		}
+	}
+}
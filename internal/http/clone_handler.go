@@ -12,6 +12,7 @@ import (
 
 	"clone-llm/internal/domain"
 	"clone-llm/internal/repository"
+	"clone-llm/internal/service"
 )
 
 // CloneHandler mantiene dependencias para endpoints del clon.
@@ -34,25 +35,44 @@ func NewCloneHandler(
 	}
 }
 
-// InitClone maneja POST /clone/init.
+// InitClone maneja POST /clone/init. El duenio del clon es el usuario del token.
 func (h *CloneHandler) InitClone(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
 	var req struct {
-		UserID string `json:"user_id" binding:"required"`
-		Name   string `json:"name" binding:"required"`
-		Bio    string `json:"bio"`
+		UserID         string   `json:"user_id"`
+		Name           string   `json:"name" binding:"required"`
+		Bio            string   `json:"bio"`
+		TraitStability *float64 `json:"trait_stability"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn("invalid init clone request", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
+	if req.UserID != "" && !ensureOwner(c, req.UserID, userID) {
+		return
+	}
+
+	stability := domain.DefaultTraitStability
+	if req.TraitStability != nil {
+		if !validTraitStability(*req.TraitStability) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "trait_stability must be between 0 and 1"})
+			return
+		}
+		stability = *req.TraitStability
+	}
 
 	profile := domain.CloneProfile{
-		ID:        uuid.NewString(),
-		UserID:    req.UserID,
-		Name:      req.Name,
-		Bio:       req.Bio,
-		CreatedAt: time.Now().UTC(),
+		ID:             uuid.NewString(),
+		UserID:         userID,
+		Name:           req.Name,
+		Bio:            req.Bio,
+		TraitStability: stability,
+		CreatedAt:      time.Now().UTC(),
 	}
 
 	if err := h.profiles.Create(c.Request.Context(), profile); err != nil {
@@ -64,11 +84,20 @@ func (h *CloneHandler) InitClone(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"profile": profile})
 }
 
-// GetCloneProfile maneja GET /clone/profile y devuelve el perfil psicologico del clon.
+// GetCloneProfile maneja GET /clones/:id y devuelve el perfil psicologico de un clon del usuario autenticado.
 func (h *CloneHandler) GetCloneProfile(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+	_, profile, ok := loadOwnedCloneProfile(c, h.logger, h.profiles)
+	if !ok {
+		return
+	}
+	h.respondCloneProfile(c, profile)
+}
+
+// GetDefaultCloneProfile maneja GET /clone/profile, la ruta previa a los clones multiples: devuelve
+// el clon por defecto del usuario autenticado.
+func (h *CloneHandler) GetDefaultCloneProfile(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
 		return
 	}
 
@@ -82,16 +111,79 @@ func (h *CloneHandler) GetCloneProfile(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch profile"})
 		return
 	}
+	h.respondCloneProfile(c, profile)
+}
 
+func (h *CloneHandler) respondCloneProfile(c *gin.Context, profile domain.CloneProfile) {
 	traits, err := h.traits.FindByProfileID(c.Request.Context(), profile.ID)
 	if err != nil {
 		h.logger.Error("get traits failed", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch traits"})
 		return
 	}
+	service.HydrateBig5(&profile, traits)
 
 	c.JSON(http.StatusOK, gin.H{
 		"profile": profile,
 		"traits":  traits,
 	})
 }
+
+// ListClones maneja GET /clones y devuelve los clones del usuario autenticado.
+func (h *CloneHandler) ListClones(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	profiles, err := h.profiles.ListByUserID(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("list clone profiles failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list clones"})
+		return
+	}
+	if profiles == nil {
+		profiles = []domain.CloneProfile{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"clones": profiles})
+}
+
+// UpdateTraitStability maneja PUT /clones/:id/stability y ajusta cuanto resisten los rasgos a nuevas observaciones.
+func (h *CloneHandler) UpdateTraitStability(c *gin.Context) {
+	profileID, ok := loadOwnedProfile(c, h.logger, h.profiles)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TraitStability *float64 `json:"trait_stability" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if !validTraitStability(*req.TraitStability) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "trait_stability must be between 0 and 1"})
+		return
+	}
+
+	if err := h.profiles.UpdateTraitStability(c.Request.Context(), profileID.String(), *req.TraitStability); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
+			return
+		}
+		h.logger.Error("update trait stability failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update stability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile_id":      profileID,
+		"trait_stability": *req.TraitStability,
+	})
+}
+
+func validTraitStability(v float64) bool {
+	return v >= 0 && v <= 1
+}
@@ -49,3 +49,22 @@ func GetAuthClaims(c *gin.Context) (service.Claims, bool) {
 	claims, ok := val.(service.Claims)
 	return claims, ok
 }
+
+// requireUserID devuelve el usuario autenticado. Si no hay claims responde 401 y devuelve false.
+func requireUserID(c *gin.Context) (string, bool) {
+	claims, ok := GetAuthClaims(c)
+	if !ok || strings.TrimSpace(claims.UserID) == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return "", false
+	}
+	return claims.UserID, true
+}
+
+// ensureOwner responde 403 y devuelve false si el recurso pertenece a otro usuario.
+func ensureOwner(c *gin.Context, ownerID, userID string) bool {
+	if ownerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return false
+	}
+	return true
+}
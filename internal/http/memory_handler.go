@@ -0,0 +1,394 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+	"clone-llm/internal/service"
+)
+
+const (
+	defaultMemoryListLimit = 50
+	maxMemoryListLimit     = 200
+	maxMemoryContentLength = 4000
+	// dateOnlyLayout permite filtrar por dia (?from=2024-05-01) ademas de RFC3339.
+	dateOnlyLayout = "2006-01-02"
+)
+
+// MemoryHandler expone la memoria narrativa del clon para inspeccion y edicion.
+type MemoryHandler struct {
+	logger     *zap.Logger
+	profiles   repository.ProfileRepository
+	characters repository.CharacterRepository
+	memories   repository.MemoryRepository
+	narrative  *service.NarrativeService
+}
+
+// NewMemoryHandler crea una instancia de MemoryHandler con dependencias necesarias.
+func NewMemoryHandler(
+	logger *zap.Logger,
+	profiles repository.ProfileRepository,
+	characters repository.CharacterRepository,
+	memories repository.MemoryRepository,
+	narrative *service.NarrativeService,
+) *MemoryHandler {
+	return &MemoryHandler{
+		logger:     logger,
+		profiles:   profiles,
+		characters: characters,
+		memories:   memories,
+		narrative:  narrative,
+	}
+}
+
+// memoryResponse es la vista publica de una memoria: oculta el embedding, que solo agrega ruido.
+type memoryResponse struct {
+	domain.NarrativeMemory
+	Embedding any `json:"embedding,omitempty"`
+}
+
+// scoredMemoryResponse agrega los puntajes de la busqueda para depurar la recuperacion.
+type scoredMemoryResponse struct {
+	memoryResponse
+	Similarity float64 `json:"similarity"`
+	Score      float64 `json:"score"`
+}
+
+func newMemoryResponses(memories []domain.NarrativeMemory) []memoryResponse {
+	out := make([]memoryResponse, 0, len(memories))
+	for _, m := range memories {
+		out = append(out, memoryResponse{NarrativeMemory: m})
+	}
+	return out
+}
+
+// ListMemories maneja GET /clones/:id/memories. Filtros opcionales: emotion, min_importance,
+// character_id, from, to (RFC3339 o YYYY-MM-DD; to es exclusivo, o inclusivo si es solo fecha), limit, offset
+// e include_archived (las olvidadas por el job de decaimiento quedan fuera por defecto).
+func (h *MemoryHandler) ListMemories(c *gin.Context) {
+	profileID, ok := loadOwnedProfile(c, h.logger, h.profiles)
+	if !ok {
+		return
+	}
+
+	filter := repository.MemoryFilter{
+		EmotionCategory: strings.TrimSpace(c.Query("emotion")),
+		Limit:           defaultMemoryListLimit,
+	}
+	intParams := []struct {
+		name string
+		min  int
+		max  int
+		dst  *int
+	}{
+		{"min_importance", 1, 10, &filter.MinImportance},
+		{"limit", 1, maxMemoryListLimit, &filter.Limit},
+		{"offset", 0, -1, &filter.Offset},
+	}
+	for _, p := range intParams {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < p.min || (p.max >= 0 && v > p.max) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + p.name})
+			return
+		}
+		*p.dst = v
+	}
+	if raw := c.Query("character_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid character_id"})
+			return
+		}
+		filter.CharacterID = &id
+	}
+	if raw := c.Query("include_archived"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid include_archived"})
+			return
+		}
+		filter.IncludeArchived = v
+	}
+	var err error
+	if filter.From, err = parseMemoryDate(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+		return
+	}
+	if filter.To, err = parseMemoryDate(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+		return
+	}
+
+	memories, err := h.memories.List(c.Request.Context(), profileID, filter)
+	if err != nil {
+		h.logger.Error("list memories failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list memories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"memories": newMemoryResponses(memories)})
+}
+
+// SearchMemories maneja GET /clones/:id/memories/search?q=...&k=N y devuelve similitud y score de cada resultado.
+func (h *MemoryHandler) SearchMemories(c *gin.Context) {
+	profileID, ok := loadOwnedProfile(c, h.logger, h.profiles)
+	if !ok {
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	k := 0
+	if raw := c.Query("k"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid k"})
+			return
+		}
+		k = v
+	}
+
+	scored, err := h.narrative.SearchMemories(c.Request.Context(), profileID, query, k)
+	if err != nil {
+		if errors.Is(err, service.ErrNarrativeServiceNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "memory search not available"})
+			return
+		}
+		h.logger.Error("search memories failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not search memories"})
+		return
+	}
+
+	results := make([]scoredMemoryResponse, 0, len(scored))
+	for _, sm := range scored {
+		results = append(results, scoredMemoryResponse{
+			memoryResponse: memoryResponse{NarrativeMemory: sm.NarrativeMemory},
+			Similarity:     sm.Similarity,
+			Score:          sm.Score,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"query": query, "results": results})
+}
+
+// GetMemory maneja GET /clones/:id/memories/:memoryID.
+func (h *MemoryHandler) GetMemory(c *gin.Context) {
+	memory, ok := h.loadMemory(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"memory": memoryResponse{NarrativeMemory: *memory}})
+}
+
+// UpdateMemory maneja PATCH /clones/:id/memories/:memoryID. Editar el contenido recalcula el embedding;
+// related_character_id vacio desliga la memoria del personaje e is_core la protege del olvido.
+func (h *MemoryHandler) UpdateMemory(c *gin.Context) {
+	memory, ok := h.loadMemory(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content            *string    `json:"content"`
+		Importance         *int       `json:"importance"`
+		EmotionalWeight    *int       `json:"emotional_weight"`
+		EmotionalIntensity *int       `json:"emotional_intensity"`
+		EmotionCategory    *string    `json:"emotion_category"`
+		RelatedCharacterID *string    `json:"related_character_id"`
+		HappenedAt         *time.Time `json:"happened_at"`
+		IsCore             *bool      `json:"is_core"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	ranges := []struct {
+		field    string
+		value    *int
+		min, max int
+	}{
+		{"importance", req.Importance, 1, 10},
+		{"emotional_weight", req.EmotionalWeight, 1, 10},
+		{"emotional_intensity", req.EmotionalIntensity, 0, 100},
+	}
+	for _, r := range ranges {
+		if r.value != nil && (*r.value < r.min || *r.value > r.max) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": r.field + " must be between " + strconv.Itoa(r.min) + " and " + strconv.Itoa(r.max)})
+			return
+		}
+	}
+
+	reembed := false
+	if req.Content != nil {
+		content := strings.TrimSpace(*req.Content)
+		if content == "" || len(content) > maxMemoryContentLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid content"})
+			return
+		}
+		reembed = content != memory.Content
+		memory.Content = content
+	}
+	if req.Importance != nil {
+		memory.Importance = *req.Importance
+	}
+	if req.EmotionalWeight != nil {
+		memory.EmotionalWeight = *req.EmotionalWeight
+	}
+	if req.EmotionalIntensity != nil {
+		memory.EmotionalIntensity = *req.EmotionalIntensity
+	}
+	if req.EmotionCategory != nil {
+		category := strings.ToUpper(strings.TrimSpace(*req.EmotionCategory))
+		if category == "" {
+			category = "NEUTRAL"
+		}
+		memory.EmotionCategory = category
+		memory.SentimentLabel = category
+	}
+	if req.HappenedAt != nil {
+		memory.HappenedAt = req.HappenedAt.UTC()
+	}
+	if req.IsCore != nil {
+		memory.IsCore = *req.IsCore
+	}
+	if req.RelatedCharacterID != nil {
+		related, ok := h.resolveRelatedCharacter(c, memory.CloneProfileID, *req.RelatedCharacterID)
+		if !ok {
+			return
+		}
+		memory.RelatedCharacterID = related
+	}
+
+	updated, err := h.narrative.UpdateMemory(c.Request.Context(), *memory, reembed)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "memory not found"})
+		case errors.Is(err, service.ErrNarrativeServiceNotConfigured):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "memory editing not available"})
+		default:
+			h.logger.Error("update memory failed", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update memory"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"memory": memoryResponse{NarrativeMemory: updated}})
+}
+
+// DeleteMemory maneja DELETE /clones/:id/memories/:memoryID.
+func (h *MemoryHandler) DeleteMemory(c *gin.Context) {
+	memory, ok := h.loadMemory(c)
+	if !ok {
+		return
+	}
+
+	if err := h.narrative.DeleteMemory(c.Request.Context(), memory.CloneProfileID, memory.ID); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "memory not found"})
+		case errors.Is(err, service.ErrNarrativeServiceNotConfigured):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "memory editing not available"})
+		default:
+			h.logger.Error("delete memory failed", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete memory"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// loadMemory resuelve :id y :memoryID y valida que la memoria pertenezca al clon del usuario autenticado.
+// Escribe la respuesta de error y devuelve false si algo falla.
+func (h *MemoryHandler) loadMemory(c *gin.Context) (*domain.NarrativeMemory, bool) {
+	profileID, ok := loadOwnedProfile(c, h.logger, h.profiles)
+	if !ok {
+		return nil, false
+	}
+
+	memoryID, err := uuid.Parse(c.Param("memoryID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid memory id"})
+		return nil, false
+	}
+
+	memory, err := h.memories.GetByID(c.Request.Context(), memoryID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "memory not found"})
+			return nil, false
+		}
+		h.logger.Error("get memory failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch memory"})
+		return nil, false
+	}
+	if memory.CloneProfileID != profileID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "memory not found"})
+		return nil, false
+	}
+	return memory, true
+}
+
+// resolveRelatedCharacter valida que el personaje exista y sea del mismo clon. "" desliga la memoria.
+func (h *MemoryHandler) resolveRelatedCharacter(c *gin.Context, profileID uuid.UUID, raw string) (*uuid.UUID, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid related_character_id"})
+		return nil, false
+	}
+	character, err := h.characters.GetByID(c.Request.Context(), id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Error("get character failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch character"})
+		return nil, false
+	}
+	if character == nil || character.CloneProfileID != profileID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "character does not belong to this clone"})
+		return nil, false
+	}
+	return &id, true
+}
+
+// parseMemoryDate acepta RFC3339 o YYYY-MM-DD. Con endOfDay, una fecha sin hora se corre al dia
+// siguiente para que el limite superior exclusivo incluya el dia pedido.
+func parseMemoryDate(raw string, endOfDay bool) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	t, err := time.Parse(dateOnlyLayout, raw)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
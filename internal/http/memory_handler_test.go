@@ -0,0 +1,256 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgvector "github.com/pgvector/pgvector-go"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+	"clone-llm/internal/service"
+)
+
+type mockMemoryRepo struct {
+	memories   map[uuid.UUID]domain.NarrativeMemory
+	lastFilter repository.MemoryFilter
+	search     []repository.ScoredMemory
+}
+
+func (m *mockMemoryRepo) Create(_ context.Context, memory domain.NarrativeMemory) error {
+	if m.memories == nil {
+		m.memories = make(map[uuid.UUID]domain.NarrativeMemory)
+	}
+	m.memories[memory.ID] = memory
+	return nil
+}
+
+func (m *mockMemoryRepo) Search(context.Context, uuid.UUID, pgvector.Vector, int, float64) ([]repository.ScoredMemory, error) {
+	return m.search, nil
+}
+
+func (m *mockMemoryRepo) ListByCharacter(context.Context, uuid.UUID, int) ([]domain.NarrativeMemory, error) {
+	return nil, nil
+}
+
+func (m *mockMemoryRepo) GetRecentHighImpactByProfile(context.Context, uuid.UUID, int, int, int) ([]domain.NarrativeMemory, error) {
+	return nil, nil
+}
+
+func (m *mockMemoryRepo) ListBySourceSession(context.Context, string) ([]domain.NarrativeMemory, error) {
+	return nil, nil
+}
+
+func (m *mockMemoryRepo) List(_ context.Context, profileID uuid.UUID, filter repository.MemoryFilter) ([]domain.NarrativeMemory, error) {
+	m.lastFilter = filter
+	var out []domain.NarrativeMemory
+	for _, mem := range m.memories {
+		if mem.CloneProfileID == profileID {
+			out = append(out, mem)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockMemoryRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.NarrativeMemory, error) {
+	mem, ok := m.memories[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &mem, nil
+}
+
+func (m *mockMemoryRepo) Update(_ context.Context, memory domain.NarrativeMemory) error {
+	if _, ok := m.memories[memory.ID]; !ok {
+		return pgx.ErrNoRows
+	}
+	m.memories[memory.ID] = memory
+	return nil
+}
+
+func (m *mockMemoryRepo) Delete(_ context.Context, id uuid.UUID) error {
+	if _, ok := m.memories[id]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(m.memories, id)
+	return nil
+}
+
+func (m *mockMemoryRepo) MarkRecalled(context.Context, []uuid.UUID, time.Time) error { return nil }
+
+func (m *mockMemoryRepo) FindNearest(context.Context, uuid.UUID, *uuid.UUID, pgvector.Vector) (*repository.ScoredMemory, error) {
+	return nil, nil
+}
+
+func (m *mockMemoryRepo) MergeOccurrence(context.Context, domain.NarrativeMemory) error { return nil }
+
+// mockEmbeddingLLM devuelve un embedding fijo y cuenta las llamadas.
+type mockEmbeddingLLM struct {
+	embeds int
+}
+
+func (m *mockEmbeddingLLM) CreateEmbedding(context.Context, string) ([]float32, error) {
+	m.embeds++
+	return []float32{0.1, 0.2}, nil
+}
+
+func (m *mockEmbeddingLLM) Generate(context.Context, string) (string, error) { return "", nil }
+
+func setupMemoryRouter(h *MemoryHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(authClaimsKey, service.Claims{UserID: "owner-1"})
+		c.Next()
+	})
+	r.GET("/clones/:id/memories", h.ListMemories)
+	r.GET("/clones/:id/memories/search", h.SearchMemories)
+	r.GET("/clones/:id/memories/:memoryID", h.GetMemory)
+	r.PATCH("/clones/:id/memories/:memoryID", h.UpdateMemory)
+	r.DELETE("/clones/:id/memories/:memoryID", h.DeleteMemory)
+	return r
+}
+
+type memoryTestEnv struct {
+	profileID uuid.UUID
+	memoryID  uuid.UUID
+	charID    uuid.UUID
+	memories  *mockMemoryRepo
+	llm       *mockEmbeddingLLM
+	router    *gin.Engine
+}
+
+func newMemoryTestEnv() memoryTestEnv {
+	env := memoryTestEnv{profileID: uuid.New(), memoryID: uuid.New(), charID: uuid.New(), llm: &mockEmbeddingLLM{}}
+	profiles := &mockProfileRepo{profiles: map[string]domain.CloneProfile{env.profileID.String(): {ID: env.profileID.String(), UserID: "owner-1"}}}
+	chars := &mockCharacterRepo{chars: map[uuid.UUID]domain.Character{env.charID: {ID: env.charID, CloneProfileID: env.profileID, Name: "Ana"}}}
+	env.memories = &mockMemoryRepo{memories: map[uuid.UUID]domain.NarrativeMemory{env.memoryID: {
+		ID:              env.memoryID,
+		CloneProfileID:  env.profileID,
+		Content:         "Ana me presto plata",
+		Embedding:       pgvector.NewVector([]float32{1, 2, 3}),
+		Importance:      5,
+		EmotionalWeight: 5,
+		EmotionCategory: "ALEGRIA",
+		HappenedAt:      time.Now().UTC(),
+	}}}
+	narrative := service.NewNarrativeService(chars, env.memories, env.llm)
+	env.router = setupMemoryRouter(NewMemoryHandler(zap.NewNop(), profiles, chars, env.memories, narrative))
+	return env
+}
+
+func TestMemoryHandlerListFilters(t *testing.T) {
+	env := newMemoryTestEnv()
+	base := "/clones/" + env.profileID.String() + "/memories"
+
+	rec := performRequest(env.router, http.MethodGet, base+"?emotion=alegria&min_importance=4&character_id="+env.charID.String()+"&from=2024-01-01&to=2024-01-31", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	f := env.memories.lastFilter
+	if f.EmotionCategory != "alegria" || f.MinImportance != 4 || f.CharacterID == nil || *f.CharacterID != env.charID {
+		t.Fatalf("unexpected filter: %+v", f)
+	}
+	if f.From == nil || f.To == nil || !f.To.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected date-only to to be inclusive, got from=%v to=%v", f.From, f.To)
+	}
+	if strings.Contains(rec.Body.String(), `"embedding"`) {
+		t.Fatalf("embedding must not be exposed: %s", rec.Body.String())
+	}
+
+	for _, q := range []string{"?min_importance=11", "?character_id=nope", "?from=ayer", "?limit=0"} {
+		if rec := performRequest(env.router, http.MethodGet, base+q, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status 400, got %d", q, rec.Code)
+		}
+	}
+}
+
+func TestMemoryHandlerSearchReturnsScores(t *testing.T) {
+	env := newMemoryTestEnv()
+	env.memories.search = []repository.ScoredMemory{{NarrativeMemory: env.memories.memories[env.memoryID], Similarity: 0.82, Score: 0.9}}
+	base := "/clones/" + env.profileID.String() + "/memories/search"
+
+	if rec := performRequest(env.router, http.MethodGet, base, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without q, got %d", rec.Code)
+	}
+
+	rec := performRequest(env.router, http.MethodGet, base+"?q=plata&k=3", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Results []struct {
+			ID         uuid.UUID `json:"id"`
+			Similarity float64   `json:"similarity"`
+			Score      float64   `json:"score"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Results) != 1 || body.Results[0].ID != env.memoryID || body.Results[0].Similarity != 0.82 || body.Results[0].Score != 0.9 {
+		t.Fatalf("unexpected results: %+v", body.Results)
+	}
+}
+
+func TestMemoryHandlerUpdateAndDelete(t *testing.T) {
+	env := newMemoryTestEnv()
+	item := "/clones/" + env.profileID.String() + "/memories/" + env.memoryID.String()
+
+	if rec := performRequest(env.router, http.MethodPatch, item, map[string]any{"importance": 0}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for out-of-range importance, got %d", rec.Code)
+	}
+	if rec := performRequest(env.router, http.MethodPatch, item, map[string]any{"related_character_id": uuid.NewString()}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for foreign character, got %d", rec.Code)
+	}
+
+	rec := performRequest(env.router, http.MethodPatch, item, map[string]any{"importance": 9, "emotion_category": "tristeza", "related_character_id": env.charID.String()})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	stored := env.memories.memories[env.memoryID]
+	if stored.Importance != 9 || stored.EmotionCategory != "TRISTEZA" || stored.RelatedCharacterID == nil || *stored.RelatedCharacterID != env.charID {
+		t.Fatalf("unexpected stored memory: %+v", stored)
+	}
+	if env.llm.embeds != 0 {
+		t.Fatalf("metadata edits must not re-embed, got %d calls", env.llm.embeds)
+	}
+
+	rec = performRequest(env.router, http.MethodPatch, item, map[string]any{"content": "Ana me devolvio la plata"})
+	if rec.Code != http.StatusOK || env.llm.embeds != 1 {
+		t.Fatalf("expected content edit to re-embed, got status %d and %d calls", rec.Code, env.llm.embeds)
+	}
+	if got := env.memories.memories[env.memoryID].Embedding.Slice(); len(got) != 2 {
+		t.Fatalf("expected new embedding, got %v", got)
+	}
+
+	other := "/clones/" + env.profileID.String() + "/memories/" + uuid.NewString()
+	if rec := performRequest(env.router, http.MethodGet, other, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for unknown memory, got %d", rec.Code)
+	}
+	if rec := performRequest(env.router, http.MethodDelete, item, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204 on delete, got %d", rec.Code)
+	}
+	if rec := performRequest(env.router, http.MethodGet, item, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestMemoryHandlerRejectsForeignClone(t *testing.T) {
+	env := newMemoryTestEnv()
+	foreign := uuid.New()
+	profiles := &mockProfileRepo{profiles: map[string]domain.CloneProfile{foreign.String(): {ID: foreign.String(), UserID: "someone-else"}}}
+	r := setupMemoryRouter(NewMemoryHandler(zap.NewNop(), profiles, &mockCharacterRepo{}, env.memories, nil))
+
+	if rec := performRequest(r, http.MethodGet, "/clones/"+foreign.String()+"/memories", nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
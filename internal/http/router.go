@@ -5,6 +5,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"clone-llm/internal/service"
 )
 
 // NewRouter configura el router de Gin con middlewares y rutas base.
@@ -13,6 +15,9 @@ func NewRouter(
 	userH *UserHandler,
 	chatH *ChatHandler,
 	cloneH *CloneHandler,
+	charH *CharacterHandler,
+	memH *MemoryHandler,
+	jwtSvc *service.JWTService,
 ) *gin.Engine {
 	r := gin.New()
 
@@ -31,12 +36,41 @@ func NewRouter(
 	auth.POST("/refresh", userH.RefreshToken)
 	auth.POST("/logout", userH.Logout)
 
-	clone := r.Group("/clone")
+	// Rutas del clon y del chat: requieren access token y operan sobre el usuario del token.
+	authMW := JWTAuthMiddleware(jwtSvc)
+
+	clone := r.Group("/clone", authMW)
 	clone.POST("/init", cloneH.InitClone)
-	clone.GET("/profile", cloneH.GetCloneProfile)
+	clone.GET("/profile", cloneH.GetDefaultCloneProfile)
+
+	r.GET("/clones", authMW, cloneH.ListClones)
+
+	clones := r.Group("/clones/:id", authMW)
+	clones.GET("", cloneH.GetCloneProfile)
+	clones.PUT("/stability", cloneH.UpdateTraitStability)
+	clones.GET("/characters", charH.ListCharacters)
+	clones.POST("/characters", charH.CreateCharacter)
+	clones.GET("/characters/:charID", charH.GetCharacter)
+	clones.PATCH("/characters/:charID", charH.UpdateCharacter)
+	clones.DELETE("/characters/:charID", charH.DeleteCharacter)
+	clones.GET("/characters/:charID/timeline", charH.GetTimeline)
+	clones.GET("/characters/:charID/aliases", charH.ListAliases)
+	clones.POST("/characters/:charID/aliases", charH.CreateAlias)
+	clones.DELETE("/characters/:charID/aliases/:aliasID", charH.DeleteAlias)
+	clones.GET("/memories", memH.ListMemories)
+	clones.GET("/memories/search", memH.SearchMemories)
+	clones.GET("/memories/:memoryID", memH.GetMemory)
+	clones.PATCH("/memories/:memoryID", memH.UpdateMemory)
+	clones.DELETE("/memories/:memoryID", memH.DeleteMemory)
 
-	r.POST("/session", chatH.CreateSession)
-	r.POST("/message", chatH.PostMessage)
+	r.POST("/session", authMW, chatH.CreateSession)
+	r.GET("/sessions", authMW, chatH.ListSessions)
+	r.GET("/sessions/:id", authMW, chatH.GetSession)
+	r.POST("/sessions/:id/close", authMW, chatH.CloseSession)
+	r.PUT("/sessions/:id/speaking-as", authMW, chatH.SetSpeakingAs)
+	r.POST("/message", authMW, chatH.PostMessage)
+	r.GET("/message/stream", authMW, chatH.StreamMessage)
+	r.POST("/message/stream", authMW, chatH.StreamMessage)
 
 	return r
 }
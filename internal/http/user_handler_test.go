@@ -11,11 +11,13 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 
 	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
 	"clone-llm/internal/service"
 )
 
@@ -383,3 +385,469 @@ func TestUserHandlerRefreshToken_Success(t *testing.T) {
 		t.Fatalf("expected status 200, got %d", rec.Code)
 	}
 }
+
+type mockSessionRepo struct {
+	sessions map[string]domain.Session
+}
+
+func (m *mockSessionRepo) Create(_ context.Context, session domain.Session) error {
+	if m.sessions == nil {
+		m.sessions = make(map[string]domain.Session)
+	}
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *mockSessionRepo) GetByID(_ context.Context, id string) (domain.Session, error) {
+	s, ok := m.sessions[id]
+	if !ok {
+		return domain.Session{}, pgx.ErrNoRows
+	}
+	return s, nil
+}
+
+func (m *mockSessionRepo) ListByUserID(_ context.Context, userID string) ([]domain.Session, error) {
+	var out []domain.Session
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockSessionRepo) UpdateRelationship(context.Context, string, domain.RelationshipVectors) error {
+	return nil
+}
+
+func (m *mockSessionRepo) Close(_ context.Context, id string, closedAt time.Time) error {
+	s, ok := m.sessions[id]
+	if !ok || s.ClosedAt != nil {
+		return repository.ErrSessionAlreadyClosed
+	}
+	s.ClosedAt = &closedAt
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *mockSessionRepo) ClaimConsolidation(context.Context, string, time.Time) (bool, error) {
+	return true, nil
+}
+
+func (m *mockSessionRepo) ReleaseConsolidation(context.Context, string) error {
+	return nil
+}
+
+func (m *mockSessionRepo) SetSpeakingAs(_ context.Context, id, characterID string) error {
+	s, ok := m.sessions[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	s.SpeakingAsCharacterID = characterID
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *mockSessionRepo) ListIdle(context.Context, time.Time, int) ([]domain.Session, error) {
+	return nil, nil
+}
+
+type mockMessageRepo struct {
+	created []domain.Message
+}
+
+func (m *mockMessageRepo) Create(_ context.Context, message domain.Message) error {
+	m.created = append(m.created, message)
+	return nil
+}
+
+func (m *mockMessageRepo) ListBySessionID(context.Context, string) ([]domain.Message, error) {
+	return nil, nil
+}
+
+type mockTraitRepo struct {
+	traits []domain.Trait
+}
+
+func (m *mockTraitRepo) Upsert(context.Context, domain.Trait) error { return nil }
+
+func (m *mockTraitRepo) FindByProfileID(_ context.Context, profileID string) ([]domain.Trait, error) {
+	var out []domain.Trait
+	for _, t := range m.traits {
+		if t.ProfileID == profileID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockTraitRepo) FindByCategory(context.Context, string, string) ([]domain.Trait, error) {
+	return nil, nil
+}
+
+func (m *mockTraitRepo) CreateObservation(context.Context, domain.TraitObservation) error {
+	return nil
+}
+
+func (m *mockTraitRepo) UpdateCategory(context.Context, string, string, func([]domain.Trait) ([]domain.Trait, error)) error {
+	return nil
+}
+
+type authTestEnv struct {
+	router     *gin.Engine
+	jwtSvc     *service.JWTService
+	sessions   *mockSessionRepo
+	messages   *mockMessageRepo
+	profiles   *mockProfileRepo
+	characters *mockCharacterRepo
+}
+
+func setupAuthRouter(t *testing.T) authTestEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := zap.NewNop()
+	jwtSvc := service.NewJWTServiceWithStore("test-secret", 15*time.Minute, 30*time.Minute, service.NewMemoryRefreshTokenStore())
+	userSvc := service.NewUserService(logger, newMockUserRepo(), &mockEmailSender{}, nil)
+	env := authTestEnv{
+		jwtSvc:     jwtSvc,
+		sessions:   &mockSessionRepo{},
+		messages:   &mockMessageRepo{},
+		profiles:   &mockProfileRepo{},
+		characters: &mockCharacterRepo{},
+	}
+	env.router = NewRouter(
+		logger,
+		NewUserHandler(logger, userSvc, jwtSvc),
+		NewChatHandler(logger, env.sessions, env.messages, env.profiles, env.characters, service.NewSessionService(env.sessions, logger), nil, nil),
+		NewCloneHandler(logger, env.profiles, &mockTraitRepo{}),
+		NewCharacterHandler(logger, env.profiles, env.characters, &mockRelationshipEventRepo{}, &mockCharacterAliasRepo{}),
+		NewMemoryHandler(logger, env.profiles, env.characters, &mockMemoryRepo{}, nil),
+		jwtSvc,
+	)
+	return env
+}
+
+func (env authTestEnv) tokenFor(t *testing.T, userID string) string {
+	t.Helper()
+	pair, err := env.jwtSvc.GeneratePair(domain.User{ID: userID, Email: userID + "@example.com", CreatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatalf("generate pair: %v", err)
+	}
+	return pair.AccessToken
+}
+
+func performAuthRequest(r http.Handler, method, path, token string, body any) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouterRequiresTokenOnCloneAndChatRoutes(t *testing.T) {
+	env := setupAuthRouter(t)
+
+	cases := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/clone/init"},
+		{http.MethodGet, "/clone/profile"},
+		{http.MethodGet, "/clones/p1"},
+		{http.MethodPut, "/clones/p1/stability"},
+		{http.MethodPost, "/session"},
+		{http.MethodPost, "/message"},
+		{http.MethodGet, "/message/stream"},
+	}
+	for _, tc := range cases {
+		rec := performAuthRequest(env.router, tc.method, tc.path, "", nil)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s %s: expected status 401, got %d", tc.method, tc.path, rec.Code)
+		}
+	}
+}
+
+func TestChatHandlerCreateSession_UsesTokenUser(t *testing.T) {
+	env := setupAuthRouter(t)
+	token := env.tokenFor(t, "u1")
+
+	rec := performAuthRequest(env.router, http.MethodPost, "/session", token, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, s := range env.sessions.sessions {
+		if s.UserID != "u1" {
+			t.Fatalf("expected session owned by token user, got %q", s.UserID)
+		}
+	}
+
+	rec = performAuthRequest(env.router, http.MethodPost, "/session", token, map[string]string{"user_id": "u2"})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 when impersonating, got %d", rec.Code)
+	}
+}
+
+func TestChatHandlerPostMessage_ForeignSessionForbidden(t *testing.T) {
+	env := setupAuthRouter(t)
+	env.sessions.sessions = map[string]domain.Session{"s-ajena": {ID: "s-ajena", UserID: "u2"}}
+
+	rec := performAuthRequest(env.router, http.MethodPost, "/message", env.tokenFor(t, "u1"), map[string]string{
+		"session_id": "s-ajena",
+		"content":    "hola",
+		"role":       "user",
+	})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+	if len(env.messages.created) != 0 {
+		t.Fatalf("message must not be persisted on forbidden session")
+	}
+}
+
+func TestCloneHandlerUpdateTraitStability_OwnershipChecked(t *testing.T) {
+	env := setupAuthRouter(t)
+	p1 := uuid.NewString()
+	env.profiles.profiles = map[string]domain.CloneProfile{p1: {ID: p1, UserID: "u1", TraitStability: 0.8}}
+
+	rec := performAuthRequest(env.router, http.MethodPut, "/clones/"+p1+"/stability", env.tokenFor(t, "u2"), map[string]float64{"trait_stability": 0.3})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+
+	rec = performAuthRequest(env.router, http.MethodPut, "/clones/not-a-uuid/stability", env.tokenFor(t, "u1"), map[string]float64{"trait_stability": 0.3})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a malformed id, got %d", rec.Code)
+	}
+
+	rec = performAuthRequest(env.router, http.MethodPut, "/clones/"+p1+"/stability", env.tokenFor(t, "u1"), map[string]float64{"trait_stability": 0.3})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if env.profiles.profiles[p1].TraitStability != 0.3 {
+		t.Fatalf("expected stability updated, got %v", env.profiles.profiles[p1].TraitStability)
+	}
+}
+
+func TestCloneHandlerGetCloneProfile_ByID(t *testing.T) {
+	env := setupAuthRouter(t)
+	p1 := uuid.NewString()
+	env.profiles.profiles = map[string]domain.CloneProfile{p1: {ID: p1, UserID: "u1"}}
+
+	rec := performAuthRequest(env.router, http.MethodGet, "/clones/"+p1, env.tokenFor(t, "u2"), nil)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for a foreign clone, got %d", rec.Code)
+	}
+
+	rec = performAuthRequest(env.router, http.MethodGet, "/clones/"+uuid.NewString(), env.tokenFor(t, "u1"), nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for an unknown clone, got %d", rec.Code)
+	}
+
+	rec = performAuthRequest(env.router, http.MethodGet, "/clones/p1", env.tokenFor(t, "u1"), nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a malformed id, got %d", rec.Code)
+	}
+
+	rec = performAuthRequest(env.router, http.MethodGet, "/clones/"+p1, env.tokenFor(t, "u1"), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCloneHandlerGetDefaultCloneProfile(t *testing.T) {
+	env := setupAuthRouter(t)
+	p1 := uuid.NewString()
+	env.profiles.profiles = map[string]domain.CloneProfile{p1: {ID: p1, UserID: "u1", Name: "Ana"}}
+
+	rec := performAuthRequest(env.router, http.MethodGet, "/clone/profile", env.tokenFor(t, "u1"), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Profile domain.CloneProfile `json:"profile"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Profile.ID != p1 {
+		t.Fatalf("expected the caller's default clone %s, got %s", p1, resp.Profile.ID)
+	}
+
+	rec = performAuthRequest(env.router, http.MethodGet, "/clone/profile", env.tokenFor(t, "u2"), nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 without a clone, got %d", rec.Code)
+	}
+}
+
+func TestChatHandlerCreateSession_BindsOwnedProfile(t *testing.T) {
+	env := setupAuthRouter(t)
+	env.profiles.profiles = map[string]domain.CloneProfile{
+		"p1": {ID: "p1", UserID: "u1"},
+		"p2": {ID: "p2", UserID: "u2"},
+	}
+	token := env.tokenFor(t, "u1")
+
+	rec := performAuthRequest(env.router, http.MethodPost, "/session", token, map[string]string{"profile_id": "p2"})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for foreign profile, got %d", rec.Code)
+	}
+
+	rec = performAuthRequest(env.router, http.MethodPost, "/session", token, map[string]string{"profile_id": "p1"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Session domain.Session `json:"session"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response failed: %v", err)
+	}
+	if resp.Session.CloneProfileID != "p1" {
+		t.Fatalf("expected session bound to p1, got %q", resp.Session.CloneProfileID)
+	}
+}
+
+func TestCloneHandlerListClones_OnlyOwnProfiles(t *testing.T) {
+	env := setupAuthRouter(t)
+	env.profiles.profiles = map[string]domain.CloneProfile{
+		"p1": {ID: "p1", UserID: "u1", Name: "Uno"},
+		"p2": {ID: "p2", UserID: "u1", Name: "Dos"},
+		"p3": {ID: "p3", UserID: "u2", Name: "Ajeno"},
+	}
+
+	rec := performAuthRequest(env.router, http.MethodGet, "/clones", env.tokenFor(t, "u1"), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp struct {
+		Clones []domain.CloneProfile `json:"clones"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response failed: %v", err)
+	}
+	if len(resp.Clones) != 2 {
+		t.Fatalf("expected 2 clones, got %d", len(resp.Clones))
+	}
+	for _, p := range resp.Clones {
+		if p.UserID != "u1" {
+			t.Fatalf("listed foreign clone: %+v", p)
+		}
+	}
+}
+
+func TestChatHandlerSessionLifecycle(t *testing.T) {
+	env := setupAuthRouter(t)
+	now := time.Now().UTC()
+	env.sessions.sessions = map[string]domain.Session{
+		"activa":  {ID: "activa", UserID: "u1", ExpiresAt: now.Add(time.Hour)},
+		"vencida": {ID: "vencida", UserID: "u1", ExpiresAt: now.Add(-time.Hour)},
+		"ajena":   {ID: "ajena", UserID: "u2", ExpiresAt: now.Add(time.Hour)},
+	}
+	token := env.tokenFor(t, "u1")
+
+	rec := performAuthRequest(env.router, http.MethodGet, "/sessions", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var list struct {
+		Sessions []struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"sessions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("unmarshal response failed: %v", err)
+	}
+	if len(list.Sessions) != 2 {
+		t.Fatalf("expected 2 own sessions, got %d", len(list.Sessions))
+	}
+
+	if rec := performAuthRequest(env.router, http.MethodGet, "/sessions/ajena", token, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for foreign session, got %d", rec.Code)
+	}
+
+	msg := map[string]string{"session_id": "vencida", "content": "hola", "role": "user"}
+	if rec := performAuthRequest(env.router, http.MethodPost, "/message", token, msg); rec.Code != http.StatusGone {
+		t.Fatalf("expected status 410 for expired session, got %d", rec.Code)
+	}
+
+	if rec := performAuthRequest(env.router, http.MethodPost, "/sessions/activa/close", token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 on close, got %d", rec.Code)
+	}
+	if rec := performAuthRequest(env.router, http.MethodPost, "/sessions/activa/close", token, nil); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 on second close, got %d", rec.Code)
+	}
+	msg["session_id"] = "activa"
+	if rec := performAuthRequest(env.router, http.MethodPost, "/message", token, msg); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for closed session, got %d", rec.Code)
+	}
+	if len(env.messages.created) != 0 {
+		t.Fatalf("messages must not be persisted on inactive sessions")
+	}
+}
+
+func TestChatHandlerExpiredSessionStaysExpired(t *testing.T) {
+	env := setupAuthRouter(t)
+	env.sessions.sessions = map[string]domain.Session{
+		"vencida": {ID: "vencida", UserID: "u1", ExpiresAt: time.Now().UTC().Add(-time.Hour)},
+	}
+	token := env.tokenFor(t, "u1")
+
+	msg := map[string]string{"session_id": "vencida", "content": "hola", "role": "user"}
+	for i := 0; i < 2; i++ {
+		if rec := performAuthRequest(env.router, http.MethodPost, "/message", token, msg); rec.Code != http.StatusGone {
+			t.Fatalf("message %d: expected status 410 for expired session, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := performAuthRequest(env.router, http.MethodGet, "/sessions/vencida", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp struct {
+		Session struct {
+			Status string `json:"status"`
+		} `json:"session"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response failed: %v", err)
+	}
+	if resp.Session.Status != domain.SessionStatusExpired {
+		t.Fatalf("expected status %q, got %q", domain.SessionStatusExpired, resp.Session.Status)
+	}
+}
+
+func TestChatHandlerSetSpeakingAs(t *testing.T) {
+	env := setupAuthRouter(t)
+	profileID := uuid.New()
+	own := domain.Character{ID: uuid.New(), CloneProfileID: profileID, Name: "Ana"}
+	foreign := domain.Character{ID: uuid.New(), CloneProfileID: uuid.New(), Name: "Beto"}
+	env.characters.chars = map[uuid.UUID]domain.Character{own.ID: own, foreign.ID: foreign}
+	env.sessions.sessions = map[string]domain.Session{
+		"s1": {ID: "s1", UserID: "u1", CloneProfileID: profileID.String(), ExpiresAt: time.Now().UTC().Add(time.Hour)},
+	}
+	token := env.tokenFor(t, "u1")
+
+	if rec := performAuthRequest(env.router, http.MethodPut, "/sessions/s1/speaking-as", token, map[string]string{"character_id": foreign.ID.String()}); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a character of another clone, got %d", rec.Code)
+	}
+	if rec := performAuthRequest(env.router, http.MethodPut, "/sessions/s1/speaking-as", token, map[string]string{"character_id": own.ID.String()}); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := env.sessions.sessions["s1"].SpeakingAsCharacterID; got != own.ID.String() {
+		t.Fatalf("expected speaking-as to be stored, got %q", got)
+	}
+	if rec := performAuthRequest(env.router, http.MethodPut, "/sessions/s1/speaking-as", token, map[string]string{"character_id": ""}); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 when clearing, got %d", rec.Code)
+	}
+	if got := env.sessions.sessions["s1"].SpeakingAsCharacterID; got != "" {
+		t.Fatalf("expected speaking-as to be cleared, got %q", got)
+	}
+}
@@ -0,0 +1,264 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	defaultAnthropicVersion   = "2023-06-01"
+	defaultAnthropicMaxTokens = 4096
+)
+
+// ErrEmbeddingsNotConfigured se devuelve cuando el proveedor no genera embeddings y no se
+// configuro un proveedor de embeddings aparte.
+var ErrEmbeddingsNotConfigured = errors.New("llm embeddings provider not configured")
+
+// Embedder genera embeddings. HTTPClient lo implementa; los proveedores sin endpoint de
+// embeddings (Anthropic) delegan en uno.
+type Embedder interface {
+	CreateEmbedding(ctx context.Context, text string) ([]float32, error)
+}
+
+// AnthropicConfig configura el cliente de la Messages API de Anthropic.
+type AnthropicConfig struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	// Version va en el header anthropic-version (por defecto 2023-06-01).
+	Version string
+	// MaxTokens es obligatorio en la Messages API; por defecto 4096.
+	MaxTokens int
+	// SystemPrompt opcional, enviado en el campo system de cada request.
+	SystemPrompt string
+	// Temperature nil usa la del modelo.
+	Temperature *float64
+}
+
+// AnthropicClient implementa LLMClient sobre la Messages API de Anthropic.
+// Los embeddings se delegan en el Embedder configurado.
+type AnthropicClient struct {
+	cfg      AnthropicConfig
+	embedder Embedder
+	client   *http.Client
+	logger   logger
+}
+
+// NewAnthropicClient construye el cliente; embedder puede ser nil si no se usan embeddings.
+func NewAnthropicClient(cfg AnthropicConfig, embedder Embedder, log any) *AnthropicClient {
+	l := asLogger(log)
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultAnthropicBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Version == "" {
+		cfg.Version = defaultAnthropicVersion
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = defaultAnthropicMaxTokens
+	}
+	return &AnthropicClient{
+		cfg:      cfg,
+		embedder: embedder,
+		client:   &http.Client{Timeout: 60 * time.Second},
+		logger:   l,
+	}
+}
+
+// SetTimeout reemplaza el timeout de cada request HTTP, incluido el del proveedor de embeddings.
+func (c *AnthropicClient) SetTimeout(d time.Duration) {
+	c.client.Timeout = d
+	if t, ok := c.embedder.(interface{ SetTimeout(time.Duration) }); ok {
+		t.SetTimeout(d)
+	}
+}
+
+func (c *AnthropicClient) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := c.do(ctx, prompt, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+
+	var mr anthropicMessageResponse
+	if err := json.Unmarshal(respBody, &mr); err != nil {
+		return "", fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	var out strings.Builder
+	for _, block := range mr.Content {
+		if block.Type == "text" {
+			out.WriteString(block.Text)
+		}
+	}
+	if out.Len() == 0 {
+		return "", fmt.Errorf("llm empty response")
+	}
+	return out.String(), nil
+}
+
+// GenerateStream usa la Messages API con stream: true y consume los eventos SSE
+// (content_block_delta con text_delta); un evento error corta el stream.
+func (c *AnthropicClient) GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error) {
+	resp, err := c.do(ctx, prompt, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return full.String(), fmt.Errorf("unmarshal stream event: %w", err)
+		}
+		switch ev.Type {
+		case "error":
+			apiErr := &APIError{Provider: "anthropic", StatusCode: resp.StatusCode}
+			if ev.Error != nil {
+				apiErr.Type = ev.Error.Type
+				apiErr.Message = ev.Error.Message
+			}
+			return full.String(), apiErr
+		case "message_stop":
+			if full.Len() == 0 {
+				return "", fmt.Errorf("llm empty response")
+			}
+			return full.String(), nil
+		case "content_block_delta":
+			if ev.Delta == nil || ev.Delta.Type != "text_delta" || ev.Delta.Text == "" {
+				continue
+			}
+			full.WriteString(ev.Delta.Text)
+			if onChunk != nil {
+				if err := onChunk(ev.Delta.Text); err != nil {
+					return full.String(), err
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("read stream: %w", err)
+	}
+
+	if full.Len() == 0 {
+		return "", fmt.Errorf("llm empty response")
+	}
+	return full.String(), nil
+}
+
+// CreateEmbedding delega en el proveedor de embeddings: Anthropic no expone un endpoint propio.
+func (c *AnthropicClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if c.embedder == nil {
+		return nil, ErrEmbeddingsNotConfigured
+	}
+	return c.embedder.CreateEmbedding(ctx, text)
+}
+
+// do envia el request a /messages y devuelve la respuesta si el status es exitoso;
+// los errores HTTP se traducen a *APIError con el tipo informado por la API.
+func (c *AnthropicClient) do(ctx context.Context, prompt string, stream bool) (*http.Response, error) {
+	reqBody := anthropicMessageRequest{
+		Model:     c.cfg.Model,
+		MaxTokens: c.cfg.MaxTokens,
+		System:    c.cfg.SystemPrompt,
+		Messages: []chatMessage{
+			{Role: "user", Content: prompt},
+		},
+		Stream:      stream,
+		Temperature: c.cfg.Temperature,
+	}
+
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/messages", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("x-api-key", c.cfg.APIKey)
+	req.Header.Set("anthropic-version", c.cfg.Version)
+	req.Header.Set("Content-Type", "application/json")
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if c.logger != nil {
+		c.logger.Printf("llm error status %d: %s", resp.StatusCode, string(respBody))
+	}
+	apiErr := &APIError{Provider: "anthropic", StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header, time.Now())}
+	var er anthropicErrorResponse
+	if json.Unmarshal(respBody, &er) == nil && er.Error != nil {
+		apiErr.Type = er.Error.Type
+		apiErr.Message = er.Error.Message
+	}
+	return nil, apiErr
+}
+
+type anthropicMessageRequest struct {
+	Model       string        `json:"model"`
+	MaxTokens   int           `json:"max_tokens"`
+	System      string        `json:"system,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	Stream      bool          `json:"stream,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
+}
+
+type anthropicMessageResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+type anthropicErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type anthropicErrorResponse struct {
+	Error *anthropicErrorDetail `json:"error"`
+}
+
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta *struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta,omitempty"`
+	Error *anthropicErrorDetail `json:"error,omitempty"`
+}
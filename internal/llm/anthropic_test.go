@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeEmbedder struct {
+	calls int
+}
+
+func (f *fakeEmbedder) CreateEmbedding(context.Context, string) ([]float32, error) {
+	f.calls++
+	return []float32{0.5, 0.25}, nil
+}
+
+// newAnthropicStandIn levanta un /messages que valida headers y cuerpo y responde con handle.
+func newAnthropicStandIn(t *testing.T, handle func(w http.ResponseWriter, req anthropicMessageRequest)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "secret" {
+			t.Errorf("expected x-api-key header, got %q", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != defaultAnthropicVersion {
+			t.Errorf("expected anthropic-version header, got %q", got)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("anthropic requests must not send a bearer token")
+		}
+		var req anthropicMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		handle(w, req)
+	}))
+}
+
+func TestAnthropicClient_Generate(t *testing.T) {
+	srv := newAnthropicStandIn(t, func(w http.ResponseWriter, req anthropicMessageRequest) {
+		if req.Model != "claude-test" || req.MaxTokens != defaultAnthropicMaxTokens || req.System != "Sos un clon." || req.Stream {
+			t.Errorf("unexpected request body: %+v", req)
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content != "hola" {
+			t.Errorf("unexpected messages: %+v", req.Messages)
+		}
+		fmt.Fprint(w, `{"type":"message","content":[{"type":"text","text":"{\"public_response\":"},{"type":"text","text":"\"hola\"}"}]}`)
+	})
+	defer srv.Close()
+
+	client := NewAnthropicClient(AnthropicConfig{BaseURL: srv.URL + "/v1/", APIKey: "secret", Model: "claude-test", SystemPrompt: "Sos un clon."}, nil, nil)
+	got, err := client.Generate(context.Background(), "hola")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != `{"public_response":"hola"}` {
+		t.Fatalf("unexpected text: %q", got)
+	}
+}
+
+func TestAnthropicClient_GenerateStream(t *testing.T) {
+	srv := newAnthropicStandIn(t, func(w http.ResponseWriter, req anthropicMessageRequest) {
+		if !req.Stream {
+			t.Errorf("expected stream request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_1"}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ho"}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"la"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, ev := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", ev)
+		}
+	})
+	defer srv.Close()
+
+	client := NewAnthropicClient(AnthropicConfig{BaseURL: srv.URL + "/v1", APIKey: "secret", Model: "claude-test"}, nil, nil)
+	var chunks []string
+	got, err := client.GenerateStream(context.Background(), "hola", func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != "hola" || strings.Join(chunks, "|") != "ho|la" {
+		t.Fatalf("unexpected stream result %q chunks %v", got, chunks)
+	}
+}
+
+func TestAnthropicClient_MapsAPIErrors(t *testing.T) {
+	srv := newAnthropicStandIn(t, func(w http.ResponseWriter, req anthropicMessageRequest) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	})
+	defer srv.Close()
+
+	client := NewAnthropicClient(AnthropicConfig{BaseURL: srv.URL + "/v1", APIKey: "secret", Model: "claude-test"}, nil, nil)
+	_, err := client.Generate(context.Background(), "hola")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Type != "rate_limit_error" || apiErr.Message != "slow down" {
+		t.Fatalf("unexpected api error: %+v", apiErr)
+	}
+}
+
+func TestAnthropicClient_DelegatesEmbeddings(t *testing.T) {
+	client := NewAnthropicClient(AnthropicConfig{Model: "claude-test"}, nil, nil)
+	if _, err := client.CreateEmbedding(context.Background(), "hola"); !errors.Is(err, ErrEmbeddingsNotConfigured) {
+		t.Fatalf("expected ErrEmbeddingsNotConfigured, got %v", err)
+	}
+
+	embedder := &fakeEmbedder{}
+	client = NewAnthropicClient(AnthropicConfig{Model: "claude-test"}, embedder, nil)
+	got, err := client.CreateEmbedding(context.Background(), "hola")
+	if err != nil || len(got) != 2 || embedder.calls != 1 {
+		t.Fatalf("expected delegated embedding, got %v %v (calls=%d)", got, err, embedder.calls)
+	}
+}
+
+func TestNewClient_SelectsProvider(t *testing.T) {
+	if c, err := NewClient(ProviderConfig{APIKey: "k", Model: "gpt"}, nil); err != nil {
+		t.Fatalf("expected default provider, got %v", err)
+	} else if _, ok := c.(*HTTPClient); !ok {
+		t.Fatalf("expected HTTPClient for default provider, got %T", c)
+	}
+
+	c, err := NewClient(ProviderConfig{Provider: "Anthropic", APIKey: "k", Model: "claude", EmbeddingBaseURL: "http://embeddings", EmbeddingAPIKey: "ek"}, nil)
+	if err != nil {
+		t.Fatalf("expected anthropic provider, got %v", err)
+	}
+	ac, ok := c.(*AnthropicClient)
+	if !ok || ac.embedder == nil || ac.cfg.BaseURL != defaultAnthropicBaseURL {
+		t.Fatalf("unexpected anthropic client: %+v", c)
+	}
+
+	if _, err := NewClient(ProviderConfig{Provider: "palm"}, nil); err == nil {
+		t.Fatalf("expected error for unknown provider")
+	}
+}
+
+func TestNewClient_AnthropicKeyNeverReachesEmbeddingHost(t *testing.T) {
+	var gotAuth string
+	embeddings := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data": [{"embedding": [0.1, 0.2]}]}`)
+	}))
+	defer embeddings.Close()
+
+	if _, err := NewClient(ProviderConfig{Provider: ProviderAnthropic, APIKey: "anthropic-secret", Model: "claude", EmbeddingBaseURL: embeddings.URL}, nil); !errors.Is(err, ErrAPIKeyRequired) {
+		t.Fatalf("expected ErrAPIKeyRequired without EMBEDDING_API_KEY, got %v", err)
+	}
+
+	c, err := NewClient(ProviderConfig{Provider: ProviderAnthropic, APIKey: "anthropic-secret", Model: "claude", EmbeddingBaseURL: embeddings.URL, EmbeddingAPIKey: "embedding-key"}, nil)
+	if err != nil {
+		t.Fatalf("expected client, got %v", err)
+	}
+	if _, err := c.CreateEmbedding(context.Background(), "hola"); err != nil {
+		t.Fatalf("expected embedding, got %v", err)
+	}
+	if gotAuth != "Bearer embedding-key" || strings.Contains(gotAuth, "anthropic-secret") {
+		t.Fatalf("expected only the embedding key on the embedding host, got %q", gotAuth)
+	}
+
+	// Un endpoint de embeddings en el mismo host que la generacion (un proxy propio) reutiliza la clave.
+	if _, err := NewClient(ProviderConfig{Provider: ProviderAnthropic, BaseURL: embeddings.URL + "/v1", APIKey: "proxy-key", Model: "claude", EmbeddingBaseURL: embeddings.URL + "/v1"}, nil); err != nil {
+		t.Fatalf("expected same-host embeddings to reuse the key, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,11 +10,16 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 // LLMClient define la interfaz para generar respuestas con un LLM.
 type LLMClient interface {
 	Generate(ctx context.Context, prompt string) (string, error)
+	// GenerateStream pide la respuesta en streaming, llama a onChunk con cada fragmento
+	// de texto y devuelve el texto completo al terminar. Si onChunk devuelve error se corta el stream.
+	GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error)
 	CreateEmbedding(ctx context.Context, text string) ([]float32, error)
 }
 
@@ -21,33 +27,68 @@ type logger interface {
 	Printf(format string, v ...interface{})
 }
 
+// asLogger adapta el log que reciben los constructores: cualquier cosa con Printf (*log.Logger) o el
+// *zap.Logger de los comandos. Devuelve nil si no es ninguno de los dos y los clientes no loguean.
+func asLogger(log any) logger {
+	switch l := log.(type) {
+	case logger:
+		return l
+	case *zap.Logger:
+		if l == nil {
+			return nil
+		}
+		return zap.NewStdLog(l)
+	}
+	return nil
+}
+
+// DefaultEmbeddingModel es el modelo de embeddings de la API compatible con OpenAI si no se configura otro.
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
 // HTTPClient implementa LLMClient usando la API de OpenAI-compatible.
 type HTTPClient struct {
-	baseURL string
-	apiKey  string
-	model   string
-	client  *http.Client
-	logger  logger
+	baseURL        string
+	apiKey         string
+	model          string
+	embeddingModel string
+	temperature    *float64
+	client         *http.Client
+	logger         logger
 }
 
 // NewHTTPClient construye un cliente HTTP apuntando a la API de chat completions.
 func NewHTTPClient(baseURL, apiKey, model string, log any) *HTTPClient {
-	l, _ := log.(logger)
+	l := asLogger(log)
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
 	}
 	return &HTTPClient{
-		baseURL: strings.TrimRight(baseURL, "/"),
-		apiKey:  apiKey,
-		model:   model,
-		client:  &http.Client{Timeout: 60 * time.Second},
-		logger:  l,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		apiKey:         apiKey,
+		model:          model,
+		embeddingModel: DefaultEmbeddingModel,
+		client:         &http.Client{Timeout: 60 * time.Second},
+		logger:         l,
+	}
+}
+
+// SetTemperature fija la temperatura de generacion; nil usa la del modelo.
+func (c *HTTPClient) SetTemperature(t *float64) { c.temperature = t }
+
+// SetTimeout reemplaza el timeout de 60s de cada request HTTP (0 no lo limita).
+func (c *HTTPClient) SetTimeout(d time.Duration) { c.client.Timeout = d }
+
+// SetEmbeddingModel cambia el modelo de /embeddings; vacio conserva DefaultEmbeddingModel.
+func (c *HTTPClient) SetEmbeddingModel(model string) {
+	if model != "" {
+		c.embeddingModel = model
 	}
 }
 
 func (c *HTTPClient) Generate(ctx context.Context, prompt string) (string, error) {
 	reqBody := chatRequest{
-		Model: c.model,
+		Model:       c.model,
+		Temperature: c.temperature,
 		Messages: []chatMessage{
 			{Role: "user", Content: prompt},
 		},
@@ -62,7 +103,7 @@ func (c *HTTPClient) Generate(ctx context.Context, prompt string) (string, error
 	if err != nil {
 		return "", fmt.Errorf("create request: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	c.setAuth(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
@@ -77,10 +118,7 @@ func (c *HTTPClient) Generate(ctx context.Context, prompt string) (string, error
 	}
 
 	if resp.StatusCode >= 400 {
-		if c.logger != nil {
-			c.logger.Printf("llm error status %d: %s", resp.StatusCode, string(respBody))
-		}
-		return "", fmt.Errorf("llm http error: status=%d", resp.StatusCode)
+		return "", c.apiError(resp, respBody, "llm")
 	}
 
 	var cr chatResponse
@@ -99,10 +137,87 @@ func (c *HTTPClient) Generate(ctx context.Context, prompt string) (string, error
 	return cr.Choices[0].Message.Content, nil
 }
 
+// GenerateStream usa chat completions con stream: true y consume los eventos SSE del proveedor.
+func (c *HTTPClient) GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error) {
+	reqBody := chatRequest{
+		Model:       c.model,
+		Temperature: c.temperature,
+		Messages: []chatMessage{
+			{Role: "user", Content: prompt},
+		},
+		Stream: true,
+	}
+
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	c.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", c.apiError(resp, respBody, "llm")
+	}
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return full.String(), fmt.Errorf("llm api error: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		text := chunk.Choices[0].Delta.Content
+		full.WriteString(text)
+		if onChunk != nil {
+			if err := onChunk(text); err != nil {
+				return full.String(), err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("read stream: %w", err)
+	}
+
+	if full.Len() == 0 {
+		return "", fmt.Errorf("llm empty response")
+	}
+	return full.String(), nil
+}
+
 // CreateEmbedding obtiene el embedding del texto usando el endpoint de embeddings.
 func (c *HTTPClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	payload := map[string]any{
-		"model": "text-embedding-3-small",
+		"model": c.embeddingModel,
 		"input": text,
 	}
 	bodyBytes, err := json.Marshal(payload)
@@ -115,7 +230,7 @@ func (c *HTTPClient) CreateEmbedding(ctx context.Context, text string) ([]float3
 	if err != nil {
 		return nil, fmt.Errorf("create embedding request: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	c.setAuth(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
@@ -130,10 +245,7 @@ func (c *HTTPClient) CreateEmbedding(ctx context.Context, text string) ([]float3
 	}
 
 	if resp.StatusCode >= 400 {
-		if c.logger != nil {
-			c.logger.Printf("embedding error status %d: %s", resp.StatusCode, string(respBody))
-		}
-		return nil, fmt.Errorf("embedding http error: status=%d", resp.StatusCode)
+		return nil, c.apiError(resp, respBody, "embedding")
 	}
 
 	var er embeddingResponse
@@ -150,9 +262,41 @@ func (c *HTTPClient) CreateEmbedding(ctx context.Context, text string) ([]float3
 	return er.Data[0].Embedding, nil
 }
 
+// setAuth agrega el bearer token; los servidores locales (llama.cpp) pueden no tener clave.
+func (c *HTTPClient) setAuth(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// apiError registra la respuesta fallida y la traduce a *APIError con el tipo y codigo informados.
+func (c *HTTPClient) apiError(resp *http.Response, body []byte, what string) error {
+	if c.logger != nil {
+		c.logger.Printf("%s error status %d: %s", what, resp.StatusCode, string(body))
+	}
+	apiErr := &APIError{Provider: "openai", StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header, time.Now())}
+	var er struct {
+		Error *struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    any    `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &er) == nil && er.Error != nil {
+		apiErr.Type = er.Error.Type
+		apiErr.Message = er.Error.Message
+		if code, ok := er.Error.Code.(string); ok {
+			apiErr.Code = code
+		}
+	}
+	return apiErr
+}
+
 type chatRequest struct {
-	Model    string        `json:"model"`
-	Messages []chatMessage `json:"messages"`
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Stream      bool          `json:"stream,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
 }
 
 type chatMessage struct {
@@ -169,6 +313,15 @@ type chatResponse struct {
 	} `json:"error,omitempty"`
 }
 
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta chatMessage `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
 type embeddingResponse struct {
 	Data []struct {
 		Embedding []float32 `json:"embedding"`
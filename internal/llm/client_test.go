@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPClient_UsesConfiguredEmbeddingModel(t *testing.T) {
+	var gotModel string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		gotModel = req.Model
+		fmt.Fprint(w, `{"data":[{"embedding":[0.1,0.2]}]}`)
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(srv.URL, "k", "gpt", nil)
+	if _, err := client.CreateEmbedding(context.Background(), "hola"); err != nil || gotModel != DefaultEmbeddingModel {
+		t.Fatalf("expected default embedding model, got %q (%v)", gotModel, err)
+	}
+
+	client.SetEmbeddingModel("text-embedding-3-large")
+	if _, err := client.CreateEmbedding(context.Background(), "hola"); err != nil || gotModel != "text-embedding-3-large" {
+		t.Fatalf("expected configured embedding model, got %q (%v)", gotModel, err)
+	}
+}
+
+func TestProviderConfig_EmbeddingModelName(t *testing.T) {
+	cases := []struct {
+		cfg  ProviderConfig
+		want string
+	}{
+		{ProviderConfig{}, DefaultEmbeddingModel},
+		{ProviderConfig{Provider: ProviderAnthropic}, DefaultEmbeddingModel},
+		{ProviderConfig{Provider: "Ollama"}, DefaultOllamaEmbeddingModel},
+		{ProviderConfig{Provider: ProviderOllama, EmbeddingModel: "mxbai-embed-large"}, "mxbai-embed-large"},
+	}
+	for _, tc := range cases {
+		if got := tc.cfg.EmbeddingModelName(); got != tc.want {
+			t.Fatalf("EmbeddingModelName(%+v) = %q, want %q", tc.cfg, got, tc.want)
+		}
+	}
+}
+
+// newSSEServer responde cada request de chat con los eventos dados, en formato "data: ...".
+func newSSEServer(t *testing.T, events ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if !req.Stream || r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("expected stream request, got %+v", req)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range events {
+			fmt.Fprintf(w, "%s\n\n", ev)
+		}
+	}))
+}
+
+func TestHTTPClient_GenerateStream(t *testing.T) {
+	srv := newSSEServer(t,
+		`: keep-alive`,
+		`data: {"choices":[{"delta":{"role":"assistant"}}]}`,
+		`data: {"choices":[{"delta":{"content":"ho"}}]}`,
+		`data:{"choices":[{"delta":{"content":"la"}}]}`,
+		`data: [DONE]`,
+		`data: {"choices":[{"delta":{"content":" ignorado"}}]}`,
+	)
+	defer srv.Close()
+
+	var chunks []string
+	got, err := NewHTTPClient(srv.URL, "k", "gpt", nil).GenerateStream(context.Background(), "hola", func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != "hola" || strings.Join(chunks, "|") != "ho|la" {
+		t.Fatalf("unexpected stream result %q chunks %v", got, chunks)
+	}
+}
+
+func TestHTTPClient_GenerateStreamErrorMidStream(t *testing.T) {
+	srv := newSSEServer(t,
+		`data: {"choices":[{"delta":{"content":"ho"}}]}`,
+		`data: {"error":{"message":"server overloaded"}}`,
+	)
+	defer srv.Close()
+
+	got, err := NewHTTPClient(srv.URL, "k", "gpt", nil).GenerateStream(context.Background(), "hola", nil)
+	if err == nil || !strings.Contains(err.Error(), "server overloaded") {
+		t.Fatalf("expected mid-stream api error, got %v", err)
+	}
+	if got != "ho" {
+		t.Fatalf("expected partial text before the error, got %q", got)
+	}
+}
+
+func TestHTTPClient_GenerateStreamAbortsOnChunkError(t *testing.T) {
+	srv := newSSEServer(t,
+		`data: {"choices":[{"delta":{"content":"ho"}}]}`,
+		`data: {"choices":[{"delta":{"content":"la"}}]}`,
+		`data: [DONE]`,
+	)
+	defer srv.Close()
+
+	errStop := errors.New("client gone")
+	calls := 0
+	got, err := NewHTTPClient(srv.URL, "k", "gpt", nil).GenerateStream(context.Background(), "hola", func(string) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 || got != "ho" {
+		t.Fatalf("expected stream to stop at first chunk, got %q %v (calls=%d)", got, err, calls)
+	}
+}
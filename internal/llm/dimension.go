@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrEmbeddingDimension indica un embedding mas grande que la columna vectorial.
+var ErrEmbeddingDimension = errors.New("embedding dimension exceeds vector column")
+
+// dimensionClient adapta los embeddings del proveedor al tamano de la columna vectorial.
+type dimensionClient struct {
+	LLMClient
+	dim int
+}
+
+// WithEmbeddingDimension envuelve client para que CreateEmbedding devuelva siempre vectores de dim
+// posiciones. Los embeddings mas cortos (nomic-embed-text: 768) se completan con ceros, lo que no
+// cambia la similitud coseno entre vectores del mismo modelo; los mas largos no entran en la columna
+// y devuelven ErrEmbeddingDimension. Mezclar modelos en una misma base no es valido aunque las
+// dimensiones coincidan.
+func WithEmbeddingDimension(client LLMClient, dim int) LLMClient {
+	if client == nil || dim <= 0 {
+		return client
+	}
+	return &dimensionClient{LLMClient: client, dim: dim}
+}
+
+func (c *dimensionClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embed, err := c.LLMClient.CreateEmbedding(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return fitEmbedding(embed, c.dim)
+}
+
+func fitEmbedding(embed []float32, dim int) ([]float32, error) {
+	switch {
+	case len(embed) == dim:
+		return embed, nil
+	case len(embed) > dim:
+		return nil, fmt.Errorf("%w: got %d, column holds %d", ErrEmbeddingDimension, len(embed), dim)
+	}
+	out := make([]float32, dim)
+	copy(out, embed)
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errores tipados de los proveedores. Se comparan con errors.Is: *APIError los reporta segun su
+// status y tipo, y ResilientClient envuelve con ErrProviderDown los timeouts, errores de red y el
+// circuito abierto.
+var (
+	ErrRateLimited    = errors.New("llm rate limited")
+	ErrContextTooLong = errors.New("llm context too long")
+	ErrProviderDown   = errors.New("llm provider unavailable")
+)
+
+// APIError es un error devuelto por la API del proveedor, con el status HTTP y el tipo de error.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string
+	// Code es el codigo de error de la API compatible con OpenAI (p.ej. context_length_exceeded).
+	Code    string
+	Message string
+	// RetryAfter es la espera pedida por el proveedor en el header Retry-After (0 si no la informa).
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s api error: status=%d", e.Provider, e.StatusCode)
+	if e.Type != "" {
+		msg += " type=" + e.Type
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is traduce el status y el tipo informados por el proveedor a los errores tipados.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || e.Type == "rate_limit_error"
+	case ErrProviderDown:
+		// 529 y overloaded_error son la forma de Anthropic de decir "sobrecargado".
+		return e.StatusCode >= 500 || e.Type == "overloaded_error" || e.Type == "api_error"
+	case ErrContextTooLong:
+		return e.contextTooLong()
+	}
+	return false
+}
+
+func (e *APIError) contextTooLong() bool {
+	if e.StatusCode == http.StatusRequestEntityTooLarge || e.Code == "context_length_exceeded" {
+		return true
+	}
+	if e.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	msg := strings.ToLower(e.Message)
+	for _, hint := range []string{"context length", "context window", "prompt is too long", "too many tokens", "maximum context"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryAfter devuelve cuanto conviene esperar antes de reintentar segun err, o 0 si no se sabe.
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	var open *circuitOpenError
+	if errors.As(err, &open) {
+		return open.retryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter interpreta el header Retry-After en segundos o como fecha HTTP.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -4,16 +4,40 @@ import "context"
 
 // MockClient permite tests sin llamar a un LLM real.
 type MockClient struct {
-	Response       string
-	Err            error
-	Embedding      []float32
-	EmbeddingError error
+	Response        string
+	StreamChunkSize int
+	Err             error
+	Embedding       []float32
+	EmbeddingError  error
 }
 
 func (m *MockClient) Generate(ctx context.Context, prompt string) (string, error) {
 	return m.Response, m.Err
 }
 
+// GenerateStream emite Response en fragmentos de StreamChunkSize bytes (todo junto si es 0).
+func (m *MockClient) GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error) {
+	if m.Err != nil {
+		return "", m.Err
+	}
+	size := m.StreamChunkSize
+	if size <= 0 {
+		size = len(m.Response)
+	}
+	for start := 0; start < len(m.Response); start += size {
+		end := start + size
+		if end > len(m.Response) {
+			end = len(m.Response)
+		}
+		if onChunk != nil {
+			if err := onChunk(m.Response[start:end]); err != nil {
+				return m.Response[:end], err
+			}
+		}
+	}
+	return m.Response, nil
+}
+
 func (m *MockClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	if m.EmbeddingError != nil {
 		return nil, m.EmbeddingError
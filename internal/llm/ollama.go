@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// DefaultOllamaEmbeddingModel es el modelo de /api/embeddings si no se configura otro (768 dimensiones).
+const DefaultOllamaEmbeddingModel = "nomic-embed-text"
+
+// OllamaConfig configura el cliente de la API nativa de Ollama (/api/chat y /api/embeddings).
+type OllamaConfig struct {
+	BaseURL string
+	Model   string
+	// EmbeddingModel es el modelo de /api/embeddings (por defecto DefaultOllamaEmbeddingModel).
+	EmbeddingModel string
+	// MaxTokens se envia como options.num_predict; 0 deja el limite del modelo.
+	MaxTokens    int
+	SystemPrompt string
+	// Temperature nil usa la del modelo.
+	Temperature *float64
+}
+
+// OllamaClient implementa LLMClient contra un servidor Ollama local; no necesita API key.
+type OllamaClient struct {
+	cfg    OllamaConfig
+	client *http.Client
+	logger logger
+}
+
+// NewOllamaClient construye el cliente. Los modelos locales son lentos en laptops, por eso el
+// timeout es mas generoso que el de los proveedores remotos.
+func NewOllamaClient(cfg OllamaConfig, log any) *OllamaClient {
+	l := asLogger(log)
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultOllamaBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.EmbeddingModel == "" {
+		cfg.EmbeddingModel = DefaultOllamaEmbeddingModel
+	}
+	return &OllamaClient{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 5 * time.Minute},
+		logger: l,
+	}
+}
+
+// SetTimeout reemplaza el timeout de cada request HTTP (0 no lo limita).
+func (c *OllamaClient) SetTimeout(d time.Duration) { c.client.Timeout = d }
+
+func (c *OllamaClient) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := c.post(ctx, "/api/chat", c.chatRequest(prompt, false))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+
+	var cr ollamaChatResponse
+	if err := json.Unmarshal(respBody, &cr); err != nil {
+		return "", fmt.Errorf("unmarshal response: %w", err)
+	}
+	if cr.Error != "" {
+		return "", &APIError{Provider: "ollama", StatusCode: resp.StatusCode, Message: cr.Error}
+	}
+	if cr.Message.Content == "" {
+		return "", fmt.Errorf("llm empty response")
+	}
+	return cr.Message.Content, nil
+}
+
+// GenerateStream consume el stream de /api/chat: un objeto JSON por linea hasta el que trae done=true.
+func (c *OllamaClient) GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error) {
+	resp, err := c.post(ctx, "/api/chat", c.chatRequest(prompt, true))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return full.String(), fmt.Errorf("unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return full.String(), &APIError{Provider: "ollama", StatusCode: resp.StatusCode, Message: chunk.Error}
+		}
+		if text := chunk.Message.Content; text != "" {
+			full.WriteString(text)
+			if onChunk != nil {
+				if err := onChunk(text); err != nil {
+					return full.String(), err
+				}
+			}
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("read stream: %w", err)
+	}
+
+	if full.Len() == 0 {
+		return "", fmt.Errorf("llm empty response")
+	}
+	return full.String(), nil
+}
+
+// CreateEmbedding usa /api/embeddings con el modelo de embeddings configurado.
+func (c *OllamaClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	resp, err := c.post(ctx, "/api/embeddings", ollamaEmbeddingRequest{Model: c.cfg.EmbeddingModel, Prompt: text})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read embedding response: %w", err)
+	}
+
+	var er ollamaEmbeddingResponse
+	if err := json.Unmarshal(respBody, &er); err != nil {
+		return nil, fmt.Errorf("unmarshal embedding response: %w", err)
+	}
+	if er.Error != "" {
+		return nil, &APIError{Provider: "ollama", StatusCode: resp.StatusCode, Message: er.Error}
+	}
+	if len(er.Embedding) == 0 {
+		return nil, fmt.Errorf("embedding empty response")
+	}
+	return er.Embedding, nil
+}
+
+func (c *OllamaClient) chatRequest(prompt string, stream bool) ollamaChatRequest {
+	req := ollamaChatRequest{Model: c.cfg.Model, Stream: stream}
+	if c.cfg.SystemPrompt != "" {
+		req.Messages = append(req.Messages, chatMessage{Role: "system", Content: c.cfg.SystemPrompt})
+	}
+	req.Messages = append(req.Messages, chatMessage{Role: "user", Content: prompt})
+	if c.cfg.MaxTokens > 0 || c.cfg.Temperature != nil {
+		req.Options = &ollamaOptions{NumPredict: c.cfg.MaxTokens, Temperature: c.cfg.Temperature}
+	}
+	return req
+}
+
+// post envia body a path y devuelve la respuesta si el status es exitoso; los errores HTTP
+// se traducen a *APIError con el mensaje que informa Ollama (p.ej. modelo no descargado).
+func (c *OllamaClient) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+path, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if c.logger != nil {
+		c.logger.Printf("llm error status %d: %s", resp.StatusCode, string(respBody))
+	}
+	apiErr := &APIError{Provider: "ollama", StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header, time.Now())}
+	var er struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(respBody, &er) == nil {
+		apiErr.Message = er.Error
+	}
+	return nil, apiErr
+}
+
+type ollamaOptions struct {
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string         `json:"model"`
+	Messages []chatMessage  `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Options  *ollamaOptions `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message chatMessage `json:"message"`
+	Done    bool        `json:"done"`
+	Error   string      `json:"error,omitempty"`
+}
+
+type ollamaEmbeddingRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+}
+
+type ollamaEmbeddingResponse struct {
+	Embedding []float32 `json:"embedding"`
+	Error     string    `json:"error,omitempty"`
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllamaClient_Generate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if req.Model != "llama3.1" || req.Stream || req.Options == nil || req.Options.NumPredict != 256 {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[1].Content != "hola" {
+			t.Errorf("unexpected messages: %+v", req.Messages)
+		}
+		fmt.Fprint(w, `{"model":"llama3.1","message":{"role":"assistant","content":"que tal"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient(OllamaConfig{BaseURL: srv.URL + "/", Model: "llama3.1", MaxTokens: 256, SystemPrompt: "Sos un clon."}, nil)
+	got, err := client.Generate(context.Background(), "hola")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != "que tal" {
+		t.Fatalf("unexpected text: %q", got)
+	}
+}
+
+func TestOllamaClient_GenerateStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lines := []string{
+			`{"message":{"role":"assistant","content":"ho"},"done":false}`,
+			`{"message":{"role":"assistant","content":"la"},"done":false}`,
+			`{"message":{"role":"assistant","content":""},"done":true}`,
+		}
+		fmt.Fprint(w, strings.Join(lines, "\n"))
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient(OllamaConfig{BaseURL: srv.URL, Model: "llama3.1"}, nil)
+	var chunks []string
+	got, err := client.GenerateStream(context.Background(), "hola", func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != "hola" || strings.Join(chunks, "|") != "ho|la" {
+		t.Fatalf("unexpected stream result %q chunks %v", got, chunks)
+	}
+}
+
+func TestOllamaClient_CreateEmbedding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req ollamaEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if req.Model == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"model \"missing\" not found, try pulling it first"}`)
+			return
+		}
+		if req.Model != DefaultOllamaEmbeddingModel || req.Prompt != "hola" {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		fmt.Fprint(w, `{"embedding":[0.1,0.2,0.3]}`)
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient(OllamaConfig{BaseURL: srv.URL, Model: "llama3.1"}, nil)
+	got, err := client.CreateEmbedding(context.Background(), "hola")
+	if err != nil || len(got) != 3 {
+		t.Fatalf("expected embedding, got %v %v", got, err)
+	}
+
+	client = NewOllamaClient(OllamaConfig{BaseURL: srv.URL, Model: "llama3.1", EmbeddingModel: "missing"}, nil)
+	_, err = client.CreateEmbedding(context.Background(), "hola")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || !strings.Contains(apiErr.Message, "not found") {
+		t.Fatalf("expected not found api error, got %v", err)
+	}
+}
+
+type fixedEmbeddingClient struct {
+	LLMClient
+	embed []float32
+}
+
+func (c fixedEmbeddingClient) CreateEmbedding(context.Context, string) ([]float32, error) {
+	return c.embed, nil
+}
+
+func TestWithEmbeddingDimension(t *testing.T) {
+	client := WithEmbeddingDimension(fixedEmbeddingClient{embed: []float32{0.6, 0.8}}, 4)
+	got, err := client.CreateEmbedding(context.Background(), "hola")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(got) != 4 || got[0] != 0.6 || got[1] != 0.8 || got[2] != 0 || got[3] != 0 {
+		t.Fatalf("expected zero-padded embedding, got %v", got)
+	}
+
+	client = WithEmbeddingDimension(fixedEmbeddingClient{embed: []float32{1, 2, 3}}, 2)
+	if _, err := client.CreateEmbedding(context.Background(), "hola"); !errors.Is(err, ErrEmbeddingDimension) {
+		t.Fatalf("expected ErrEmbeddingDimension, got %v", err)
+	}
+}
+
+func TestNewClient_LocalProvidersWithoutAPIKey(t *testing.T) {
+	if _, err := NewClient(ProviderConfig{Provider: ProviderOpenAI, Model: "gpt"}, nil); !errors.Is(err, ErrAPIKeyRequired) {
+		t.Fatalf("expected ErrAPIKeyRequired for openai, got %v", err)
+	}
+
+	c, err := NewClient(ProviderConfig{Provider: ProviderOllama, Model: "llama3.1"}, nil)
+	if err != nil {
+		t.Fatalf("expected ollama provider, got %v", err)
+	}
+	if oc, ok := c.(*OllamaClient); !ok || oc.cfg.BaseURL != defaultOllamaBaseURL {
+		t.Fatalf("unexpected ollama client: %+v", c)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no bearer token without api key")
+		}
+		fmt.Fprint(w, `{"data":[{"embedding":[0.1,0.2]}]}`)
+	}))
+	defer srv.Close()
+
+	if _, err := NewClient(ProviderConfig{Provider: ProviderLlamaCpp, BaseURL: srv.URL + "/v1"}, nil); !errors.Is(err, ErrEmbeddingModelRequired) {
+		t.Fatalf("expected ErrEmbeddingModelRequired for llamacpp without EMBEDDING_MODEL, got %v", err)
+	}
+	c, err = NewClient(ProviderConfig{Provider: ProviderLlamaCpp, BaseURL: srv.URL + "/v1", EmbeddingModel: "bge-m3", EmbeddingDimension: 3}, nil)
+	if err != nil {
+		t.Fatalf("expected llamacpp provider, got %v", err)
+	}
+	got, err := c.CreateEmbedding(context.Background(), "hola")
+	if err != nil || len(got) != 3 {
+		t.Fatalf("expected padded llamacpp embedding, got %v %v", got, err)
+	}
+}
@@ -0,0 +1,173 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Proveedores soportados por NewClient (LLM_PROVIDER).
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	// ProviderOllama usa la API nativa de Ollama; ProviderLlamaCpp el modo OpenAI de llama.cpp server.
+	// Ambos corren en local y no necesitan API key.
+	ProviderOllama   = "ollama"
+	ProviderLlamaCpp = "llamacpp"
+)
+
+const defaultLlamaCppBaseURL = "http://localhost:8080/v1"
+
+var (
+	// ErrAPIKeyRequired se devuelve cuando un proveedor remoto no tiene API key configurada.
+	ErrAPIKeyRequired = errors.New("llm api key required")
+	// ErrEmbeddingModelRequired se devuelve con llamacpp sin EMBEDDING_MODEL: el server usa el modelo
+	// que tenga cargado y sin nombre explicito las memorias quedarian etiquetadas con un modelo ajeno.
+	ErrEmbeddingModelRequired = errors.New("embedding model required")
+)
+
+// ProviderConfig elige y configura el proveedor de generacion y, aparte, el de embeddings.
+type ProviderConfig struct {
+	Provider     string
+	BaseURL      string
+	APIKey       string
+	Model        string
+	MaxTokens    int
+	SystemPrompt string
+	// Temperature nil usa la del modelo (los modelos de razonamiento solo aceptan la suya).
+	Temperature *float64
+	// Embeddings: los proveedores sin endpoint propio (Anthropic) usan esta API compatible con OpenAI.
+	// APIKey solo se reutiliza si EmbeddingBaseURL apunta al mismo host que la generacion; si no,
+	// EmbeddingAPIKey es obligatoria para no mandar la clave de un proveedor a otro.
+	EmbeddingBaseURL string
+	EmbeddingAPIKey  string
+	// EmbeddingModel vacio usa el modelo por defecto del proveedor (ver EmbeddingModelName).
+	EmbeddingModel string
+	// EmbeddingDimension es el tamano de la columna vectorial; si es > 0 los embeddings se adaptan a el.
+	EmbeddingDimension int
+	// Resilience, si no es nil, envuelve el cliente con reintentos y circuit breaker; su CallTimeout
+	// reemplaza el timeout HTTP fijo de cada proveedor.
+	Resilience *ResilienceConfig
+
+	// generationOnly marca los clientes de rol, cuyos embeddings sirve el cliente base.
+	generationOnly bool
+}
+
+// EmbeddingModelName devuelve el modelo que efectivamente genera los embeddings, el que se guarda
+// en cada memoria.
+func (c ProviderConfig) EmbeddingModelName() string {
+	if model := strings.TrimSpace(c.EmbeddingModel); model != "" {
+		return model
+	}
+	if strings.ToLower(strings.TrimSpace(c.Provider)) == ProviderOllama {
+		return DefaultOllamaEmbeddingModel
+	}
+	return DefaultEmbeddingModel
+}
+
+// NewClient construye el LLMClient del proveedor configurado. Un proveedor vacio equivale a openai.
+func NewClient(cfg ProviderConfig, log any) (LLMClient, error) {
+	client, err := newProviderClient(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Resilience != nil {
+		if t, ok := client.(interface{ SetTimeout(time.Duration) }); ok {
+			t.SetTimeout(cfg.Resilience.CallTimeout)
+		}
+	}
+	client = WithEmbeddingDimension(client, cfg.EmbeddingDimension)
+	if cfg.Resilience != nil {
+		client = NewResilientClient(client, *cfg.Resilience, log)
+	}
+	return client, nil
+}
+
+func newProviderClient(cfg ProviderConfig, log any) (LLMClient, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	switch provider {
+	case "", ProviderOpenAI, ProviderAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("%w for provider %q", ErrAPIKeyRequired, cfg.Provider)
+		}
+	}
+
+	switch provider {
+	case "", ProviderOpenAI:
+		client := NewHTTPClient(cfg.BaseURL, cfg.APIKey, cfg.Model, log)
+		client.SetEmbeddingModel(cfg.EmbeddingModel)
+		client.SetTemperature(cfg.Temperature)
+		return client, nil
+	case ProviderAnthropic:
+		var embedder Embedder
+		if cfg.EmbeddingBaseURL != "" {
+			embeddingKey, err := anthropicEmbeddingKey(cfg)
+			if err != nil {
+				return nil, err
+			}
+			client := NewHTTPClient(cfg.EmbeddingBaseURL, embeddingKey, "", log)
+			client.SetEmbeddingModel(cfg.EmbeddingModel)
+			embedder = client
+		}
+		return NewAnthropicClient(AnthropicConfig{
+			BaseURL:      cfg.BaseURL,
+			APIKey:       cfg.APIKey,
+			Model:        cfg.Model,
+			MaxTokens:    cfg.MaxTokens,
+			SystemPrompt: cfg.SystemPrompt,
+			Temperature:  cfg.Temperature,
+		}, embedder, log), nil
+	case ProviderOllama:
+		return NewOllamaClient(OllamaConfig{
+			BaseURL:        cfg.BaseURL,
+			Model:          cfg.Model,
+			EmbeddingModel: cfg.EmbeddingModel,
+			MaxTokens:      cfg.MaxTokens,
+			SystemPrompt:   cfg.SystemPrompt,
+			Temperature:    cfg.Temperature,
+		}, log), nil
+	case ProviderLlamaCpp:
+		// llama.cpp server expone /v1/chat/completions y /v1/embeddings (con --embeddings) y
+		// solo valida la clave si se inicio con --api-key.
+		if strings.TrimSpace(cfg.EmbeddingModel) == "" && !cfg.generationOnly {
+			return nil, fmt.Errorf("%w for provider %q: set EMBEDDING_MODEL to the model loaded in llama-server", ErrEmbeddingModelRequired, cfg.Provider)
+		}
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = defaultLlamaCppBaseURL
+		}
+		client := NewHTTPClient(baseURL, cfg.APIKey, cfg.Model, log)
+		client.SetEmbeddingModel(cfg.EmbeddingModel)
+		client.SetTemperature(cfg.Temperature)
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider %q", cfg.Provider)
+	}
+}
+
+// anthropicEmbeddingKey elige la clave del endpoint de embeddings. La de Anthropic solo se reutiliza
+// si ese endpoint esta en el mismo host (p.ej. un proxy propio que sirve ambos).
+func anthropicEmbeddingKey(cfg ProviderConfig) (string, error) {
+	if cfg.EmbeddingAPIKey != "" {
+		return cfg.EmbeddingAPIKey, nil
+	}
+	generationURL := cfg.BaseURL
+	if generationURL == "" {
+		generationURL = defaultAnthropicBaseURL
+	}
+	if sameHost(generationURL, cfg.EmbeddingBaseURL) {
+		return cfg.APIKey, nil
+	}
+	return "", fmt.Errorf("%w: embeddings at %s need EMBEDDING_API_KEY", ErrAPIKeyRequired, cfg.EmbeddingBaseURL)
+}
+
+func sameHost(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil || ua.Host == "" {
+		return false
+	}
+	return strings.EqualFold(ua.Host, ub.Host)
+}
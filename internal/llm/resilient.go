@@ -0,0 +1,243 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 10 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ResilienceConfig define reintentos, deadlines y circuit breaker de ResilientClient.
+type ResilienceConfig struct {
+	// MaxRetries son los reintentos despues del primer intento (0 no reintenta).
+	MaxRetries int
+	// BaseDelay se duplica en cada reintento hasta MaxDelay. Un Retry-After mas largo que
+	// MaxDelay no se espera: el error vuelve enseguida al llamador.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// CallTimeout es el deadline de cada intento (incluye el stream completo); 0 no lo limita.
+	CallTimeout time.Duration
+	// BreakerThreshold llamadas fallidas seguidas abren el circuito durante BreakerCooldown.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// ResilientClient envuelve un LLMClient con reintentos con backoff exponencial para 429, 5xx,
+// timeouts y errores de red, un deadline por intento y un circuit breaker. Con el circuito abierto
+// las llamadas fallan enseguida con ErrProviderDown en lugar de esperar al proveedor caido; pasado
+// el cooldown se vuelve a probar y el primer exito lo cierra.
+type ResilientClient struct {
+	next   LLMClient
+	cfg    ResilienceConfig
+	logger logger
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewResilientClient construye el decorador con los defaults para los campos en cero.
+func NewResilientClient(next LLMClient, cfg ResilienceConfig, log any) *ResilientClient {
+	l := asLogger(log)
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = defaultRetryBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = defaultRetryMaxDelay
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = defaultBreakerThreshold
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = defaultBreakerCooldown
+	}
+	return &ResilientClient{
+		next:   next,
+		cfg:    cfg,
+		logger: l,
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+}
+
+func (c *ResilientClient) Generate(ctx context.Context, prompt string) (string, error) {
+	var out string
+	err := c.call(ctx, "generate", func(ctx context.Context) (bool, error) {
+		var err error
+		out, err = c.next.Generate(ctx, prompt)
+		return true, err
+	})
+	return out, err
+}
+
+// GenerateStream solo reintenta si el proveedor fallo antes de emitir el primer fragmento:
+// despues el llamador ya mostro texto y repetir duplicaria la respuesta.
+func (c *ResilientClient) GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error) {
+	var out string
+	err := c.call(ctx, "generate stream", func(ctx context.Context) (bool, error) {
+		emitted := false
+		var err error
+		out, err = c.next.GenerateStream(ctx, prompt, func(chunk string) error {
+			emitted = true
+			if onChunk != nil {
+				return onChunk(chunk)
+			}
+			return nil
+		})
+		return !emitted, err
+	})
+	return out, err
+}
+
+func (c *ResilientClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	var out []float32
+	err := c.call(ctx, "create embedding", func(ctx context.Context) (bool, error) {
+		var err error
+		out, err = c.next.CreateEmbedding(ctx, text)
+		return true, err
+	})
+	return out, err
+}
+
+// call ejecuta fn con reintentos. fn devuelve si el intento fallido se puede repetir.
+func (c *ResilientClient) call(ctx context.Context, op string, fn func(ctx context.Context) (bool, error)) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.allow(); err != nil {
+			return err
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if c.cfg.CallTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, c.cfg.CallTimeout)
+		}
+		retryable, err := fn(attemptCtx)
+		timedOut := ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		if err == nil {
+			c.recordSuccess()
+			return nil
+		}
+		if ctx.Err() != nil {
+			// Cancelo el llamador (p.ej. el cliente corto el stream): no es culpa del proveedor.
+			return err
+		}
+		if timedOut {
+			err = fmt.Errorf("%w: %s timed out after %s: %w", ErrProviderDown, op, c.cfg.CallTimeout, err)
+		} else if isNetworkError(err) {
+			err = fmt.Errorf("%w: %w", ErrProviderDown, err)
+		}
+		if !transient(err) {
+			// El proveedor respondio (p.ej. 400 por contexto largo): el circuito no cuenta el fallo.
+			c.recordSuccess()
+			return err
+		}
+
+		delay, ok := c.retryDelay(attempt, err)
+		if !retryable || !ok {
+			c.recordFailure()
+			return err
+		}
+		if c.logger != nil {
+			c.logger.Printf("llm %s failed (attempt %d), retrying in %s: %v", op, attempt+1, delay, err)
+		}
+		if err := c.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// retryDelay devuelve la espera antes del reintento attempt+1, o false si no hay que reintentar.
+func (c *ResilientClient) retryDelay(attempt int, err error) (time.Duration, bool) {
+	if attempt >= c.cfg.MaxRetries {
+		return 0, false
+	}
+	if wait := RetryAfter(err); wait > 0 {
+		if wait > c.cfg.MaxDelay {
+			return 0, false
+		}
+		return wait, true
+	}
+	delay := c.cfg.BaseDelay << attempt
+	if delay <= 0 || delay > c.cfg.MaxDelay {
+		delay = c.cfg.MaxDelay
+	}
+	// Hasta un 20% de jitter para que los turnos concurrentes no reintenten todos a la vez.
+	return delay + rand.N(delay/5+1), true
+}
+
+func (c *ResilientClient) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now := c.now(); now.Before(c.openUntil) {
+		return &circuitOpenError{retryAfter: c.openUntil.Sub(now)}
+	}
+	return nil
+}
+
+func (c *ResilientClient) recordSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+	c.openUntil = time.Time{}
+}
+
+func (c *ResilientClient) recordFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures++
+	if c.failures >= c.cfg.BreakerThreshold {
+		c.openUntil = c.now().Add(c.cfg.BreakerCooldown)
+		if c.logger != nil {
+			c.logger.Printf("llm circuit open for %s after %d failed calls", c.cfg.BreakerCooldown, c.failures)
+		}
+	}
+}
+
+// circuitOpenError es el rechazo inmediato con el circuito abierto; equivale a ErrProviderDown.
+type circuitOpenError struct {
+	retryAfter time.Duration
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("llm circuit open, retry in %s", e.retryAfter.Round(time.Second))
+}
+
+func (e *circuitOpenError) Is(target error) bool { return target == ErrProviderDown }
+
+// transient indica si el error puede resolverse reintentando.
+func transient(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrProviderDown)
+}
+
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
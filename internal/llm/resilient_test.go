@@ -0,0 +1,207 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// scriptedClient devuelve los errores de script en orden y despues responde "ok".
+type scriptedClient struct {
+	script []error
+	calls  int
+	chunk  bool // GenerateStream emite un fragmento antes de fallar
+}
+
+func (s *scriptedClient) next() error {
+	s.calls++
+	if len(s.script) == 0 {
+		return nil
+	}
+	err := s.script[0]
+	s.script = s.script[1:]
+	return err
+}
+
+func (s *scriptedClient) Generate(context.Context, string) (string, error) {
+	if err := s.next(); err != nil {
+		return "", err
+	}
+	return "ok", nil
+}
+
+func (s *scriptedClient) GenerateStream(_ context.Context, _ string, onChunk func(string) error) (string, error) {
+	if s.chunk {
+		_ = onChunk("ho")
+	}
+	if err := s.next(); err != nil {
+		return "ho", err
+	}
+	return "hola", nil
+}
+
+func (s *scriptedClient) CreateEmbedding(context.Context, string) ([]float32, error) {
+	if err := s.next(); err != nil {
+		return nil, err
+	}
+	return []float32{1}, nil
+}
+
+func newTestResilient(next LLMClient, cfg ResilienceConfig) (*ResilientClient, *[]time.Duration) {
+	c := NewResilientClient(next, cfg, nil)
+	var waits []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return c, &waits
+}
+
+func TestResilientClient_RetriesHonoringRetryAfter(t *testing.T) {
+	next := &scriptedClient{script: []error{
+		&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second},
+		&APIError{StatusCode: http.StatusBadGateway},
+	}}
+	c, waits := newTestResilient(next, ResilienceConfig{MaxRetries: 2, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second})
+
+	got, err := c.Generate(context.Background(), "hola")
+	if err != nil || got != "ok" || next.calls != 3 {
+		t.Fatalf("expected success on third attempt, got %q %v (calls=%d)", got, err, next.calls)
+	}
+	if len(*waits) != 2 || (*waits)[0] != 2*time.Second {
+		t.Fatalf("expected Retry-After wait first, got %v", *waits)
+	}
+	if w := (*waits)[1]; w < 200*time.Millisecond || w > 240*time.Millisecond {
+		t.Fatalf("expected exponential backoff with jitter, got %v", w)
+	}
+}
+
+func TestResilientClient_LogsRetriesAndBreakerThroughZap(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	next := &scriptedClient{script: []error{
+		&APIError{StatusCode: http.StatusBadGateway},
+		&APIError{StatusCode: http.StatusBadGateway},
+	}}
+	c := NewResilientClient(next, ResilienceConfig{MaxRetries: 1, BreakerThreshold: 1}, zap.New(core))
+	c.sleep = func(context.Context, time.Duration) error { return nil }
+
+	if _, err := c.Generate(context.Background(), "hola"); err == nil {
+		t.Fatalf("expected the second failure to be returned")
+	}
+	if n := logs.FilterMessageSnippet("retrying in").Len(); n != 1 {
+		t.Fatalf("expected one retry log entry, got %d", n)
+	}
+	if n := logs.FilterMessageSnippet("circuit open").Len(); n != 1 {
+		t.Fatalf("expected one breaker log entry, got %d", n)
+	}
+}
+
+func TestResilientClient_DoesNotRetryPermanentErrors(t *testing.T) {
+	next := &scriptedClient{script: []error{
+		&APIError{StatusCode: http.StatusBadRequest, Code: "context_length_exceeded"},
+	}}
+	c, waits := newTestResilient(next, ResilienceConfig{MaxRetries: 3})
+
+	_, err := c.Generate(context.Background(), "hola")
+	if !errors.Is(err, ErrContextTooLong) || next.calls != 1 || len(*waits) != 0 {
+		t.Fatalf("expected immediate ErrContextTooLong, got %v (calls=%d)", err, next.calls)
+	}
+
+	next = &scriptedClient{script: []error{&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}}}
+	c, _ = newTestResilient(next, ResilienceConfig{MaxRetries: 3, MaxDelay: 10 * time.Second})
+	if _, err := c.Generate(context.Background(), "hola"); !errors.Is(err, ErrRateLimited) || next.calls != 1 {
+		t.Fatalf("expected ErrRateLimited without waiting a long Retry-After, got %v (calls=%d)", err, next.calls)
+	}
+}
+
+func TestResilientClient_StreamNotRetriedAfterFirstChunk(t *testing.T) {
+	next := &scriptedClient{chunk: true, script: []error{&APIError{StatusCode: http.StatusInternalServerError}}}
+	c, _ := newTestResilient(next, ResilienceConfig{MaxRetries: 3})
+
+	_, err := c.GenerateStream(context.Background(), "hola", func(string) error { return nil })
+	if !errors.Is(err, ErrProviderDown) || next.calls != 1 {
+		t.Fatalf("expected single attempt once text was emitted, got %v (calls=%d)", err, next.calls)
+	}
+}
+
+func TestResilientClient_CallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c, _ := newTestResilient(NewHTTPClient(srv.URL, "k", "gpt", nil), ResilienceConfig{CallTimeout: 50 * time.Millisecond})
+	_, err := c.Generate(context.Background(), "hola")
+	if !errors.Is(err, ErrProviderDown) {
+		t.Fatalf("expected ErrProviderDown on deadline, got %v", err)
+	}
+}
+
+func TestResilientClient_CircuitBreaker(t *testing.T) {
+	down := &APIError{StatusCode: http.StatusServiceUnavailable}
+	next := &scriptedClient{script: []error{down, down}}
+	c, _ := newTestResilient(next, ResilienceConfig{BreakerThreshold: 2, BreakerCooldown: 30 * time.Second})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Generate(context.Background(), "hola"); !errors.Is(err, ErrProviderDown) {
+			t.Fatalf("expected ErrProviderDown, got %v", err)
+		}
+	}
+
+	_, err := c.Generate(context.Background(), "hola")
+	if !errors.Is(err, ErrProviderDown) || next.calls != 2 {
+		t.Fatalf("expected open circuit to fail fast, got %v (calls=%d)", err, next.calls)
+	}
+	if RetryAfter(err) != 30*time.Second {
+		t.Fatalf("expected cooldown as retry-after, got %v", RetryAfter(err))
+	}
+
+	now = now.Add(31 * time.Second)
+	if got, err := c.Generate(context.Background(), "hola"); err != nil || got != "ok" {
+		t.Fatalf("expected trial call after cooldown to succeed, got %q %v", got, err)
+	}
+	if c.failures != 0 {
+		t.Fatalf("expected success to close the circuit, failures=%d", c.failures)
+	}
+}
+
+func TestHTTPClient_MapsErrorResponses(t *testing.T) {
+	cases := []struct {
+		status int
+		header string
+		body   string
+		want   error
+	}{
+		{http.StatusTooManyRequests, "3", `{"error":{"message":"slow down","type":"requests"}}`, ErrRateLimited},
+		{http.StatusBadRequest, "", `{"error":{"message":"too long","type":"invalid_request_error","code":"context_length_exceeded"}}`, ErrContextTooLong},
+		{http.StatusServiceUnavailable, "", `{"error":{"message":"overloaded","code":null}}`, ErrProviderDown},
+	}
+	for _, tc := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tc.header != "" {
+				w.Header().Set("Retry-After", tc.header)
+			}
+			w.WriteHeader(tc.status)
+			fmt.Fprint(w, tc.body)
+		}))
+		_, err := NewHTTPClient(srv.URL, "k", "gpt", nil).Generate(context.Background(), "hola")
+		srv.Close()
+		if !errors.Is(err, tc.want) {
+			t.Fatalf("status %d: expected %v, got %v", tc.status, tc.want, err)
+		}
+		if tc.header != "" && RetryAfter(err) != 3*time.Second {
+			t.Fatalf("expected Retry-After 3s, got %v", RetryAfter(err))
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Role identifica el uso de una llamada al LLM para poder servirla con otro proveedor o modelo.
+type Role string
+
+const (
+	// RoleReply genera la respuesta del clon (el modelo fuerte).
+	RoleReply Role = "reply"
+	// RoleAnalysis extrae emociones, recuerdos y personajes de cada mensaje.
+	RoleAnalysis Role = "analysis"
+	// RoleEvocation y RoleJudge son las llamadas auxiliares de la recuperacion de memorias.
+	RoleEvocation Role = "evocation"
+	RoleJudge     Role = "judge"
+	// RoleConsolidation resume sesiones y sintetiza reflexiones en segundo plano.
+	RoleConsolidation Role = "consolidation"
+)
+
+// RoleConfig sobreescribe la configuracion base para un rol. Los campos vacios heredan de la base;
+// BaseURL y APIKey solo se heredan si el rol usa el mismo proveedor.
+type RoleConfig struct {
+	Provider string
+	BaseURL  string
+	APIKey   string
+	Model    string
+	// Temperature nil hereda la temperatura base.
+	Temperature *float64
+}
+
+func (r RoleConfig) isZero() bool {
+	return strings.TrimSpace(r.Provider) == "" && r.BaseURL == "" && r.APIKey == "" &&
+		strings.TrimSpace(r.Model) == "" && r.Temperature == nil
+}
+
+// apply devuelve la configuracion base con las sobreescrituras del rol. Los embeddings del rol
+// los sirve el cliente base, asi que su cliente no configura endpoint de embeddings propio.
+func (r RoleConfig) apply(base ProviderConfig) ProviderConfig {
+	cfg := base
+	cfg.EmbeddingBaseURL = ""
+	cfg.EmbeddingAPIKey = ""
+	cfg.generationOnly = true
+	if provider := strings.TrimSpace(r.Provider); provider != "" && normalizeProvider(provider) != normalizeProvider(base.Provider) {
+		cfg.Provider = provider
+		cfg.BaseURL = ""
+		cfg.APIKey = ""
+	}
+	if r.BaseURL != "" {
+		cfg.BaseURL = r.BaseURL
+	}
+	if r.APIKey != "" {
+		cfg.APIKey = r.APIKey
+	}
+	if model := strings.TrimSpace(r.Model); model != "" {
+		cfg.Model = model
+	}
+	if r.Temperature != nil {
+		cfg.Temperature = r.Temperature
+	}
+	return cfg
+}
+
+func normalizeProvider(p string) string {
+	p = strings.ToLower(strings.TrimSpace(p))
+	if p == "" {
+		return ProviderOpenAI
+	}
+	return p
+}
+
+// Router entrega un LLMClient por rol. Los roles sin configuracion propia comparten el cliente base.
+// Los embeddings siempre salen del cliente base: cambiar el modelo de un rol no puede mezclar
+// vectores de otro modelo en narrative_memories.
+type Router struct {
+	base    LLMClient
+	clients map[Role]LLMClient
+}
+
+// NewRouter construye el cliente base y uno por cada rol configurado. Cada cliente de rol tiene
+// sus propios reintentos y circuit breaker, asi un modelo auxiliar caido no corta las respuestas.
+func NewRouter(base ProviderConfig, roles map[Role]RoleConfig, log any) (*Router, error) {
+	baseClient, err := NewClient(base, log)
+	if err != nil {
+		return nil, err
+	}
+	r := &Router{base: baseClient, clients: make(map[Role]LLMClient)}
+	for role, rc := range roles {
+		if rc.isZero() {
+			continue
+		}
+		cfg := rc.apply(base)
+		client, err := NewClient(cfg, log)
+		if err != nil {
+			return nil, fmt.Errorf("llm role %s: %w", role, err)
+		}
+		r.clients[role] = &roleClient{gen: client, embed: baseClient}
+		if l := asLogger(log); l != nil {
+			l.Printf("llm role %s: provider=%s model=%s", role, normalizeProvider(cfg.Provider), cfg.Model)
+		}
+	}
+	return r, nil
+}
+
+// For devuelve el cliente del rol, o el base si el rol no tiene configuracion propia.
+func (r *Router) For(role Role) LLMClient {
+	if client, ok := r.clients[role]; ok {
+		return client
+	}
+	return r.base
+}
+
+// Base devuelve el cliente de la configuracion LLM_* (tambien el de embeddings).
+func (r *Router) Base() LLMClient { return r.base }
+
+// roleClient genera con el modelo del rol y delega los embeddings en el cliente base.
+type roleClient struct {
+	gen   LLMClient
+	embed LLMClient
+}
+
+func (c *roleClient) Generate(ctx context.Context, prompt string) (string, error) {
+	return c.gen.Generate(ctx, prompt)
+}
+
+func (c *roleClient) GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error) {
+	return c.gen.GenerateStream(ctx, prompt, onChunk)
+}
+
+func (c *roleClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return c.embed.CreateEmbedding(ctx, text)
+}
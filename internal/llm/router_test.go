@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// chatRecorder es un servidor compatible con OpenAI que guarda el ultimo request de chat y de embeddings.
+type chatRecorder struct {
+	chat      map[string]any
+	embedding map[string]any
+}
+
+func (rec *chatRecorder) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if r.URL.Path == "/embeddings" {
+			rec.embedding = body
+			fmt.Fprint(w, `{"data":[{"embedding":[0.1,0.2]}]}`)
+			return
+		}
+		rec.chat = body
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":"ok %v"}}]}`, body["model"])
+	}))
+}
+
+func TestRouter_RoleOverridesInheritBase(t *testing.T) {
+	base, small := &chatRecorder{}, &chatRecorder{}
+	baseSrv, smallSrv := base.serve(t), small.serve(t)
+	defer baseSrv.Close()
+	defer smallSrv.Close()
+
+	temp := 0.2
+	router, err := NewRouter(ProviderConfig{BaseURL: baseSrv.URL, APIKey: "k", Model: "gpt-5.1", EmbeddingModel: "emb"}, map[Role]RoleConfig{
+		RoleJudge:     {Model: "gpt-5-mini", Temperature: &temp},
+		RoleEvocation: {Provider: ProviderLlamaCpp, BaseURL: smallSrv.URL, Model: "qwen"},
+		RoleReply:     {},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected router, got %v", err)
+	}
+	ctx := context.Background()
+
+	if router.For(RoleReply) != router.Base() || router.For(RoleAnalysis) != router.Base() {
+		t.Fatalf("expected roles without overrides to share the base client")
+	}
+
+	if got, err := router.For(RoleJudge).Generate(ctx, "hola"); err != nil || got != "ok gpt-5-mini" {
+		t.Fatalf("expected judge model on base endpoint, got %q %v", got, err)
+	}
+	if base.chat["temperature"] != 0.2 {
+		t.Fatalf("expected judge temperature 0.2, got %v", base.chat["temperature"])
+	}
+
+	if got, err := router.For(RoleEvocation).Generate(ctx, "hola"); err != nil || got != "ok qwen" {
+		t.Fatalf("expected evocation on its own provider, got %q %v", got, err)
+	}
+	if _, ok := small.chat["temperature"]; ok {
+		t.Fatalf("expected model default temperature, got %v", small.chat["temperature"])
+	}
+
+	if _, err := router.For(RoleEvocation).CreateEmbedding(ctx, "hola"); err != nil {
+		t.Fatalf("expected embedding, got %v", err)
+	}
+	if small.embedding != nil || base.embedding["model"] != "emb" {
+		t.Fatalf("expected embeddings from the base client, got base=%v role=%v", base.embedding, small.embedding)
+	}
+}
+
+func TestRouter_ProviderChangeDoesNotInheritCredentials(t *testing.T) {
+	_, err := NewRouter(ProviderConfig{Provider: ProviderOllama, Model: "llama3"}, map[Role]RoleConfig{
+		RoleReply: {Provider: ProviderAnthropic, Model: "claude"},
+	}, nil)
+	if !errors.Is(err, ErrAPIKeyRequired) {
+		t.Fatalf("expected ErrAPIKeyRequired for role without key, got %v", err)
+	}
+
+	// Los embeddings del rol salen del cliente base: un rol anthropic no necesita EMBEDDING_API_KEY.
+	if _, err := NewRouter(ProviderConfig{Provider: ProviderOpenAI, APIKey: "base-key", EmbeddingBaseURL: "https://api.openai.com/v1"}, map[Role]RoleConfig{
+		RoleJudge: {Provider: ProviderAnthropic, APIKey: "anthropic-key", Model: "claude"},
+	}, nil); err != nil {
+		t.Fatalf("expected anthropic role without embedding key, got %v", err)
+	}
+
+	if _, err := NewRouter(ProviderConfig{Provider: ProviderOpenAI, APIKey: "base-key"}, map[Role]RoleConfig{
+		RoleEvocation: {Provider: ProviderLlamaCpp, Model: "qwen"},
+	}, nil); err != nil {
+		t.Fatalf("expected llamacpp role without EMBEDDING_MODEL, got %v", err)
+	}
+
+	router, err := NewRouter(ProviderConfig{Provider: ProviderOpenAI, APIKey: "base-key", BaseURL: "http://base"}, map[Role]RoleConfig{
+		RoleAnalysis: {Provider: "openai", Model: "gpt-5-mini"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected same provider to inherit credentials, got %v", err)
+	}
+	hc, ok := router.For(RoleAnalysis).(*roleClient).gen.(*HTTPClient)
+	if !ok || hc.baseURL != "http://base" || hc.apiKey != "base-key" || hc.model != "gpt-5-mini" {
+		t.Fatalf("unexpected analysis client: %+v", router.For(RoleAnalysis))
+	}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"clone-llm/internal/domain"
+)
+
+type CharacterAliasRepository interface {
+	Create(ctx context.Context, alias domain.CharacterAlias) error
+	ListByCharacter(ctx context.Context, characterID uuid.UUID) ([]domain.CharacterAlias, error)
+	Delete(ctx context.Context, characterID, aliasID uuid.UUID) error
+}
+
+type PgCharacterAliasRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgCharacterAliasRepository(pool *pgxpool.Pool) *PgCharacterAliasRepository {
+	return &PgCharacterAliasRepository{pool: pool}
+}
+
+func (r *PgCharacterAliasRepository) Create(ctx context.Context, alias domain.CharacterAlias) error {
+	const query = `
+		INSERT INTO character_aliases (id, character_id, alias, normalized, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.pool.Exec(ctx, query, alias.ID, alias.CharacterID, alias.Alias, alias.Normalized, alias.CreatedAt)
+	return mapUniqueViolation(err)
+}
+
+// ListByCharacter devuelve los apodos del personaje en orden alfabetico.
+func (r *PgCharacterAliasRepository) ListByCharacter(ctx context.Context, characterID uuid.UUID) ([]domain.CharacterAlias, error) {
+	const query = `
+		SELECT id, character_id, alias, normalized, created_at
+		FROM character_aliases
+		WHERE character_id = $1
+		ORDER BY alias ASC
+	`
+	rows, err := r.pool.Query(ctx, query, characterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []domain.CharacterAlias
+	for rows.Next() {
+		var a domain.CharacterAlias
+		if err := rows.Scan(&a.ID, &a.CharacterID, &a.Alias, &a.Normalized, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// Delete borra el apodo del personaje. Devuelve pgx.ErrNoRows si no existe.
+func (r *PgCharacterAliasRepository) Delete(ctx context.Context, characterID, aliasID uuid.UUID) error {
+	const query = `
+		DELETE FROM character_aliases
+		WHERE id = $1 AND character_id = $2
+	`
+	tag, err := r.pool.Exec(ctx, query, aliasID, characterID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
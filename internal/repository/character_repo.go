@@ -2,19 +2,40 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"clone-llm/internal/domain"
 )
 
+// ErrAlreadyExists indica que el alta choco con una restriccion de unicidad: otro request creo
+// el mismo registro entre la validacion y el INSERT.
+var ErrAlreadyExists = errors.New("already exists")
+
+// uniqueViolation es el codigo SQLSTATE de Postgres para unique_violation.
+const uniqueViolation = "23505"
+
+// mapUniqueViolation traduce unique_violation a ErrAlreadyExists y deja pasar el resto de errores.
+func mapUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
 type CharacterRepository interface {
 	Create(ctx context.Context, character domain.Character) error
 	Update(ctx context.Context, character domain.Character) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Character, error)
 	ListByProfileID(ctx context.Context, profileID uuid.UUID) ([]domain.Character, error)
 	FindByName(ctx context.Context, profileID uuid.UUID, name string) (*domain.Character, error)
+	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 type PgCharacterRepository struct {
@@ -43,7 +64,7 @@ func (r *PgCharacterRepository) Create(ctx context.Context, character domain.Cha
 		character.CreatedAt,
 		character.UpdatedAt,
 	)
-	return err
+	return mapUniqueViolation(err)
 }
 
 func (r *PgCharacterRepository) Update(ctx context.Context, character domain.Character) error {
@@ -66,10 +87,37 @@ func (r *PgCharacterRepository) Update(ctx context.Context, character domain.Cha
 	return err
 }
 
-func (r *PgCharacterRepository) ListByProfileID(ctx context.Context, profileID uuid.UUID) ([]domain.Character, error) {
+func (r *PgCharacterRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Character, error) {
 	const query = `
 		SELECT id, clone_profile_id, name, relation, archetype, bond_status, trust, intimacy, respect, created_at, updated_at
 		FROM characters
+		WHERE id = $1
+	`
+	var c domain.Character
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&c.ID,
+		&c.CloneProfileID,
+		&c.Name,
+		&c.Relation,
+		&c.Archetype,
+		&c.BondStatus,
+		&c.Relationship.Trust,
+		&c.Relationship.Intimacy,
+		&c.Relationship.Respect,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *PgCharacterRepository) ListByProfileID(ctx context.Context, profileID uuid.UUID) ([]domain.Character, error) {
+	const query = `
+		SELECT id, clone_profile_id, name, relation, archetype, bond_status, trust, intimacy, respect, created_at, updated_at,
+			ARRAY(SELECT a.alias FROM character_aliases a WHERE a.character_id = characters.id ORDER BY a.alias) AS aliases
+		FROM characters
 		WHERE clone_profile_id = $1
 		ORDER BY intimacy DESC, trust DESC, name ASC
 	`
@@ -94,6 +142,7 @@ func (r *PgCharacterRepository) ListByProfileID(ctx context.Context, profileID u
 			&c.Relationship.Respect,
 			&c.CreatedAt,
 			&c.UpdatedAt,
+			&c.Aliases,
 		); err != nil {
 			return nil, err
 		}
@@ -130,3 +179,20 @@ func (r *PgCharacterRepository) FindByName(ctx context.Context, profileID uuid.U
 	}
 	return &c, nil
 }
+
+// Delete borra el personaje (sus apodos y eventos caen en cascada; sus memorias quedan sin personaje).
+// Devuelve pgx.ErrNoRows si no existe.
+func (r *PgCharacterRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	const query = `
+		DELETE FROM characters
+		WHERE id = $1
+	`
+	tag, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"clone-llm/internal/domain"
+)
+
+type FactRepository interface {
+	Create(ctx context.Context, fact domain.Fact) error
+	ListActive(ctx context.Context, profileID uuid.UUID, limit int) ([]domain.Fact, error)
+	FindActiveByKey(ctx context.Context, profileID uuid.UUID, subjectKey, predicateKey string) ([]domain.Fact, error)
+	Reinforce(ctx context.Context, id uuid.UUID, confidence float64, at time.Time) error
+	// CreateContradicting guarda el hecho y retira los que contradice en una sola transaccion.
+	CreateContradicting(ctx context.Context, fact domain.Fact, contradicted []uuid.UUID, at time.Time) error
+}
+
+type PgFactRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgFactRepository(pool *pgxpool.Pool) *PgFactRepository {
+	return &PgFactRepository{pool: pool}
+}
+
+const factColumns = `id, clone_profile_id, subject, predicate, object, subject_key, predicate_key,
+	source_message_id, source_session_id, confidence, contradicted_by, created_at, updated_at`
+
+func (r *PgFactRepository) Create(ctx context.Context, fact domain.Fact) error {
+	return insertFact(ctx, r.pool, fact)
+}
+
+func insertFact(ctx context.Context, q interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}, fact domain.Fact) error {
+	query := `
+		INSERT INTO facts (` + factColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+	_, err := q.Exec(ctx, query,
+		fact.ID,
+		fact.CloneProfileID,
+		fact.Subject,
+		fact.Predicate,
+		fact.Object,
+		fact.SubjectKey,
+		fact.PredicateKey,
+		fact.SourceMessageID,
+		fact.SourceSessionID,
+		fact.Confidence,
+		fact.ContradictedBy,
+		fact.CreatedAt,
+		fact.UpdatedAt,
+	)
+	return err
+}
+
+// ListActive devuelve los hechos vigentes del clon, primero los mas confiables y recientes.
+func (r *PgFactRepository) ListActive(ctx context.Context, profileID uuid.UUID, limit int) ([]domain.Fact, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `
+		SELECT ` + factColumns + `
+		FROM facts
+		WHERE clone_profile_id = $1
+		  AND contradicted_by IS NULL
+		ORDER BY confidence DESC, updated_at DESC
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, profileID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFacts(rows)
+}
+
+// FindActiveByKey devuelve los hechos vigentes con el mismo sujeto y predicado (formas plegadas).
+func (r *PgFactRepository) FindActiveByKey(ctx context.Context, profileID uuid.UUID, subjectKey, predicateKey string) ([]domain.Fact, error) {
+	query := `
+		SELECT ` + factColumns + `
+		FROM facts
+		WHERE clone_profile_id = $1
+		  AND subject_key = $2
+		  AND predicate_key = $3
+		  AND contradicted_by IS NULL
+		ORDER BY created_at ASC
+	`
+	rows, err := r.pool.Query(ctx, query, profileID, subjectKey, predicateKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFacts(rows)
+}
+
+// Reinforce actualiza la confianza de un hecho que se volvio a observar.
+func (r *PgFactRepository) Reinforce(ctx context.Context, id uuid.UUID, confidence float64, at time.Time) error {
+	const query = `
+		UPDATE facts
+		SET confidence = $2, updated_at = $3
+		WHERE id = $1
+	`
+	tag, err := r.pool.Exec(ctx, query, id, confidence, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// CreateContradicting guarda el hecho nuevo y apunta a el los hechos que contradice. Todo o nada:
+// si un hecho viejo ya no existe no queda el nuevo vigente junto a su contradiccion.
+func (r *PgFactRepository) CreateContradicting(ctx context.Context, fact domain.Fact, contradicted []uuid.UUID, at time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertFact(ctx, tx, fact); err != nil {
+		return err
+	}
+	const query = `
+		UPDATE facts
+		SET contradicted_by = $2, updated_at = $3
+		WHERE id = $1
+	`
+	for _, id := range contradicted {
+		tag, err := tx.Exec(ctx, query, id, fact.ID, at)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func scanFacts(rows pgxRows) ([]domain.Fact, error) {
+	var out []domain.Fact
+	for rows.Next() {
+		var f domain.Fact
+		if err := rows.Scan(
+			&f.ID,
+			&f.CloneProfileID,
+			&f.Subject,
+			&f.Predicate,
+			&f.Object,
+			&f.SubjectKey,
+			&f.PredicateKey,
+			&f.SourceMessageID,
+			&f.SourceSessionID,
+			&f.Confidence,
+			&f.ContradictedBy,
+			&f.CreatedAt,
+			&f.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
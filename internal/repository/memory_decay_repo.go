@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryDecayRepository es la vista de narrative_memories que usa el job de olvido.
+// La implementa PgMemoryRepository.
+type MemoryDecayRepository interface {
+	ListDecayCandidates(ctx context.Context, afterID uuid.UUID, limit int) ([]DecayCandidate, error)
+	ApplyDecay(ctx context.Context, update DecayUpdate) (bool, error)
+}
+
+// DecayCandidate es una memoria activa y no nuclear con su estado de decaimiento.
+// BaseImportance/BaseWeight/AnchorAt son nil mientras la memoria no decayo nunca.
+type DecayCandidate struct {
+	ID                 uuid.UUID
+	CloneProfileID     uuid.UUID
+	Importance         int
+	EmotionalWeight    int
+	EmotionalIntensity int
+	EmotionCategory    string
+	BaseImportance     *int
+	BaseWeight         *int
+	AnchorAt           *time.Time
+	RecallCount        int
+	LastRecalledAt     *time.Time
+	CreatedAt          time.Time
+}
+
+// DecayUpdate fija los pesos decaidos y el punto de partida usado para calcularlos.
+// ArchivedAt no nil archiva la memoria. PrevImportance/PrevAnchorAt son los valores leidos
+// al calcular el decaimiento: si la fila cambio desde entonces, la actualizacion no se aplica.
+type DecayUpdate struct {
+	ID              uuid.UUID
+	Importance      int
+	EmotionalWeight int
+	BaseImportance  int
+	BaseWeight      int
+	AnchorAt        time.Time
+	ArchivedAt      *time.Time
+
+	PrevImportance int
+	PrevAnchorAt   *time.Time
+}
+
+// ListDecayCandidates pagina por id las memorias no archivadas ni nucleares.
+func (r *PgMemoryRepository) ListDecayCandidates(ctx context.Context, afterID uuid.UUID, limit int) ([]DecayCandidate, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+	query := `
+		SELECT id, clone_profile_id, importance, emotional_weight, emotional_intensity, emotion_category,
+		       decay_base_importance, decay_base_weight, decay_anchor_at, recall_count, last_recalled_at, created_at
+		FROM narrative_memories
+		WHERE archived_at IS NULL
+		  AND NOT is_core
+		  AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DecayCandidate
+	for rows.Next() {
+		var (
+			c         DecayCandidate
+			category  *string
+			intensity *int
+		)
+		if err := rows.Scan(
+			&c.ID,
+			&c.CloneProfileID,
+			&c.Importance,
+			&c.EmotionalWeight,
+			&intensity,
+			&category,
+			&c.BaseImportance,
+			&c.BaseWeight,
+			&c.AnchorAt,
+			&c.RecallCount,
+			&c.LastRecalledAt,
+			&c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if intensity != nil {
+			c.EmotionalIntensity = *intensity
+		}
+		if category != nil {
+			c.EmotionCategory = *category
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ApplyDecay persiste el resultado de una pasada de olvido. No toca updated_at: decaer no es una edicion.
+// Es optimista: devuelve false sin error si la memoria se edito, se fusiono o decayo en otra pasada
+// despues de leerla, para no pisar ese cambio.
+func (r *PgMemoryRepository) ApplyDecay(ctx context.Context, update DecayUpdate) (bool, error) {
+	query := `
+		UPDATE narrative_memories
+		SET importance = $2,
+		    emotional_weight = $3,
+		    decay_base_importance = $4,
+		    decay_base_weight = $5,
+		    decay_anchor_at = $6,
+		    archived_at = $7
+		WHERE id = $1
+		  AND importance = $8
+		  AND decay_anchor_at IS NOT DISTINCT FROM $9
+	`
+	tag, err := r.pool.Exec(ctx, query,
+		update.ID,
+		update.Importance,
+		update.EmotionalWeight,
+		update.BaseImportance,
+		update.BaseWeight,
+		update.AnchorAt,
+		update.ArchivedAt,
+		update.PrevImportance,
+		update.PrevAnchorAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
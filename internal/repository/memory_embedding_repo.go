@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgvector "github.com/pgvector/pgvector-go"
+)
+
+// ErrReembedPending indica que quedan memorias sin el embedding nuevo al intentar el cambio de columna.
+var ErrReembedPending = errors.New("memories pending re-embedding")
+
+// MemoryEmbeddingRepository es la vista de narrative_memories que usan la validacion de arranque
+// y el comando de re-embedding. La implementa PgMemoryRepository.
+//
+// El re-embedding escribe los vectores nuevos en una columna auxiliar (embedding_next, con la
+// dimension nueva) mientras la API sigue buscando sobre embedding; FinishReembed reemplaza una
+// columna por la otra en una sola transaccion.
+type MemoryEmbeddingRepository interface {
+	EmbeddingDimension(ctx context.Context) (int, error)
+	CountStaleEmbeddings(ctx context.Context, model string) (int, error)
+	PrepareReembed(ctx context.Context, dimension int) error
+	ListPendingReembed(ctx context.Context, model string, afterID uuid.UUID, limit int) ([]ReembedCandidate, error)
+	SetNextEmbedding(ctx context.Context, id uuid.UUID, content string, embedding pgvector.Vector, model string) error
+	FinishReembed(ctx context.Context, model string) error
+}
+
+// ReembedCandidate es una memoria cuyo embedding todavia no se regenero con el modelo nuevo.
+type ReembedCandidate struct {
+	ID      uuid.UUID
+	Content string
+}
+
+// EmbeddingDimension devuelve la dimension declarada de narrative_memories.embedding (VECTOR(n)).
+func (r *PgMemoryRepository) EmbeddingDimension(ctx context.Context) (int, error) {
+	return columnDimension(ctx, r.pool, "embedding")
+}
+
+// CountStaleEmbeddings cuenta las memorias con embedding generado por otro modelo (o desconocido).
+func (r *PgMemoryRepository) CountStaleEmbeddings(ctx context.Context, model string) (int, error) {
+	const query = `
+		SELECT COUNT(*)
+		FROM narrative_memories
+		WHERE embedding IS NOT NULL
+		  AND embedding_model <> $1
+	`
+	var n int
+	err := r.pool.QueryRow(ctx, query, model).Scan(&n)
+	return n, err
+}
+
+// PrepareReembed crea las columnas auxiliares para la dimension pedida. Si ya existen con la misma
+// dimension se conservan, asi un re-embedding interrumpido retoma donde quedo.
+func (r *PgMemoryRepository) PrepareReembed(ctx context.Context, dimension int) error {
+	if dimension <= 0 {
+		return fmt.Errorf("invalid embedding dimension %d", dimension)
+	}
+	current, err := columnDimension(ctx, r.pool, "embedding_next")
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if err == nil && current == dimension {
+		return nil
+	}
+	_, err = r.pool.Exec(ctx, fmt.Sprintf(`
+		ALTER TABLE narrative_memories
+		    DROP COLUMN IF EXISTS embedding_next,
+		    DROP COLUMN IF EXISTS embedding_next_model,
+		    ADD COLUMN embedding_next VECTOR(%d),
+		    ADD COLUMN embedding_next_model VARCHAR(100)
+	`, dimension))
+	return err
+}
+
+// ListPendingReembed pagina por id las memorias que todavia no tienen embedding_next del modelo pedido.
+// Incluye las archivadas: si vuelven a activarse tienen que ser comparables con el resto.
+func (r *PgMemoryRepository) ListPendingReembed(ctx context.Context, model string, afterID uuid.UUID, limit int) ([]ReembedCandidate, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	const query = `
+		SELECT id, content
+		FROM narrative_memories
+		WHERE embedding_next_model IS DISTINCT FROM $1
+		  AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+	rows, err := r.pool.Query(ctx, query, model, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ReembedCandidate
+	for rows.Next() {
+		var c ReembedCandidate
+		if err := rows.Scan(&c.ID, &c.Content); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SetNextEmbedding guarda el embedding nuevo sin tocar el que usa la API. content es el texto que se
+// embebio: si la memoria se edito mientras tanto devuelve pgx.ErrNoRows y la memoria sigue pendiente.
+func (r *PgMemoryRepository) SetNextEmbedding(ctx context.Context, id uuid.UUID, content string, embedding pgvector.Vector, model string) error {
+	const query = `
+		UPDATE narrative_memories
+		SET embedding_next = $3,
+		    embedding_next_model = $4
+		WHERE id = $1
+		  AND content = $2
+	`
+	tag, err := r.pool.Exec(ctx, query, id, content, embedding, model)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// FinishReembed reemplaza embedding por embedding_next y reconstruye el indice vectorial. Bloquea la
+// tabla y, si entre tanto se crearon memorias con el modelo viejo, devuelve ErrReembedPending sin
+// cambiar nada para que el llamador las procese y reintente.
+func (r *PgMemoryRepository) FinishReembed(ctx context.Context, model string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `LOCK TABLE narrative_memories IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+	var pending int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM narrative_memories WHERE embedding_next_model IS DISTINCT FROM $1`, model).Scan(&pending); err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d", ErrReembedPending, pending)
+	}
+
+	// Al borrar la columna se borra tambien idx_narrative_memories_embedding.
+	statements := []string{
+		`ALTER TABLE narrative_memories DROP COLUMN embedding`,
+		`ALTER TABLE narrative_memories RENAME COLUMN embedding_next TO embedding`,
+		`UPDATE narrative_memories SET embedding_model = embedding_next_model`,
+		`ALTER TABLE narrative_memories DROP COLUMN embedding_next_model`,
+		`CREATE INDEX idx_narrative_memories_embedding ON narrative_memories USING ivfflat (embedding vector_cosine_ops)`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// reembedInProgress indica si existen las columnas auxiliares de un re-embedding (PrepareReembed
+// las crea y FinishReembed las reemplaza).
+func (r *PgMemoryRepository) reembedInProgress(ctx context.Context) (bool, error) {
+	_, err := columnDimension(ctx, r.pool, "embedding_next")
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// columnDimension lee la dimension de una columna vector de narrative_memories (pgvector la guarda
+// en atttypmod). Devuelve pgx.ErrNoRows si la columna no existe.
+func columnDimension(ctx context.Context, q interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}, column string) (int, error) {
+	const query = `
+		SELECT atttypmod
+		FROM pg_attribute
+		WHERE attrelid = 'narrative_memories'::regclass
+		  AND attname = $1
+		  AND NOT attisdropped
+	`
+	var dim int
+	if err := q.QueryRow(ctx, query, column).Scan(&dim); err != nil {
+		return 0, err
+	}
+	return dim, nil
+}
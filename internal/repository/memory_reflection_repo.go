@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/domain"
+)
+
+// MemoryReflectionRepository es la vista de narrative_memories que usa el job de reflexion.
+// La implementa PgMemoryRepository.
+type MemoryReflectionRepository interface {
+	ListReflectionGroups(ctx context.Context, since time.Time, minMemories int) ([]ReflectionGroup, error)
+	ListPendingReflection(ctx context.Context, profileID, characterID uuid.UUID, since time.Time, limit int) ([]domain.NarrativeMemory, error)
+	MarkReflected(ctx context.Context, ids []uuid.UUID, at time.Time) error
+}
+
+// ReflectionGroup es un personaje de un clon con suficientes memorias recientes sin reflexionar.
+type ReflectionGroup struct {
+	CloneProfileID uuid.UUID
+	CharacterID    uuid.UUID
+	Pending        int
+}
+
+// ListReflectionGroups agrupa por clon y personaje las memorias activas creadas desde since que
+// todavia no pasaron por el job, devolviendo los grupos con al menos minMemories.
+func (r *PgMemoryRepository) ListReflectionGroups(ctx context.Context, since time.Time, minMemories int) ([]ReflectionGroup, error) {
+	if minMemories <= 0 {
+		minMemories = 1
+	}
+	query := `
+		SELECT clone_profile_id, related_character_id, COUNT(*)
+		FROM narrative_memories
+		WHERE reflected_at IS NULL
+		  AND archived_at IS NULL
+		  AND related_character_id IS NOT NULL
+		  AND memory_kind <> 'reflection'
+		  AND created_at >= $1
+		GROUP BY clone_profile_id, related_character_id
+		HAVING COUNT(*) >= $2
+		ORDER BY clone_profile_id, related_character_id
+	`
+	rows, err := r.pool.Query(ctx, query, since, minMemories)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ReflectionGroup
+	for rows.Next() {
+		var g ReflectionGroup
+		if err := rows.Scan(&g.CloneProfileID, &g.CharacterID, &g.Pending); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+// ListPendingReflection devuelve las memorias sin reflexionar del personaje, las mas importantes primero.
+func (r *PgMemoryRepository) ListPendingReflection(ctx context.Context, profileID, characterID uuid.UUID, since time.Time, limit int) ([]domain.NarrativeMemory, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `
+		SELECT ` + memoryColumns + `
+		FROM narrative_memories
+		WHERE clone_profile_id = $1
+		  AND related_character_id = $2
+		  AND reflected_at IS NULL
+		  AND archived_at IS NULL
+		  AND memory_kind <> 'reflection'
+		  AND created_at >= $3
+		ORDER BY importance DESC, happened_at DESC
+		LIMIT $4
+	`
+	rows, err := r.pool.Query(ctx, query, profileID, characterID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMemories(rows)
+}
+
+// MarkReflected saca las memorias de la cola del job. No toca updated_at: reflexionar no es una edicion.
+func (r *PgMemoryRepository) MarkReflected(ctx context.Context, ids []uuid.UUID, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	const query = `
+		UPDATE narrative_memories
+		SET reflected_at = $2
+		WHERE id = ANY($1)
+	`
+	_, err := r.pool.Exec(ctx, query, ids, at)
+	return err
+}
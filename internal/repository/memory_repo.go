@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	pgvector "github.com/pgvector/pgvector-go"
 
@@ -15,12 +18,33 @@ import (
 type MemoryRepository interface {
 	Create(ctx context.Context, memory domain.NarrativeMemory) error
 	Search(ctx context.Context, profileID uuid.UUID, queryEmbedding pgvector.Vector, k int, emotionalWeightFactor float64) ([]ScoredMemory, error)
-	ListByCharacter(ctx context.Context, characterID uuid.UUID) ([]domain.NarrativeMemory, error)
+	ListByCharacter(ctx context.Context, characterID uuid.UUID, limit int) ([]domain.NarrativeMemory, error)
 	GetRecentHighImpactByProfile(ctx context.Context, profileID uuid.UUID, limit int, minImportance int, minEmotionalIntensity int) ([]domain.NarrativeMemory, error)
+	ListBySourceSession(ctx context.Context, sessionID string) ([]domain.NarrativeMemory, error)
+	List(ctx context.Context, profileID uuid.UUID, filter MemoryFilter) ([]domain.NarrativeMemory, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.NarrativeMemory, error)
+	Update(ctx context.Context, memory domain.NarrativeMemory) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	MarkRecalled(ctx context.Context, ids []uuid.UUID, at time.Time) error
+	FindNearest(ctx context.Context, profileID uuid.UUID, relatedCharacterID *uuid.UUID, embedding pgvector.Vector) (*ScoredMemory, error)
+	MergeOccurrence(ctx context.Context, memory domain.NarrativeMemory) error
+}
+
+// MemoryFilter acota el listado de memorias de un clon. Los campos en cero no filtran.
+type MemoryFilter struct {
+	EmotionCategory string
+	MinImportance   int
+	CharacterID     *uuid.UUID
+	From            *time.Time // happened_at >= From
+	To              *time.Time // happened_at < To
+	IncludeArchived bool
+	Limit           int
+	Offset          int
 }
 
 type PgMemoryRepository struct {
-	pool *pgxpool.Pool
+	pool           *pgxpool.Pool
+	embeddingModel string
 }
 
 type ScoredMemory struct {
@@ -33,6 +57,19 @@ func NewPgMemoryRepository(pool *pgxpool.Pool) *PgMemoryRepository {
 	return &PgMemoryRepository{pool: pool}
 }
 
+// SetEmbeddingModel registra el modelo de embeddings configurado; se guarda en las memorias
+// que no traen uno propio.
+func (r *PgMemoryRepository) SetEmbeddingModel(model string) { r.embeddingModel = model }
+
+func (r *PgMemoryRepository) embeddingModelFor(memory domain.NarrativeMemory) string {
+	if model := strings.TrimSpace(memory.EmbeddingModel); model != "" {
+		return model
+	}
+	return r.embeddingModel
+}
+
+const memoryColumns = `id, clone_profile_id, related_character_id, content, embedding, embedding_model, importance, emotional_weight, emotional_intensity, emotion_category, sentiment_label, memory_kind, source_session_id, source_memory_ids, is_core, archived_at, recall_count, last_recalled_at, occurrence_count, happened_at, created_at, updated_at`
+
 func (r *PgMemoryRepository) Create(ctx context.Context, memory domain.NarrativeMemory) error {
 	intensity := memory.EmotionalIntensity
 	if intensity <= 0 {
@@ -42,16 +79,32 @@ func (r *PgMemoryRepository) Create(ctx context.Context, memory domain.Narrative
 	if category == "" {
 		category = "NEUTRAL"
 	}
-	const query = `
+	kind := strings.TrimSpace(memory.Kind)
+	if kind == "" {
+		kind = domain.MemoryKindEpisodic
+	}
+	occurrences := memory.OccurrenceCount
+	if occurrences <= 0 {
+		occurrences = 1
+	}
+	query := `
 		INSERT INTO narrative_memories (
-			id, clone_profile_id, related_character_id, content, embedding, importance, emotional_weight, emotional_intensity, emotion_category, sentiment_label, happened_at, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			` + memoryColumns + `
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
 	`
 
 	var related interface{}
 	if memory.RelatedCharacterID != nil {
 		related = *memory.RelatedCharacterID
 	}
+	var source interface{}
+	if memory.SourceSessionID != nil {
+		source = *memory.SourceSessionID
+	}
+	sourceMemories := make([]string, 0, len(memory.SourceMemoryIDs))
+	for _, id := range memory.SourceMemoryIDs {
+		sourceMemories = append(sourceMemories, id.String())
+	}
 
 	_, err := r.pool.Exec(ctx, query,
 		memory.ID,
@@ -59,11 +112,20 @@ func (r *PgMemoryRepository) Create(ctx context.Context, memory domain.Narrative
 		related,
 		memory.Content,
 		memory.Embedding,
+		r.embeddingModelFor(memory),
 		memory.Importance,
 		memory.EmotionalWeight,
 		intensity,
 		category,
 		memory.SentimentLabel,
+		kind,
+		source,
+		sourceMemories,
+		memory.IsCore,
+		memory.ArchivedAt,
+		memory.RecallCount,
+		memory.LastRecalledAt,
+		occurrences,
 		memory.HappenedAt,
 		memory.CreatedAt,
 		memory.UpdatedAt,
@@ -75,29 +137,20 @@ func (r *PgMemoryRepository) Search(ctx context.Context, profileID uuid.UUID, qu
 	if k <= 0 {
 		k = 5
 	}
-	const query = `
-		SELECT 
-			id,
-			clone_profile_id,
-			related_character_id,
-			content,
-			embedding,
-			importance,
-			emotional_weight,
-			emotional_intensity,
-			emotion_category,
-			sentiment_label,
-			happened_at,
-			created_at,
-			updated_at,
+	query := `
+		SELECT
+			` + memoryColumns + `,
 			(1 - (embedding <=> $2)) AS similarity,
 			(
 				(1 - (embedding <=> $2)) -- similitud vectorial
 				+ (COALESCE(emotional_intensity, 10) * COALESCE(emotional_weight, 5) * $4) -- refuerzo emocional ponderado
-				- (EXTRACT(EPOCH FROM (NOW() - created_at)) / 86400.0 * 0.01) -- olvido por antiguedad (dias)
+				+ (LN(1 + recall_count) * 0.02) -- refuerzo por evocaciones previas
+				+ (CASE WHEN memory_kind = 'reflection' THEN 0.05 ELSE 0 END) -- las reflexiones ganan a los episodios crudos
+				- (EXTRACT(EPOCH FROM (NOW() - COALESCE(last_recalled_at, created_at))) / 86400.0 * 0.01) -- olvido desde la ultima vez que se uso (dias)
 			) AS score
 		FROM narrative_memories
 		WHERE clone_profile_id = $1
+		  AND archived_at IS NULL
 		ORDER BY score DESC
 		LIMIT $3
 	`
@@ -110,14 +163,20 @@ func (r *PgMemoryRepository) Search(ctx context.Context, profileID uuid.UUID, qu
 	return scanScoredMemories(rows)
 }
 
-func (r *PgMemoryRepository) ListByCharacter(ctx context.Context, characterID uuid.UUID) ([]domain.NarrativeMemory, error) {
-	const query = `
-		SELECT id, clone_profile_id, related_character_id, content, embedding, importance, emotional_weight, emotional_intensity, emotion_category, sentiment_label, happened_at, created_at, updated_at
+// ListByCharacter devuelve las memorias mas recientes ligadas al personaje, como mucho limit.
+func (r *PgMemoryRepository) ListByCharacter(ctx context.Context, characterID uuid.UUID, limit int) ([]domain.NarrativeMemory, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	query := `
+		SELECT ` + memoryColumns + `
 		FROM narrative_memories
 		WHERE related_character_id = $1
+		  AND archived_at IS NULL
 		ORDER BY happened_at DESC
+		LIMIT $2
 	`
-	rows, err := r.pool.Query(ctx, query, characterID)
+	rows, err := r.pool.Query(ctx, query, characterID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -130,10 +189,11 @@ func (r *PgMemoryRepository) GetRecentHighImpactByProfile(ctx context.Context, p
 	if limit <= 0 {
 		limit = 3
 	}
-	const query = `
-		SELECT id, clone_profile_id, related_character_id, content, embedding, importance, emotional_weight, emotional_intensity, emotion_category, sentiment_label, happened_at, created_at, updated_at
+	query := `
+		SELECT ` + memoryColumns + `
 		FROM narrative_memories
 		WHERE clone_profile_id = $1
+		  AND archived_at IS NULL
 		  AND (importance >= $2 OR emotional_intensity >= $3)
 		ORDER BY COALESCE(happened_at, created_at) DESC
 		LIMIT $4
@@ -148,22 +208,282 @@ func (r *PgMemoryRepository) GetRecentHighImpactByProfile(ctx context.Context, p
 	return scanMemories(rows)
 }
 
+// ListBySourceSession devuelve las memorias consolidadas a partir de una sesion, en orden de creacion.
+func (r *PgMemoryRepository) ListBySourceSession(ctx context.Context, sessionID string) ([]domain.NarrativeMemory, error) {
+	query := `
+		SELECT ` + memoryColumns + `
+		FROM narrative_memories
+		WHERE source_session_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := r.pool.Query(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMemories(rows)
+}
+
+// List devuelve las memorias del clon que cumplen el filtro, de la mas reciente a la mas antigua.
+func (r *PgMemoryRepository) List(ctx context.Context, profileID uuid.UUID, filter MemoryFilter) ([]domain.NarrativeMemory, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	conds := []string{"clone_profile_id = $1"}
+	args := []interface{}{profileID}
+	if !filter.IncludeArchived {
+		conds = append(conds, "archived_at IS NULL")
+	}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if category := strings.TrimSpace(filter.EmotionCategory); category != "" {
+		add("UPPER(emotion_category) = UPPER($%d)", category)
+	}
+	if filter.MinImportance > 0 {
+		add("importance >= $%d", filter.MinImportance)
+	}
+	if filter.CharacterID != nil {
+		add("related_character_id = $%d", *filter.CharacterID)
+	}
+	if filter.From != nil {
+		add("happened_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("happened_at < $%d", *filter.To)
+	}
+	args = append(args, limit, filter.Offset)
+
+	query := `
+		SELECT ` + memoryColumns + `
+		FROM narrative_memories
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY happened_at DESC, created_at DESC
+		LIMIT $` + fmt.Sprint(len(args)-1) + ` OFFSET $` + fmt.Sprint(len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMemories(rows)
+}
+
+func (r *PgMemoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.NarrativeMemory, error) {
+	query := `
+		SELECT ` + memoryColumns + `
+		FROM narrative_memories
+		WHERE id = $1
+	`
+	rows, err := r.pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(memories) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &memories[0], nil
+}
+
+// Update reescribe los campos editables de una memoria (contenido, embedding, pesos, emocion, personaje,
+// fecha y marca de nuclear). Reinicia el decaimiento: los pesos editados pasan a ser el nuevo punto de partida.
+// Si hay un re-embedding en curso y el contenido cambia, descarta el embedding_next ya calculado para que
+// FinishReembed no instale el vector del texto viejo.
+func (r *PgMemoryRepository) Update(ctx context.Context, memory domain.NarrativeMemory) error {
+	reembedding, err := r.reembedInProgress(ctx)
+	if err != nil {
+		return err
+	}
+	// En el SET, content todavia es el valor anterior a este UPDATE.
+	var resetNext string
+	if reembedding {
+		resetNext = `,
+		    embedding_next = CASE WHEN content = $3 THEN embedding_next END,
+		    embedding_next_model = CASE WHEN content = $3 THEN embedding_next_model END`
+	}
+	query := `
+		UPDATE narrative_memories
+		SET related_character_id = $2,
+		    content = $3,
+		    embedding = $4,
+		    importance = $5,
+		    emotional_weight = $6,
+		    emotional_intensity = $7,
+		    emotion_category = $8,
+		    sentiment_label = $9,
+		    happened_at = $10,
+		    is_core = $11,
+		    updated_at = $12,
+		    embedding_model = $13,
+		    decay_base_importance = NULL,
+		    decay_base_weight = NULL,
+		    decay_anchor_at = $12` + resetNext + `
+		WHERE id = $1
+	`
+	var related interface{}
+	if memory.RelatedCharacterID != nil {
+		related = *memory.RelatedCharacterID
+	}
+	tag, err := r.pool.Exec(ctx, query,
+		memory.ID,
+		related,
+		memory.Content,
+		memory.Embedding,
+		memory.Importance,
+		memory.EmotionalWeight,
+		memory.EmotionalIntensity,
+		memory.EmotionCategory,
+		memory.SentimentLabel,
+		memory.HappenedAt,
+		memory.IsCore,
+		memory.UpdatedAt,
+		r.embeddingModelFor(memory),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgMemoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM narrative_memories WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// MarkRecalled registra en un solo UPDATE que las memorias entraron al contexto de un turno.
+func (r *PgMemoryRepository) MarkRecalled(ctx context.Context, ids []uuid.UUID, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := `
+		UPDATE narrative_memories
+		SET recall_count = recall_count + 1,
+		    last_recalled_at = $2
+		WHERE id = ANY($1)
+	`
+	_, err := r.pool.Exec(ctx, query, ids, at)
+	return err
+}
+
+// FindNearest devuelve la memoria activa mas parecida (similitud coseno pura, sin refuerzos) del clon
+// ligada al mismo personaje, o nil si no hay ninguna. Score queda igual a Similarity.
+// Las reflexiones no cuentan: un mensaje nunca se fusiona con una conclusion sintetizada.
+func (r *PgMemoryRepository) FindNearest(ctx context.Context, profileID uuid.UUID, relatedCharacterID *uuid.UUID, embedding pgvector.Vector) (*ScoredMemory, error) {
+	var related interface{}
+	if relatedCharacterID != nil {
+		related = *relatedCharacterID
+	}
+	query := `
+		SELECT
+			` + memoryColumns + `,
+			(1 - (embedding <=> $2)) AS similarity,
+			(1 - (embedding <=> $2)) AS score
+		FROM narrative_memories
+		WHERE clone_profile_id = $1
+		  AND archived_at IS NULL
+		  AND memory_kind <> 'reflection'
+		  AND related_character_id IS NOT DISTINCT FROM $3
+		ORDER BY embedding <=> $2
+		LIMIT 1
+	`
+	rows, err := r.pool.Query(ctx, query, profileID, embedding, related)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scored, err := scanScoredMemories(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(scored) == 0 {
+		return nil, nil
+	}
+	return &scored[0], nil
+}
+
+// MergeOccurrence suma una repeticion a una memoria existente con los pesos ya reforzados.
+// Como una edicion, reinicia el decaimiento desde los pesos nuevos.
+func (r *PgMemoryRepository) MergeOccurrence(ctx context.Context, memory domain.NarrativeMemory) error {
+	query := `
+		UPDATE narrative_memories
+		SET importance = $2,
+		    emotional_weight = $3,
+		    emotional_intensity = $4,
+		    emotion_category = $5,
+		    sentiment_label = $6,
+		    happened_at = $7,
+		    updated_at = $8,
+		    occurrence_count = occurrence_count + 1,
+		    decay_base_importance = NULL,
+		    decay_base_weight = NULL,
+		    decay_anchor_at = $8
+		WHERE id = $1
+	`
+	tag, err := r.pool.Exec(ctx, query,
+		memory.ID,
+		memory.Importance,
+		memory.EmotionalWeight,
+		memory.EmotionalIntensity,
+		memory.EmotionCategory,
+		memory.SentimentLabel,
+		memory.HappenedAt,
+		memory.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 func scanMemories(rows pgxRows) ([]domain.NarrativeMemory, error) {
 	var memories []domain.NarrativeMemory
 	for rows.Next() {
 		var m domain.NarrativeMemory
 		var related sql.NullString
+		var sourceMemories []string
 		if err := rows.Scan(
 			&m.ID,
 			&m.CloneProfileID,
 			&related,
 			&m.Content,
 			&m.Embedding,
+			&m.EmbeddingModel,
 			&m.Importance,
 			&m.EmotionalWeight,
 			&m.EmotionalIntensity,
 			&m.EmotionCategory,
 			&m.SentimentLabel,
+			&m.Kind,
+			&m.SourceSessionID,
+			&sourceMemories,
+			&m.IsCore,
+			&m.ArchivedAt,
+			&m.RecallCount,
+			&m.LastRecalledAt,
+			&m.OccurrenceCount,
 			&m.HappenedAt,
 			&m.CreatedAt,
 			&m.UpdatedAt,
@@ -176,6 +496,7 @@ func scanMemories(rows pgxRows) ([]domain.NarrativeMemory, error) {
 				m.RelatedCharacterID = &id
 			}
 		}
+		m.SourceMemoryIDs = parseMemoryIDs(sourceMemories)
 		memories = append(memories, m)
 	}
 	if err := rows.Err(); err != nil {
@@ -189,6 +510,7 @@ func scanScoredMemories(rows pgxRows) ([]ScoredMemory, error) {
 	for rows.Next() {
 		var m domain.NarrativeMemory
 		var related sql.NullString
+		var sourceMemories []string
 		var similarity, score float64
 		if err := rows.Scan(
 			&m.ID,
@@ -196,11 +518,20 @@ func scanScoredMemories(rows pgxRows) ([]ScoredMemory, error) {
 			&related,
 			&m.Content,
 			&m.Embedding,
+			&m.EmbeddingModel,
 			&m.Importance,
 			&m.EmotionalWeight,
 			&m.EmotionalIntensity,
 			&m.EmotionCategory,
 			&m.SentimentLabel,
+			&m.Kind,
+			&m.SourceSessionID,
+			&sourceMemories,
+			&m.IsCore,
+			&m.ArchivedAt,
+			&m.RecallCount,
+			&m.LastRecalledAt,
+			&m.OccurrenceCount,
 			&m.HappenedAt,
 			&m.CreatedAt,
 			&m.UpdatedAt,
@@ -215,6 +546,7 @@ func scanScoredMemories(rows pgxRows) ([]ScoredMemory, error) {
 				m.RelatedCharacterID = &id
 			}
 		}
+		m.SourceMemoryIDs = parseMemoryIDs(sourceMemories)
 		memories = append(memories, ScoredMemory{NarrativeMemory: m, Similarity: similarity, Score: score})
 	}
 	if err := rows.Err(); err != nil {
@@ -223,6 +555,20 @@ func scanScoredMemories(rows pgxRows) ([]ScoredMemory, error) {
 	return memories, nil
 }
 
+// parseMemoryIDs convierte los ids de uuid[] escaneados como texto, descartando los invalidos.
+func parseMemoryIDs(raw []string) []uuid.UUID {
+	if len(raw) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(raw))
+	for _, s := range raw {
+		if id, err := uuid.Parse(s); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // pgxRows is a minimal interface to allow scanning from pgx rows and simplify testing.
 type pgxRows interface {
 	Next() bool
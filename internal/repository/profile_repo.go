@@ -14,6 +14,8 @@ type ProfileRepository interface {
 	Create(ctx context.Context, profile domain.CloneProfile) error
 	GetByID(ctx context.Context, id string) (domain.CloneProfile, error)
 	GetByUserID(ctx context.Context, userID string) (domain.CloneProfile, error)
+	ListByUserID(ctx context.Context, userID string) ([]domain.CloneProfile, error)
+	UpdateTraitStability(ctx context.Context, id string, stability float64) error
 }
 
 type PgProfileRepository struct {
@@ -26,14 +28,19 @@ func NewPgProfileRepository(pool *pgxpool.Pool) *PgProfileRepository {
 
 func (r *PgProfileRepository) Create(ctx context.Context, profile domain.CloneProfile) error {
 	const query = `
-		INSERT INTO clone_profiles (id, user_id, name, bio, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO clone_profiles (id, user_id, name, bio, trait_stability, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
+	stability := profile.TraitStability
+	if stability < 0 || stability > 1 {
+		stability = domain.DefaultTraitStability
+	}
 	_, err := r.pool.Exec(ctx, query,
 		profile.ID,
 		profile.UserID,
 		profile.Name,
 		profile.Bio,
+		stability,
 		profile.CreatedAt,
 	)
 	return err
@@ -41,7 +48,7 @@ func (r *PgProfileRepository) Create(ctx context.Context, profile domain.ClonePr
 
 func (r *PgProfileRepository) GetByID(ctx context.Context, id string) (domain.CloneProfile, error) {
 	const query = `
-		SELECT id, user_id, name, bio, created_at
+		SELECT id, user_id, name, bio, trait_stability, created_at
 		FROM clone_profiles
 		WHERE id = $1
 	`
@@ -51,6 +58,7 @@ func (r *PgProfileRepository) GetByID(ctx context.Context, id string) (domain.Cl
 		&profile.UserID,
 		&profile.Name,
 		&profile.Bio,
+		&profile.TraitStability,
 		&profile.CreatedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -61,9 +69,11 @@ func (r *PgProfileRepository) GetByID(ctx context.Context, id string) (domain.Cl
 
 func (r *PgProfileRepository) GetByUserID(ctx context.Context, userID string) (domain.CloneProfile, error) {
 	const query = `
-		SELECT id, user_id, name, bio, created_at
+		SELECT id, user_id, name, bio, trait_stability, created_at
 		FROM clone_profiles
 		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
 	`
 	var profile domain.CloneProfile
 	err := r.pool.QueryRow(ctx, query, userID).Scan(
@@ -71,6 +81,7 @@ func (r *PgProfileRepository) GetByUserID(ctx context.Context, userID string) (d
 		&profile.UserID,
 		&profile.Name,
 		&profile.Bio,
+		&profile.TraitStability,
 		&profile.CreatedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -78,3 +89,44 @@ func (r *PgProfileRepository) GetByUserID(ctx context.Context, userID string) (d
 	}
 	return profile, err
 }
+
+// ListByUserID devuelve todos los clones del usuario, del mas reciente al mas antiguo.
+func (r *PgProfileRepository) ListByUserID(ctx context.Context, userID string) ([]domain.CloneProfile, error) {
+	const query = `
+		SELECT id, user_id, name, bio, trait_stability, created_at
+		FROM clone_profiles
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []domain.CloneProfile
+	for rows.Next() {
+		var p domain.CloneProfile
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Bio, &p.TraitStability, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func (r *PgProfileRepository) UpdateTraitStability(ctx context.Context, id string, stability float64) error {
+	const query = `
+		UPDATE clone_profiles
+		SET trait_stability = $1
+		WHERE id = $2
+	`
+	tag, err := r.pool.Exec(ctx, query, stability, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
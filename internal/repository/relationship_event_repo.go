@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"clone-llm/internal/domain"
+)
+
+type RelationshipEventRepository interface {
+	Create(ctx context.Context, event domain.RelationshipEvent) error
+	ListByCharacter(ctx context.Context, characterID uuid.UUID, limit int) ([]domain.RelationshipEvent, error)
+}
+
+type PgRelationshipEventRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgRelationshipEventRepository(pool *pgxpool.Pool) *PgRelationshipEventRepository {
+	return &PgRelationshipEventRepository{pool: pool}
+}
+
+func (r *PgRelationshipEventRepository) Create(ctx context.Context, event domain.RelationshipEvent) error {
+	const query = `
+		INSERT INTO relationship_events (
+			id, character_id, clone_profile_id, message_id, cause,
+			trust_before, intimacy_before, respect_before,
+			trust_after, intimacy_after, respect_after,
+			bond_status, dynamic, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	var messageID interface{}
+	if event.MessageID != "" {
+		messageID = event.MessageID
+	}
+
+	_, err := r.pool.Exec(ctx, query,
+		event.ID,
+		event.CharacterID,
+		event.CloneProfileID,
+		messageID,
+		event.Cause,
+		event.Before.Trust,
+		event.Before.Intimacy,
+		event.Before.Respect,
+		event.After.Trust,
+		event.After.Intimacy,
+		event.After.Respect,
+		event.BondStatus,
+		event.Dynamic,
+		event.CreatedAt,
+	)
+	return err
+}
+
+func (r *PgRelationshipEventRepository) ListByCharacter(ctx context.Context, characterID uuid.UUID, limit int) ([]domain.RelationshipEvent, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	const query = `
+		SELECT
+			id, character_id, clone_profile_id, message_id, cause,
+			trust_before, intimacy_before, respect_before,
+			trust_after, intimacy_after, respect_after,
+			bond_status, dynamic, created_at
+		FROM relationship_events
+		WHERE character_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, characterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.RelationshipEvent
+	for rows.Next() {
+		var e domain.RelationshipEvent
+		var messageID, bondStatus *string
+		if err := rows.Scan(
+			&e.ID,
+			&e.CharacterID,
+			&e.CloneProfileID,
+			&messageID,
+			&e.Cause,
+			&e.Before.Trust,
+			&e.Before.Intimacy,
+			&e.Before.Respect,
+			&e.After.Trust,
+			&e.After.Intimacy,
+			&e.After.Respect,
+			&bondStatus,
+			&e.Dynamic,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if messageID != nil {
+			e.MessageID = *messageID
+		}
+		if bondStatus != nil {
+			e.BondStatus = *bondStatus
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
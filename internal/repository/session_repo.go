@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -10,9 +11,20 @@ import (
 	"clone-llm/internal/domain"
 )
 
+// ErrSessionAlreadyClosed indica que Close no encontro una sesion abierta con ese id: otro cierre
+// (explicito, expiracion o barrido de inactivas) gano la carrera.
+var ErrSessionAlreadyClosed = errors.New("session already closed")
+
 type SessionRepository interface {
 	Create(ctx context.Context, session domain.Session) error
 	GetByID(ctx context.Context, id string) (domain.Session, error)
+	ListByUserID(ctx context.Context, userID string) ([]domain.Session, error)
+	UpdateRelationship(ctx context.Context, id string, rel domain.RelationshipVectors) error
+	Close(ctx context.Context, id string, closedAt time.Time) error
+	SetSpeakingAs(ctx context.Context, id, characterID string) error
+	ListIdle(ctx context.Context, idleSince time.Time, limit int) ([]domain.Session, error)
+	ClaimConsolidation(ctx context.Context, id string, at time.Time) (bool, error)
+	ReleaseConsolidation(ctx context.Context, id string) error
 }
 
 type PgSessionRepository struct {
@@ -25,12 +37,23 @@ func NewPgSessionRepository(pool *pgxpool.Pool) *PgSessionRepository {
 
 func (r *PgSessionRepository) Create(ctx context.Context, session domain.Session) error {
 	const query = `
-		INSERT INTO sessions (id, user_id, token, expires_at, trust_level, intimacy_level, respect_level, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO sessions (id, user_id, clone_profile_id, speaking_as_character_id, token, expires_at, trust_level, intimacy_level, respect_level, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
+	var profileID interface{}
+	if session.CloneProfileID != "" {
+		profileID = session.CloneProfileID
+	}
+	var speakingAs interface{}
+	if session.SpeakingAsCharacterID != "" {
+		speakingAs = session.SpeakingAsCharacterID
+	}
+
 	_, err := r.pool.Exec(ctx, query,
 		session.ID,
 		session.UserID,
+		profileID,
+		speakingAs,
 		session.Token,
 		session.ExpiresAt,
 		session.Relationship.Trust,
@@ -41,25 +64,178 @@ func (r *PgSessionRepository) Create(ctx context.Context, session domain.Session
 	return err
 }
 
+const sessionColumns = `id, user_id, clone_profile_id, speaking_as_character_id, token, expires_at, closed_at, trust_level, intimacy_level, respect_level, created_at`
+
 func (r *PgSessionRepository) GetByID(ctx context.Context, id string) (domain.Session, error) {
-	const query = `
-		SELECT id, user_id, token, expires_at, trust_level, intimacy_level, respect_level, created_at
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE id = $1
+	`
+	session, err := scanSession(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Session{}, err
+	}
+	return session, err
+}
+
+// ListByUserID devuelve las sesiones del usuario, de la mas reciente a la mas antigua.
+func (r *PgSessionRepository) ListByUserID(ctx context.Context, userID string) ([]domain.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
 		FROM sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []domain.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Close marca la sesion como cerrada. No pisa un cierre previo: si la sesion ya estaba cerrada
+// devuelve ErrSessionAlreadyClosed, asi solo quien la cerro dispara los hooks de fin.
+func (r *PgSessionRepository) Close(ctx context.Context, id string, closedAt time.Time) error {
+	const query = `
+		UPDATE sessions
+		SET closed_at = $1
+		WHERE id = $2 AND closed_at IS NULL
+	`
+	tag, err := r.pool.Exec(ctx, query, closedAt, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionAlreadyClosed
+	}
+	return nil
+}
+
+// SetSpeakingAs fija el personaje que encarna el usuario en la sesion. characterID vacio lo limpia.
+func (r *PgSessionRepository) SetSpeakingAs(ctx context.Context, id, characterID string) error {
+	const query = `
+		UPDATE sessions
+		SET speaking_as_character_id = $1
+		WHERE id = $2
+	`
+	var value interface{}
+	if characterID != "" {
+		value = characterID
+	}
+	tag, err := r.pool.Exec(ctx, query, value, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ClaimConsolidation marca la sesion como consolidada si nadie lo hizo antes. Devuelve false si otra
+// consolidacion ya la reclamo; el UPDATE condicional hace la reclamacion atomica.
+func (r *PgSessionRepository) ClaimConsolidation(ctx context.Context, id string, at time.Time) (bool, error) {
+	const query = `
+		UPDATE sessions
+		SET consolidated_at = $1
+		WHERE id = $2 AND consolidated_at IS NULL
+	`
+	tag, err := r.pool.Exec(ctx, query, at, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReleaseConsolidation libera la reclamacion tras una consolidacion fallida para que se pueda reintentar.
+func (r *PgSessionRepository) ReleaseConsolidation(ctx context.Context, id string) error {
+	const query = `
+		UPDATE sessions
+		SET consolidated_at = NULL
 		WHERE id = $1
 	`
-	var session domain.Session
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	_, err := r.pool.Exec(ctx, query, id)
+	return err
+}
+
+// ListIdle devuelve sesiones abiertas cuya ultima actividad (ultimo mensaje o creacion) es anterior a idleSince.
+func (r *PgSessionRepository) ListIdle(ctx context.Context, idleSince time.Time, limit int) ([]domain.Session, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions s
+		WHERE s.closed_at IS NULL
+		  AND COALESCE((SELECT MAX(m.created_at) FROM messages m WHERE m.session_id = s.id), s.created_at) < $1
+		ORDER BY s.created_at ASC
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, idleSince, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []domain.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func scanSession(row pgx.Row) (domain.Session, error) {
+	var (
+		session    domain.Session
+		profileID  *string
+		speakingAs *string
+	)
+	err := row.Scan(
 		&session.ID,
 		&session.UserID,
+		&profileID,
+		&speakingAs,
 		&session.Token,
 		&session.ExpiresAt,
+		&session.ClosedAt,
 		&session.Relationship.Trust,
 		&session.Relationship.Intimacy,
 		&session.Relationship.Respect,
 		&session.CreatedAt,
 	)
-	if errors.Is(err, pgx.ErrNoRows) {
+	if err != nil {
 		return domain.Session{}, err
 	}
-	return session, err
+	if profileID != nil {
+		session.CloneProfileID = *profileID
+	}
+	if speakingAs != nil {
+		session.SpeakingAsCharacterID = *speakingAs
+	}
+	return session, nil
+}
+
+func (r *PgSessionRepository) UpdateRelationship(ctx context.Context, id string, rel domain.RelationshipVectors) error {
+	const query = `
+		UPDATE sessions
+		SET trust_level = $1, intimacy_level = $2, respect_level = $3
+		WHERE id = $4
+	`
+	_, err := r.pool.Exec(ctx, query, rel.Trust, rel.Intimacy, rel.Respect, id)
+	return err
 }
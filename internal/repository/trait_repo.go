@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"clone-llm/internal/domain"
@@ -13,6 +14,10 @@ type TraitRepository interface {
 	Upsert(ctx context.Context, trait domain.Trait) error
 	FindByProfileID(ctx context.Context, profileID string) ([]domain.Trait, error)
 	FindByCategory(ctx context.Context, profileID, category string) ([]domain.Trait, error)
+	CreateObservation(ctx context.Context, obs domain.TraitObservation) error
+	// UpdateCategory relee los rasgos de la categoria y guarda los que devuelve update en una sola
+	// transaccion, serializada por perfil.
+	UpdateCategory(ctx context.Context, profileID, category string, update func(current []domain.Trait) ([]domain.Trait, error)) error
 }
 
 type PgTraitRepository struct {
@@ -24,13 +29,62 @@ func NewPgTraitRepository(pool *pgxpool.Pool) *PgTraitRepository {
 }
 
 func (r *PgTraitRepository) Upsert(ctx context.Context, trait domain.Trait) error {
+	return upsertTrait(ctx, r.pool, trait)
+}
+
+// UpdateCategory bloquea el perfil con SELECT ... FOR UPDATE, lee sus rasgos de la categoria y
+// persiste lo que devuelve update antes de soltar el bloqueo. Dos analisis concurrentes del mismo
+// clon se encadenan en lugar de pisarse la estimacion y observation_count; el bloqueo es sobre el
+// perfil para cubrir tambien los rasgos que todavia no existen.
+func (r *PgTraitRepository) UpdateCategory(ctx context.Context, profileID, category string, update func(current []domain.Trait) ([]domain.Trait, error)) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked string
+	if err := tx.QueryRow(ctx, `SELECT id FROM clone_profiles WHERE id = $1 FOR UPDATE`, profileID).Scan(&locked); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, profile_id, category, trait, value, confidence, observation_count, created_at, updated_at
+		FROM traits
+		WHERE profile_id = $1 AND category = $2
+		ORDER BY trait
+	`, profileID, category)
+	if err != nil {
+		return err
+	}
+	current, err := scanTraits(rows)
+	if err != nil {
+		return err
+	}
+
+	next, err := update(current)
+	if err != nil {
+		return err
+	}
+	for _, trait := range next {
+		if err := upsertTrait(ctx, tx, trait); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func upsertTrait(ctx context.Context, q interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}, trait domain.Trait) error {
 	const query = `
-		INSERT INTO traits (id, profile_id, category, trait, value, confidence, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO traits (id, profile_id, category, trait, value, confidence, observation_count, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (profile_id, category, trait)
 		DO UPDATE SET
 			value = EXCLUDED.value,
 			confidence = EXCLUDED.confidence,
+			observation_count = EXCLUDED.observation_count,
 			updated_at = EXCLUDED.updated_at
 	`
 
@@ -39,13 +93,19 @@ func (r *PgTraitRepository) Upsert(ctx context.Context, trait domain.Trait) erro
 		confidence = *trait.Confidence
 	}
 
-	_, err := r.pool.Exec(ctx, query,
+	count := trait.ObservationCount
+	if count <= 0 {
+		count = 1
+	}
+
+	_, err := q.Exec(ctx, query,
 		trait.ID,
 		trait.ProfileID,
 		trait.Category,
 		trait.Trait,
 		trait.Value,
 		confidence,
+		count,
 		trait.CreatedAt,
 		trait.UpdatedAt,
 	)
@@ -54,7 +114,7 @@ func (r *PgTraitRepository) Upsert(ctx context.Context, trait domain.Trait) erro
 
 func (r *PgTraitRepository) FindByProfileID(ctx context.Context, profileID string) ([]domain.Trait, error) {
 	const query = `
-		SELECT id, profile_id, category, trait, value, confidence, created_at, updated_at
+		SELECT id, profile_id, category, trait, value, confidence, observation_count, created_at, updated_at
 		FROM traits
 		WHERE profile_id = $1
 		ORDER BY category, trait
@@ -64,42 +124,12 @@ func (r *PgTraitRepository) FindByProfileID(ctx context.Context, profileID strin
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var traits []domain.Trait
-	for rows.Next() {
-		var t domain.Trait
-		var confidence sql.NullFloat64
-
-		if err := rows.Scan(
-			&t.ID,
-			&t.ProfileID,
-			&t.Category,
-			&t.Trait,
-			&t.Value,
-			&confidence,
-			&t.CreatedAt,
-			&t.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		if confidence.Valid {
-			val := confidence.Float64
-			t.Confidence = &val
-		}
-		traits = append(traits, t)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return traits, nil
+	return scanTraits(rows)
 }
 
 func (r *PgTraitRepository) FindByCategory(ctx context.Context, profileID, category string) ([]domain.Trait, error) {
 	const query = `
-		SELECT id, profile_id, category, trait, value, confidence, created_at, updated_at
+		SELECT id, profile_id, category, trait, value, confidence, observation_count, created_at, updated_at
 		FROM traits
 		WHERE profile_id = $1 AND category = $2
 		ORDER BY trait
@@ -109,6 +139,34 @@ func (r *PgTraitRepository) FindByCategory(ctx context.Context, profileID, categ
 	if err != nil {
 		return nil, err
 	}
+	return scanTraits(rows)
+}
+
+func (r *PgTraitRepository) CreateObservation(ctx context.Context, obs domain.TraitObservation) error {
+	const query = `
+		INSERT INTO trait_observations (id, profile_id, category, trait, value, confidence, estimate_after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	var confidence interface{}
+	if obs.Confidence != nil {
+		confidence = *obs.Confidence
+	}
+
+	_, err := r.pool.Exec(ctx, query,
+		obs.ID,
+		obs.ProfileID,
+		obs.Category,
+		obs.Trait,
+		obs.Value,
+		confidence,
+		obs.EstimateAfter,
+		obs.CreatedAt,
+	)
+	return err
+}
+
+func scanTraits(rows pgxRows) ([]domain.Trait, error) {
 	defer rows.Close()
 
 	var traits []domain.Trait
@@ -123,6 +181,7 @@ func (r *PgTraitRepository) FindByCategory(ctx context.Context, profileID, categ
 			&t.Trait,
 			&t.Value,
 			&confidence,
+			&t.ObservationCount,
 			&t.CreatedAt,
 			&t.UpdatedAt,
 		); err != nil {
@@ -21,7 +21,9 @@ type AnalysisService struct {
 	llmClient   llm.LLMClient
 	traitRepo   repository.TraitRepository
 	profileRepo repository.ProfileRepository
+	sessionRepo repository.SessionRepository
 	logger      *zap.Logger
+	factService *FactService
 }
 
 var allowedBigFiveTraits = map[string]struct{}{
@@ -46,74 +48,176 @@ func NewAnalysisService(
 	}
 }
 
+// SetFactService habilita el registro de los hechos que el analisis extrae de cada mensaje.
+func (s *AnalysisService) SetFactService(svc *FactService) { s.factService = svc }
+
+// SetSessionRepository permite atribuir cada mensaje al clon ligado a su sesion.
+// Sin el, rasgos y hechos van al clon mas reciente del usuario.
+func (s *AnalysisService) SetSessionRepository(repo repository.SessionRepository) {
+	s.sessionRepo = repo
+}
+
 // AnalyzeAndPersist guarda los rasgos inferidos y devuelve error si falla.
 func (s *AnalysisService) AnalyzeAndPersist(ctx context.Context, userID, text string) error {
+	return s.analyzeAndPersist(ctx, userID, "", "", text)
+}
+
+// AnalyzeMessageAndPersist hace lo mismo que AnalyzeAndPersist con un mensaje ya guardado,
+// para que los hechos extraidos queden ligados al mensaje de origen y al clon de su sesion.
+func (s *AnalysisService) AnalyzeMessageAndPersist(ctx context.Context, msg domain.Message) error {
+	return s.analyzeAndPersist(ctx, msg.UserID, msg.SessionID, msg.ID, msg.Content)
+}
+
+func (s *AnalysisService) analyzeAndPersist(ctx context.Context, userID, sessionID, messageID, text string) error {
 	if s.profileRepo == nil || s.traitRepo == nil || s.llmClient == nil {
 		return errors.New("analysis service not configured")
 	}
 
-	profile, err := s.profileRepo.GetByUserID(ctx, userID)
+	profile, err := s.resolveProfile(ctx, userID, sessionID)
 	if err != nil {
-		return fmt.Errorf("get profile for user %s: %w", userID, err)
+		return err
 	}
 
 	parsed, err := s.runAnalysis(ctx, text)
 	if err != nil {
 		return err
 	}
+	s.recordFacts(ctx, profile.ID, messageID, parsed.Facts)
 
+	// Leer, combinar y guardar en una sola transaccion: los analisis de dos mensajes en vuelo
+	// no pueden pisarse la estimacion ni observation_count.
+	estimator := NewTraitEstimator(profile.TraitStability)
 	now := time.Now().UTC()
-	for _, t := range parsed.Traits {
-		normalizedTrait := strings.ToLower(strings.TrimSpace(t.Trait))
-		if _, ok := allowedBigFiveTraits[normalizedTrait]; !ok {
-			if s.logger != nil {
-				s.logger.Warn("ignoring unsupported trait", zap.String("trait", t.Trait), zap.String("profile_id", profile.ID))
-			}
-			continue
+	var observations []domain.TraitObservation
+	err = s.traitRepo.UpdateCategory(ctx, profile.ID, domain.TraitCategoryBigFive, func(existing []domain.Trait) ([]domain.Trait, error) {
+		observations = observations[:0]
+		current := make(map[string]domain.Trait, len(existing))
+		for _, t := range existing {
+			current[strings.ToLower(strings.TrimSpace(t.Trait))] = t
 		}
 
-		value := t.Value
-		if value < 0 {
-			value = 0
-		}
-		if value > 100 {
-			value = 100
-		}
+		var updated []domain.Trait
+		for _, t := range parsed.Traits {
+			normalizedTrait := strings.ToLower(strings.TrimSpace(t.Trait))
+			if _, ok := allowedBigFiveTraits[normalizedTrait]; !ok {
+				if s.logger != nil {
+					s.logger.Warn("ignoring unsupported trait", zap.String("trait", t.Trait), zap.String("profile_id", profile.ID))
+				}
+				continue
+			}
+
+			value := clampTraitValue(t.Value)
 
-		confidence := t.Confidence
-		if confidence != nil {
-			c := *confidence
-			if c < 0 {
-				c = 0
+			confidence := t.Confidence
+			if confidence != nil {
+				c := *confidence
+				if c < 0 {
+					c = 0
+				}
+				if c > 1 {
+					c = 1
+				}
+				confidence = &c
 			}
-			if c > 1 {
-				c = 1
+
+			// La observacion no pisa el rasgo: se combina con la estimacion acumulada.
+			var prev *domain.Trait
+			if p, ok := current[normalizedTrait]; ok {
+				prev = &p
 			}
-			confidence = &c
-		}
+			estimated := estimator.Estimate(prev, value, confidence)
+
+			trait := domain.Trait{
+				ID:               uuid.NewString(),
+				ProfileID:        profile.ID,
+				Category:         domain.TraitCategoryBigFive,
+				Trait:            normalizedTrait,
+				Value:            estimated.Value,
+				Confidence:       estimated.Confidence,
+				ObservationCount: estimated.ObservationCount,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			}
+			if prev != nil {
+				trait.ID = prev.ID
+				trait.CreatedAt = prev.CreatedAt
+			}
+			current[normalizedTrait] = trait
+			updated = append(updated, trait)
 
-		trait := domain.Trait{
-			ID:         uuid.NewString(),
-			ProfileID:  profile.ID,
-			Category:   domain.TraitCategoryBigFive,
-			Trait:      normalizedTrait,
-			Value:      value,
-			Confidence: confidence,
-			CreatedAt:  now,
-			UpdatedAt:  now,
+			observations = append(observations, domain.TraitObservation{
+				ID:            uuid.NewString(),
+				ProfileID:     profile.ID,
+				Category:      domain.TraitCategoryBigFive,
+				Trait:         normalizedTrait,
+				Value:         value,
+				Confidence:    confidence,
+				EstimateAfter: trait.Value,
+				CreatedAt:     now,
+			})
 		}
+		return updated, nil
+	})
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("trait update failed", zap.Error(err), zap.String("profile_id", profile.ID))
+		}
+		return fmt.Errorf("trait update: %w", err)
+	}
 
-		if err := s.traitRepo.Upsert(ctx, trait); err != nil {
+	for _, observation := range observations {
+		if err := s.traitRepo.CreateObservation(ctx, observation); err != nil {
+			// El historial es auxiliar: la estimacion ya quedo guardada.
 			if s.logger != nil {
-				s.logger.Warn("trait upsert failed", zap.Error(err), zap.String("profile_id", profile.ID), zap.String("trait", trait.Trait))
+				s.logger.Warn("trait observation insert failed", zap.Error(err), zap.String("profile_id", profile.ID), zap.String("trait", observation.Trait))
 			}
-			return fmt.Errorf("trait upsert: %w", err)
 		}
 	}
 
 	return nil
 }
 
+// resolveProfile devuelve el clon ligado a la sesion del mensaje; sin sesion (o sin clon ligado)
+// usa el clon del usuario. Un clon de otro usuario nunca recibe el analisis.
+func (s *AnalysisService) resolveProfile(ctx context.Context, userID, sessionID string) (domain.CloneProfile, error) {
+	if s.sessionRepo != nil && strings.TrimSpace(sessionID) != "" {
+		session, err := s.sessionRepo.GetByID(ctx, sessionID)
+		if err != nil {
+			return domain.CloneProfile{}, fmt.Errorf("get session %s: %w", sessionID, err)
+		}
+		if session.CloneProfileID != "" {
+			profile, err := s.profileRepo.GetByID(ctx, session.CloneProfileID)
+			if err != nil {
+				return domain.CloneProfile{}, fmt.Errorf("get profile %s: %w", session.CloneProfileID, err)
+			}
+			if profile.UserID != "" && profile.UserID != userID {
+				return domain.CloneProfile{}, fmt.Errorf("profile %s does not belong to user %s", profile.ID, userID)
+			}
+			return profile, nil
+		}
+	}
+
+	profile, err := s.profileRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return domain.CloneProfile{}, fmt.Errorf("get profile for user %s: %w", userID, err)
+	}
+	return profile, nil
+}
+
+// recordFacts guarda los hechos del mensaje. Es auxiliar: un fallo no invalida los rasgos.
+func (s *AnalysisService) recordFacts(ctx context.Context, profileID, messageID string, facts []domain.ExtractedFact) {
+	if s.factService == nil || len(facts) == 0 {
+		return
+	}
+	profileUUID, err := uuid.Parse(profileID)
+	if err != nil {
+		return
+	}
+	if _, err := s.factService.RecordFacts(ctx, profileUUID, facts, FactSource{MessageID: messageID}); err != nil && s.logger != nil {
+		s.logger.Warn("record facts failed", zap.Error(err), zap.String("profile_id", profileID))
+	}
+}
+
 // AnalyzeEmotion devuelve la intensidad y categoria emocional sin persistir rasgos.
 // Aplica un umbral de ruido segun la resiliencia del perfil para evitar sobrerreaccionar a inputs triviales.
 func (s *AnalysisService) AnalyzeEmotion(ctx context.Context, profile *domain.CloneProfile, text string) (EmotionAnalysis, error) {
@@ -157,11 +261,14 @@ func (s *AnalysisService) runAnalysis(ctx context.Context, text string) (Analysi
 	systemPrompt := `Eres un psicologo experto observando una conversacion. Analiza el siguiente texto del usuario y:
 - Estima valores numericos (0-100) para los rasgos del modelo Big Five (Openness, Conscientiousness, Extraversion, Agreeableness, Neuroticism).
 - Extrae la carga emocional del mensaje.
+- Extrae los hechos concretos y duraderos que el mensaje afirma sobre el usuario o su entorno (nombres, mascotas, trabajo, lugares, gustos) como tripletas sujeto/predicado/objeto. Lista vacia si no hay.
+- Marca "single_valued": true solo si el predicado admite un unico valor a la vez (vive en, trabaja en, tiene edad, su pareja es); false si se acumula (tiene un perro llamado, le gusta).
 - Devuelve SOLO un JSON con este formato:
 {
   "traits": [{"trait": "openness", "value": 85, "confidence": 0.9}, ...],
   "emotional_intensity": 75,
-  "emotion_category": "IRA"
+  "emotion_category": "IRA",
+  "facts": [{"subject": "usuario", "predicate": "tiene un perro llamado", "object": "Toby", "confidence": 0.9, "single_valued": false}]
 }
 
 Guia de emotional_intensity (1-100):
@@ -190,9 +297,10 @@ Guia de emotional_intensity (1-100):
 
 // AnalysisResponse captura rasgos y carga emocional devueltos por el LLM analista.
 type AnalysisResponse struct {
-	Traits             []llmTraitItem `json:"traits"`
-	EmotionalIntensity int            `json:"emotional_intensity"`
-	EmotionCategory    string         `json:"emotion_category"`
+	Traits             []llmTraitItem         `json:"traits"`
+	EmotionalIntensity int                    `json:"emotional_intensity"`
+	EmotionCategory    string                 `json:"emotion_category"`
+	Facts              []domain.ExtractedFact `json:"facts"`
 }
 
 type llmTraitItem struct {
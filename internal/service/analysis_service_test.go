@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"clone-llm/internal/domain"
@@ -29,11 +31,22 @@ func (m *mockProfileRepo) GetByUserID(ctx context.Context, userID string) (domai
 	return m.profile, m.err
 }
 
+func (m *mockProfileRepo) ListByUserID(ctx context.Context, userID string) ([]domain.CloneProfile, error) {
+	return []domain.CloneProfile{m.profile}, m.err
+}
+
+func (m *mockProfileRepo) UpdateTraitStability(ctx context.Context, id string, stability float64) error {
+	return errors.New("not implemented")
+}
+
 type mockTraitRepo struct {
-	upsertCount int
-	lastTrait   domain.Trait
-	allTraits   []domain.Trait
-	err         error
+	mu           sync.Mutex
+	upsertCount  int
+	lastTrait    domain.Trait
+	allTraits    []domain.Trait
+	existing     []domain.Trait
+	observations []domain.TraitObservation
+	err          error
 }
 
 func (m *mockTraitRepo) Upsert(ctx context.Context, trait domain.Trait) error {
@@ -48,7 +61,40 @@ func (m *mockTraitRepo) FindByProfileID(ctx context.Context, profileID string) (
 }
 
 func (m *mockTraitRepo) FindByCategory(ctx context.Context, profileID, category string) ([]domain.Trait, error) {
-	return nil, errors.New("not implemented")
+	return m.existing, nil
+}
+
+func (m *mockTraitRepo) UpdateCategory(ctx context.Context, profileID, category string, update func([]domain.Trait) ([]domain.Trait, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current := append([]domain.Trait(nil), m.existing...)
+	updated, err := update(current)
+	if err != nil {
+		return err
+	}
+	for _, trait := range updated {
+		if err := m.Upsert(ctx, trait); err != nil {
+			return err
+		}
+		replaced := false
+		for i := range m.existing {
+			if m.existing[i].Trait == trait.Trait {
+				m.existing[i] = trait
+				replaced = true
+			}
+		}
+		if !replaced {
+			m.existing = append(m.existing, trait)
+		}
+	}
+	return nil
+}
+
+func (m *mockTraitRepo) CreateObservation(ctx context.Context, obs domain.TraitObservation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observations = append(m.observations, obs)
+	return nil
 }
 
 func TestAnalysisServiceHappyPath(t *testing.T) {
@@ -86,6 +132,45 @@ func TestAnalysisServiceHappyPath(t *testing.T) {
 	}
 }
 
+func TestAnalysisService_ConcurrentAnalysesDoNotLoseObservations(t *testing.T) {
+	llmClient := &llm.MockClient{
+		Response: `{"traits": [{"trait": "extraversion", "value": 80, "confidence": 0.9}]}`,
+	}
+	profileRepo := &mockProfileRepo{profile: domain.CloneProfile{ID: "profile-1"}}
+	traitRepo := &mockTraitRepo{existing: []domain.Trait{{
+		ID:               "trait-1",
+		ProfileID:        "profile-1",
+		Category:         domain.TraitCategoryBigFive,
+		Trait:            "extraversion",
+		Value:            50,
+		ObservationCount: 4,
+	}}}
+
+	svc := NewAnalysisService(llmClient, traitRepo, profileRepo, zap.NewNop())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := svc.AnalyzeAndPersist(context.Background(), "user-1", "hola mundo"); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(traitRepo.existing) != 1 {
+		t.Fatalf("expected a single extraversion trait, got %d", len(traitRepo.existing))
+	}
+	if got := traitRepo.existing[0].ObservationCount; got != 6 {
+		t.Fatalf("expected observation_count 6 after two analyses, got %d", got)
+	}
+	if len(traitRepo.observations) != 2 {
+		t.Fatalf("expected 2 observations, got %d", len(traitRepo.observations))
+	}
+}
+
 func TestAnalysisServiceInvalidJSON(t *testing.T) {
 	llmClient := &llm.MockClient{
 		Response: `Lo siento, no puedo procesar...`,
@@ -193,3 +278,35 @@ fin`,
 		t.Fatalf("expected upsert called once, got %d", traitRepo.upsertCount)
 	}
 }
+
+func TestAnalysisService_UsesTheSessionClone(t *testing.T) {
+	llmClient := &llm.MockClient{
+		Response: `{"traits": [{"trait": "openness", "value": 70}], "facts": [{"subject": "usuario", "predicate": "vive en", "object": "Rosario", "single_valued": true}]}`,
+	}
+	cloneID := uuid.NewString()
+	profileRepo := &mockCloneProfileRepo{profile: domain.CloneProfile{ID: cloneID, UserID: "user-1"}}
+	traitRepo := &mockTraitRepo{}
+	facts := &fakeFactRepo{}
+	svc := NewAnalysisService(llmClient, traitRepo, profileRepo, zap.NewNop())
+	svc.SetFactService(NewFactService(facts, nil))
+	svc.SetSessionRepository(&relFakeSessionRepo{session: domain.Session{ID: "s1", UserID: "user-1", CloneProfileID: cloneID}})
+
+	msg := domain.Message{ID: uuid.NewString(), UserID: "user-1", SessionID: "s1", Content: "me mude a Rosario"}
+	if err := svc.AnalyzeMessageAndPersist(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profileRepo.lastGetByID != cloneID || profileRepo.lastGetByUser != "" {
+		t.Fatalf("expected the session clone to be loaded by id, got byID=%q byUser=%q", profileRepo.lastGetByID, profileRepo.lastGetByUser)
+	}
+	if traitRepo.lastTrait.ProfileID != cloneID {
+		t.Fatalf("expected traits on the session clone, got %q", traitRepo.lastTrait.ProfileID)
+	}
+	if len(facts.facts) != 1 || facts.facts[0].CloneProfileID.String() != cloneID {
+		t.Fatalf("expected the fact to be recorded, got %+v", facts.facts)
+	}
+
+	profileRepo.profile.UserID = "user-2"
+	if err := svc.AnalyzeMessageAndPersist(context.Background(), msg); err == nil {
+		t.Fatalf("expected an error for a clone owned by another user")
+	}
+}
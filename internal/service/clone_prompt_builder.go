@@ -11,11 +11,22 @@ import (
 type ClonePromptBuilder struct{}
 
 // BuildClonePrompt builds the full prompt sent to the generator LLM.
-func (ClonePromptBuilder) BuildClonePrompt(
+func (b ClonePromptBuilder) BuildClonePrompt(
 	profile *domain.CloneProfile,
 	traits []domain.Trait,
 	contextText, narrativeText, userMessage string,
 	trivialInput bool,
+) string {
+	return b.BuildClonePromptWithFacts(profile, traits, contextText, narrativeText, "", userMessage, trivialInput)
+}
+
+// BuildClonePromptWithFacts is BuildClonePrompt plus a [HECHOS CONOCIDOS] section with the
+// known facts about the user (one "- ..." line each, as built by FactService.BuildFactsContext).
+func (ClonePromptBuilder) BuildClonePromptWithFacts(
+	profile *domain.CloneProfile,
+	traits []domain.Trait,
+	contextText, narrativeText, factsText, userMessage string,
+	trivialInput bool,
 ) string {
 	if profile == nil {
 		profile = &domain.CloneProfile{
@@ -30,6 +41,7 @@ func (ClonePromptBuilder) BuildClonePrompt(
 	userMessage = strings.TrimSpace(userMessage)
 	contextText = strings.TrimSpace(contextText)
 	narrativeTrim := strings.TrimSpace(narrativeText)
+	factsText = strings.TrimSpace(factsText)
 
 	// 1. Identidad base
 	sb.WriteString(fmt.Sprintf("Eres %s. ", strings.TrimSpace(profile.Name)))
@@ -81,6 +93,14 @@ func (ClonePromptBuilder) BuildClonePrompt(
 		sb.WriteString("\n\n")
 	}
 
+	// 2.1 Hechos conocidos (datos estables, separados de las memorias emocionales)
+	if factsText != "" {
+		sb.WriteString("=== [HECHOS CONOCIDOS] ===\n")
+		sb.WriteString(factsText)
+		sb.WriteString("\n")
+		sb.WriteString("REGLA: Son datos que ya sabes del usuario y su entorno. Usalos con naturalidad cuando vengan al caso, no los recites ni los contradigas. Si el usuario dice algo distinto, asume que cambio y reaccionalo como personaje.\n\n")
+	}
+
 	// 3. Rasgos de personalidad
 	sb.WriteString("=== RASGOS DE PERSONALIDAD (TU CONFIGURACION BASE) ===\n")
 	if len(traits) == 0 {
@@ -179,6 +199,7 @@ func (ClonePromptBuilder) BuildClonePrompt(
   "respect_delta": 0,
   "new_state": "opcional: describe cambio de estado"
 }
+Los deltas (trust_delta, intimacy_delta, respect_delta) van de -10 a 10 y reflejan como cambia tu vinculo con el usuario tras este mensaje; usa 0 si no cambia.
 `)
 
 	return sb.String()
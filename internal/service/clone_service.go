@@ -28,11 +28,15 @@ type CloneService struct {
 	promptBuilder    ClonePromptBuilder
 	responseParser   LLMResponseParser
 	reactionEngine   ReactionEngine
+	relationshipSvc  *RelationshipService
+	sessionRepo      repository.SessionRepository
+	factService      *FactService
 }
 
 var (
 	ErrCloneServiceNotConfigured = errors.New("clone service not configured")
 	ErrCloneInvalidInput         = errors.New("clone invalid input")
+	ErrCloneSessionForbidden     = errors.New("clone session belongs to another user")
 )
 
 func NewCloneService(
@@ -61,22 +65,88 @@ func NewCloneService(
 	}
 }
 
+// SetRelationshipService habilita la evolucion de vinculos a partir de los deltas del LLM.
+func (s *CloneService) SetRelationshipService(svc *RelationshipService) { s.relationshipSvc = svc }
+
+// SetSessionRepository permite resolver el clon a partir de la sesion (clone_profile_id).
+// Sin repositorio, o con sesiones legacy sin clon, se usa el perfil del usuario.
+func (s *CloneService) SetSessionRepository(repo repository.SessionRepository) { s.sessionRepo = repo }
+
+// SetFactService agrega al prompt la seccion [HECHOS CONOCIDOS] con los hechos vigentes del clon.
+func (s *CloneService) SetFactService(svc *FactService) { s.factService = svc }
+
+// cloneTurn agrupa el estado calculado antes de invocar al LLM, compartido por Chat y ChatStream.
+type cloneTurn struct {
+	userID           string
+	sessionID        string
+	profile          domain.CloneProfile
+	activeCharacter  *domain.Character
+	interactionDebug *domain.InteractionDebug
+	prompt           string
+}
+
 // Chat genera una respuesta del clon basada en perfil, rasgos y contexto, la persiste y devuelve el mensaje completo.
 func (s *CloneService) Chat(ctx context.Context, userID, sessionID, userMessage string) (domain.Message, *domain.InteractionDebug, error) {
+	turn, err := s.prepareTurn(ctx, userID, sessionID, userMessage)
+	if err != nil {
+		return domain.Message{}, nil, err
+	}
+
+	responseRaw, err := s.llmClient.Generate(ctx, turn.prompt)
+	if err != nil {
+		return domain.Message{}, nil, fmt.Errorf("llm generate: %w", err)
+	}
+
+	return s.finishTurn(ctx, turn, responseRaw)
+}
+
+// ChatStream hace lo mismo que Chat pero consume el LLM en streaming y llama a onToken con cada
+// fragmento de public_response a medida que llega. inner_monologue nunca se emite.
+// El mensaje persistido se arma con la respuesta completa, igual que en Chat.
+func (s *CloneService) ChatStream(ctx context.Context, userID, sessionID, userMessage string, onToken func(string) error) (domain.Message, *domain.InteractionDebug, error) {
+	turn, err := s.prepareTurn(ctx, userID, sessionID, userMessage)
+	if err != nil {
+		return domain.Message{}, nil, err
+	}
+
+	parser := NewLLMStreamParser()
+	responseRaw, err := s.llmClient.GenerateStream(ctx, turn.prompt, func(chunk string) error {
+		if onToken == nil {
+			return nil
+		}
+		for _, ev := range parser.Feed(chunk) {
+			if ev.Type != LLMStreamPublicDelta {
+				continue
+			}
+			if err := onToken(ev.Text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Message{}, nil, fmt.Errorf("llm generate stream: %w", err)
+	}
+
+	return s.finishTurn(ctx, turn, responseRaw)
+}
+
+// prepareTurn carga perfil, contexto y narrativa, decide memoria/objetivo y arma el prompt del turno.
+func (s *CloneService) prepareTurn(ctx context.Context, userID, sessionID, userMessage string) (cloneTurn, error) {
 	if s == nil || s.llmClient == nil || s.messageRepo == nil || s.profileRepo == nil || s.traitRepo == nil || s.contextService == nil {
-		return domain.Message{}, nil, ErrCloneServiceNotConfigured
+		return cloneTurn{}, ErrCloneServiceNotConfigured
 	}
 
 	userID = strings.TrimSpace(userID)
 	sessionID = strings.TrimSpace(sessionID)
 	userMessage = strings.TrimSpace(userMessage)
 	if userID == "" || userMessage == "" {
-		return domain.Message{}, nil, ErrCloneInvalidInput
+		return cloneTurn{}, ErrCloneInvalidInput
 	}
 
-	profile, err := s.profileRepo.GetByUserID(ctx, userID)
+	profile, session, err := s.resolveProfile(ctx, userID, sessionID)
 	if err != nil {
-		return domain.Message{}, nil, fmt.Errorf("get profile: %w", err)
+		return cloneTurn{}, err
 	}
 
 	analysisSummary := AnalysisResult{Input: userMessage}
@@ -84,18 +154,36 @@ func (s *CloneService) Chat(ctx context.Context, userID, sessionID, userMessage
 
 	traits, err := s.traitRepo.FindByProfileID(ctx, profile.ID)
 	if err != nil {
-		return domain.Message{}, nil, fmt.Errorf("get traits: %w", err)
+		return cloneTurn{}, fmt.Errorf("get traits: %w", err)
 	}
+	HydrateBig5(&profile, traits)
 
 	contextText, err := s.contextService.GetContext(ctx, sessionID)
 	if err != nil {
-		return domain.Message{}, nil, fmt.Errorf("get context: %w", err)
+		return cloneTurn{}, fmt.Errorf("get context: %w", err)
+	}
+
+	// Personajes del clon: se listan una vez por turno y se comparten entre interlocutor, contexto y snapshot.
+	useNarrative := s.narrativeService != nil && s.narrativeService.characterRepo != nil && parseErr == nil
+	var chars []domain.Character
+	if useNarrative {
+		chars, err = s.narrativeService.characterRepo.ListByProfileID(ctx, profileUUID)
+		if err != nil {
+			log.Printf("warning: list characters: %v", err)
+			useNarrative = false
+		}
+	}
+
+	// Interlocutor del turno: personaje nombrado, "hablando como" de la sesion o aludido por su relacion.
+	var interlocutor *domain.Character
+	if useNarrative {
+		interlocutor = s.resolveInterlocutor(ctx, chars, session, userMessage)
 	}
 
 	// Contexto narrativo (opcional; no debe bloquear chat)
 	var narrativeText string
-	if s.narrativeService != nil && parseErr == nil {
-		narrativeText, err = s.narrativeService.BuildNarrativeContext(ctx, profileUUID, userMessage)
+	if useNarrative {
+		narrativeText, err = s.narrativeService.buildNarrativeContext(ctx, profileUUID, userMessage, interlocutor, chars)
 		if err != nil {
 			log.Printf("warning: build narrative context: %v", err)
 			narrativeText = ""
@@ -110,9 +198,13 @@ func (s *CloneService) Chat(ctx context.Context, userID, sessionID, userMessage
 		log.Printf("debug: narrative tension=%t text=%q", isHighTension, narrativeText)
 	}
 
-	// Snapshot del estado del vínculo (si existe) para metas/contexto
-	if s.narrativeService != nil && parseErr == nil {
-		if rel, ok := s.snapshotRelationship(ctx, profileUUID, userMessage); ok {
+	// Snapshot del estado del vínculo (si existe) para metas/contexto. Los deltas del turno solo se
+	// aplican al interlocutor resuelto; sin el, ApplyTurn actualiza solo la sesion.
+	activeCharacter := interlocutor
+	if activeCharacter != nil {
+		analysisSummary.Relationship = activeCharacter.Relationship
+	} else if useNarrative {
+		if rel, ok := snapshotRelationship(chars, userMessage); ok {
 			analysisSummary.Relationship = rel
 		}
 	}
@@ -177,9 +269,16 @@ func (s *CloneService) Chat(ctx context.Context, userID, sessionID, userMessage
 		}
 		importance := weight
 
+		// Solo se liga al interlocutor resuelto, no al vinculo de fallback.
+		var relatedID *uuid.UUID
+		if interlocutor != nil {
+			relatedID = &interlocutor.ID
+		}
+
 		if err := s.narrativeService.InjectMemory(
 			ctx,
 			profileUUID,
+			relatedID,
 			userMessage,
 			importance,
 			weight,
@@ -203,13 +302,30 @@ func (s *CloneService) Chat(ctx context.Context, userID, sessionID, userMessage
 		}
 	}
 
-	prompt := s.promptBuilder.BuildClonePrompt(&profile, traits, contextText, narrativeText, userMessage, trivialInput)
-
-	responseRaw, err := s.llmClient.Generate(ctx, prompt)
-	if err != nil {
-		return domain.Message{}, nil, fmt.Errorf("llm generate: %w", err)
+	// Hechos conocidos (opcional; no debe bloquear chat)
+	var factsText string
+	if s.factService != nil && parseErr == nil {
+		factsText, err = s.factService.BuildFactsContext(ctx, profileUUID)
+		if err != nil {
+			log.Printf("warning: build facts context: %v", err)
+			factsText = ""
+		}
 	}
 
+	prompt := s.promptBuilder.BuildClonePromptWithFacts(&profile, traits, contextText, narrativeText, factsText, userMessage, trivialInput)
+
+	return cloneTurn{
+		userID:           userID,
+		sessionID:        sessionID,
+		profile:          profile,
+		activeCharacter:  activeCharacter,
+		interactionDebug: interactionDebug,
+		prompt:           prompt,
+	}, nil
+}
+
+// finishTurn parsea la respuesta cruda del LLM, persiste el mensaje del clon y aplica los deltas de vinculo.
+func (s *CloneService) finishTurn(ctx context.Context, turn cloneTurn, responseRaw string) (domain.Message, *domain.InteractionDebug, error) {
 	log.Printf("clone raw response received (len=%d)", len(responseRaw))
 
 	llmResp, ok := s.responseParser.ParseLLMResponseSafe(responseRaw)
@@ -224,8 +340,8 @@ func (s *CloneService) Chat(ctx context.Context, userID, sessionID, userMessage
 
 	cloneMessage := domain.Message{
 		ID:        uuid.NewString(),
-		UserID:    userID,
-		SessionID: sessionID,
+		UserID:    turn.userID,
+		SessionID: turn.sessionID,
 		Content:   response,
 		Role:      "clone",
 		CreatedAt: time.Now().UTC(),
@@ -235,17 +351,61 @@ func (s *CloneService) Chat(ctx context.Context, userID, sessionID, userMessage
 		return domain.Message{}, nil, fmt.Errorf("persist clone message: %w", err)
 	}
 
-	return cloneMessage, interactionDebug, nil
+	// Evolucion del vinculo: no debe bloquear el chat si falla
+	if s.relationshipSvc != nil && ok {
+		if _, err := s.relationshipSvc.ApplyTurn(ctx, &turn.profile, turn.activeCharacter, turn.sessionID, cloneMessage.ID, llmResp); err != nil {
+			log.Printf("warning: apply relationship deltas: %v", err)
+		}
+	}
+
+	return cloneMessage, turn.interactionDebug, nil
 }
 
-// snapshotRelationship intenta recuperar el vinculo activo (o el primero disponible) para usarlo en metas.
-func (s *CloneService) snapshotRelationship(ctx context.Context, profileID uuid.UUID, userMessage string) (domain.RelationshipVectors, bool) {
-	if s.narrativeService == nil || profileID == uuid.Nil {
-		return domain.RelationshipVectors{}, false
+// resolveProfile elige el clon del turno: el ligado a la sesion si existe, o el del usuario como fallback.
+// Devuelve tambien la sesion cargada (vacia si no hay repositorio o sessionID).
+func (s *CloneService) resolveProfile(ctx context.Context, userID, sessionID string) (domain.CloneProfile, domain.Session, error) {
+	var session domain.Session
+	if s.sessionRepo != nil && sessionID != "" {
+		var err error
+		session, err = s.sessionRepo.GetByID(ctx, sessionID)
+		if err != nil {
+			return domain.CloneProfile{}, domain.Session{}, fmt.Errorf("get session: %w", err)
+		}
+		if session.UserID != "" && session.UserID != userID {
+			return domain.CloneProfile{}, domain.Session{}, ErrCloneSessionForbidden
+		}
+		if session.CloneProfileID != "" {
+			profile, err := s.profileRepo.GetByID(ctx, session.CloneProfileID)
+			if err != nil {
+				return domain.CloneProfile{}, domain.Session{}, fmt.Errorf("get profile: %w", err)
+			}
+			if profile.UserID != "" && profile.UserID != userID {
+				return domain.CloneProfile{}, domain.Session{}, ErrCloneSessionForbidden
+			}
+			return profile, session, nil
+		}
 	}
 
-	chars, err := s.narrativeService.characterRepo.ListByProfileID(ctx, profileID)
-	if err != nil || len(chars) == 0 {
+	profile, err := s.profileRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return domain.CloneProfile{}, domain.Session{}, fmt.Errorf("get profile: %w", err)
+	}
+	return profile, session, nil
+}
+
+// resolveInterlocutor resuelve el interlocutor del turno usando el "hablando como" de la sesion.
+func (s *CloneService) resolveInterlocutor(ctx context.Context, chars []domain.Character, session domain.Session, userMessage string) *domain.Character {
+	var speakingAs *uuid.UUID
+	if id, err := uuid.Parse(session.SpeakingAsCharacterID); err == nil {
+		speakingAs = &id
+	}
+	return s.narrativeService.resolveInterlocutor(ctx, chars, userMessage, speakingAs)
+}
+
+// snapshotRelationship elige el vinculo activo (o el primero disponible) para usarlo en metas.
+// Es solo contexto del prompt: el personaje elegido por descarte no recibe los deltas del turno.
+func snapshotRelationship(chars []domain.Character, userMessage string) (domain.RelationshipVectors, bool) {
+	if len(chars) == 0 {
 		return domain.RelationshipVectors{}, false
 	}
 
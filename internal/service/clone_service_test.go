@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+
 	"clone-llm/internal/domain"
 	"clone-llm/internal/llm"
 )
@@ -15,13 +17,15 @@ type mockCloneProfileRepo struct {
 	profile       domain.CloneProfile
 	err           error
 	lastGetByUser string
+	lastGetByID   string
 }
 
 func (m *mockCloneProfileRepo) Create(context.Context, domain.CloneProfile) error {
 	return errors.New("not implemented")
 }
 
-func (m *mockCloneProfileRepo) GetByID(context.Context, string) (domain.CloneProfile, error) {
+func (m *mockCloneProfileRepo) GetByID(_ context.Context, id string) (domain.CloneProfile, error) {
+	m.lastGetByID = id
 	return m.profile, m.err
 }
 
@@ -30,6 +34,14 @@ func (m *mockCloneProfileRepo) GetByUserID(_ context.Context, userID string) (do
 	return m.profile, m.err
 }
 
+func (m *mockCloneProfileRepo) ListByUserID(context.Context, string) ([]domain.CloneProfile, error) {
+	return []domain.CloneProfile{m.profile}, m.err
+}
+
+func (m *mockCloneProfileRepo) UpdateTraitStability(context.Context, string, float64) error {
+	return errors.New("not implemented")
+}
+
 type mockCloneTraitRepo struct {
 	traits []domain.Trait
 	err    error
@@ -47,6 +59,14 @@ func (m *mockCloneTraitRepo) FindByCategory(context.Context, string, string) ([]
 	return nil, errors.New("not implemented")
 }
 
+func (m *mockCloneTraitRepo) UpdateCategory(context.Context, string, string, func([]domain.Trait) ([]domain.Trait, error)) error {
+	return nil
+}
+
+func (m *mockCloneTraitRepo) CreateObservation(context.Context, domain.TraitObservation) error {
+	return errors.New("not implemented")
+}
+
 type mockCloneMessageRepo struct {
 	created []domain.Message
 	err     error
@@ -494,3 +514,126 @@ func TestCloneServiceChat_FallbackResponseWhenParserReturnsEmpty(t *testing.T) {
 		t.Fatalf("expected non-empty fallback response")
 	}
 }
+
+func TestCloneServiceChatStream_EmitsOnlyPublicResponse(t *testing.T) {
+	profileRepo := &mockCloneProfileRepo{profile: domain.CloneProfile{ID: "8f4ac247-4ec1-4f8f-9d1c-f53d8e7b4207", Name: "Clone"}}
+	messageRepo := &mockCloneMessageRepo{}
+	llmClient := &llm.MockClient{
+		Response:        `{"inner_monologue":"me molesta","public_response":"Todo bien, gracias."}`,
+		StreamChunkSize: 5,
+	}
+	svc := NewCloneService(
+		llmClient,
+		messageRepo,
+		profileRepo,
+		&mockCloneTraitRepo{},
+		&mockContextService{},
+		nil,
+		nil,
+		ClonePromptBuilder{},
+		LLMResponseParser{},
+		ReactionEngine{},
+	)
+
+	var tokens []string
+	msg, dbg, err := svc.ChatStream(context.Background(), "user-1", "s1", "como estas?", func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if dbg == nil {
+		t.Fatalf("expected interaction debug")
+	}
+	streamed := strings.Join(tokens, "")
+	if streamed != "Todo bien, gracias." || len(tokens) < 2 {
+		t.Fatalf("unexpected streamed tokens: %q", tokens)
+	}
+	if strings.Contains(streamed, "molesta") {
+		t.Fatalf("inner monologue leaked into stream")
+	}
+	if msg.Content != streamed || len(messageRepo.created) != 1 {
+		t.Fatalf("expected persisted message to match stream, got %+v", msg)
+	}
+}
+
+func TestCloneServiceChat_ResolvesProfileFromSession(t *testing.T) {
+	profileRepo := &mockCloneProfileRepo{profile: domain.CloneProfile{ID: "p2", UserID: "user-1", Name: "Segundo"}}
+	messageRepo := &mockCloneMessageRepo{}
+	svc := NewCloneService(
+		&llm.MockClient{Response: `{"public_response":"ok"}`},
+		messageRepo,
+		profileRepo,
+		&mockCloneTraitRepo{},
+		&mockContextService{},
+		nil,
+		nil,
+		ClonePromptBuilder{},
+		LLMResponseParser{},
+		ReactionEngine{},
+	)
+	svc.SetSessionRepository(&relFakeSessionRepo{session: domain.Session{ID: "s1", UserID: "user-1", CloneProfileID: "p2"}})
+
+	if _, _, err := svc.Chat(context.Background(), "user-1", "s1", "hola"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profileRepo.lastGetByID != "p2" || profileRepo.lastGetByUser != "" {
+		t.Fatalf("expected profile resolved from session, got byID=%q byUser=%q", profileRepo.lastGetByID, profileRepo.lastGetByUser)
+	}
+
+	svc.SetSessionRepository(&relFakeSessionRepo{session: domain.Session{ID: "s2", UserID: "otro", CloneProfileID: "p2"}})
+	if _, _, err := svc.Chat(context.Background(), "user-1", "s2", "hola"); !errors.Is(err, ErrCloneSessionForbidden) {
+		t.Fatalf("expected ErrCloneSessionForbidden, got %v", err)
+	}
+}
+
+// listingCharacterRepo lista personajes fijos y registra las actualizaciones.
+type listingCharacterRepo struct {
+	relFakeCharacterRepo
+	chars []domain.Character
+}
+
+func (f *listingCharacterRepo) ListByProfileID(context.Context, uuid.UUID) ([]domain.Character, error) {
+	return f.chars, nil
+}
+
+func TestCloneServiceChat_DeltasSkipUnresolvedCharacters(t *testing.T) {
+	profileID := uuid.New()
+	charRepo := &listingCharacterRepo{chars: []domain.Character{
+		{ID: uuid.New(), CloneProfileID: profileID, Name: "Ana", Relation: "hermana", Relationship: domain.RelationshipVectors{Trust: 50, Intimacy: 50, Respect: 50}},
+	}}
+	sessionRepo := &relFakeSessionRepo{session: domain.Session{ID: "s1", UserID: "user-1", CloneProfileID: profileID.String(), Relationship: domain.RelationshipVectors{Trust: 50, Intimacy: 50, Respect: 50}}}
+	narrativeSvc := &NarrativeService{characterRepo: charRepo, memoryRepo: fakeMemoryRepo{}, llmClient: fakeSilentLLM{}}
+	svc := NewCloneService(
+		&llm.MockClient{Response: `{"public_response":"ok","trust_delta":-10,"new_state":"dolido"}`},
+		&mockCloneMessageRepo{},
+		&mockCloneProfileRepo{profile: domain.CloneProfile{ID: profileID.String(), UserID: "user-1", Name: "Clone", Big5: domain.Big5Profile{Neuroticism: 100}}},
+		&mockCloneTraitRepo{},
+		&mockContextService{},
+		narrativeSvc,
+		nil,
+		ClonePromptBuilder{},
+		LLMResponseParser{},
+		ReactionEngine{},
+	)
+	svc.SetSessionRepository(sessionRepo)
+	svc.SetRelationshipService(NewRelationshipService(charRepo, sessionRepo, nil))
+
+	if _, _, err := svc.Chat(context.Background(), "user-1", "s1", "hoy me fue mal en el trabajo"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(charRepo.updated) != 0 {
+		t.Fatalf("expected no character update without a resolved interlocutor, got %+v", charRepo.updated)
+	}
+	if sessionRepo.updated == nil || sessionRepo.updated.Trust >= 50 {
+		t.Fatalf("expected deltas applied to the session, got %+v", sessionRepo.updated)
+	}
+
+	if _, _, err := svc.Chat(context.Background(), "user-1", "s1", "Ana me grito otra vez"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(charRepo.updated) != 1 || charRepo.updated[0].Name != "Ana" {
+		t.Fatalf("expected named character to receive the deltas, got %+v", charRepo.updated)
+	}
+}
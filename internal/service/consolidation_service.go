@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgvector "github.com/pgvector/pgvector-go"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/llm"
+	"clone-llm/internal/repository"
+)
+
+const (
+	// consolidationMaxMessages limita el transcript a los ultimos N mensajes de la sesion.
+	consolidationMaxMessages = 60
+	// consolidationMaxFacts evita que una respuesta verborragica llene la memoria de hechos.
+	consolidationMaxFacts = 10
+	// consolidationTimeout acota el trabajo en background lanzado desde el hook.
+	consolidationTimeout = 2 * time.Minute
+
+	consolidationSummaryImportance = 6
+	consolidationFactImportance    = 5
+)
+
+var ErrConsolidationNotConfigured = errors.New("consolidation service not configured")
+
+// ConsolidationService resume una sesion terminada en memorias narrativas:
+// el resumen queda como memoria episodica y cada hecho extraido como memoria semantica,
+// todas ligadas a la sesion de origen.
+type ConsolidationService struct {
+	llmClient   llm.LLMClient
+	messageRepo repository.MessageRepository
+	memoryRepo  repository.MemoryRepository
+	sessionRepo repository.SessionRepository
+	logger      *zap.Logger
+	factService *FactService
+	profileRepo repository.ProfileRepository
+}
+
+func NewConsolidationService(
+	llmClient llm.LLMClient,
+	messageRepo repository.MessageRepository,
+	memoryRepo repository.MemoryRepository,
+	sessionRepo repository.SessionRepository,
+	logger *zap.Logger,
+) *ConsolidationService {
+	return &ConsolidationService{
+		llmClient:   llmClient,
+		messageRepo: messageRepo,
+		memoryRepo:  memoryRepo,
+		sessionRepo: sessionRepo,
+		logger:      logger,
+	}
+}
+
+// SetFactService habilita el registro de los hechos estructurados de la sesion en el almacen de hechos.
+func (s *ConsolidationService) SetFactService(svc *FactService) { s.factService = svc }
+
+// SetProfileRepository habilita consolidar sesiones creadas sin clon: se resumen contra el clon por
+// defecto del usuario, el mismo que CloneService usa para responder en ellas.
+func (s *ConsolidationService) SetProfileRepository(repo repository.ProfileRepository) {
+	s.profileRepo = repo
+}
+
+// Hook devuelve un SessionEndHook que consolida la sesion en background,
+// para no demorar el request que la cerro.
+func (s *ConsolidationService) Hook() SessionEndHook {
+	return func(ctx context.Context, session domain.Session, reason string) error {
+		if s == nil {
+			return ErrConsolidationNotConfigured
+		}
+		bg := context.WithoutCancel(ctx)
+		go func() {
+			runCtx, cancel := context.WithTimeout(bg, consolidationTimeout)
+			defer cancel()
+			if _, err := s.ConsolidateSession(runCtx, session); err != nil && s.logger != nil {
+				s.logger.Warn("session consolidation failed", zap.Error(err), zap.String("session_id", session.ID), zap.String("reason", reason))
+			}
+		}()
+		return nil
+	}
+}
+
+// ConsolidateSession envia el transcript de la sesion al LLM y persiste el resultado.
+// Una sesion sin clon asociado se consolida contra el clon por defecto del usuario (ver SetProfileRepository).
+// Sesiones sin clon resoluble, sin mensajes o ya consolidadas devuelven un resultado vacio sin error.
+func (s *ConsolidationService) ConsolidateSession(ctx context.Context, session domain.Session) (domain.MemoryConsolidation, error) {
+	if s == nil || s.llmClient == nil || s.messageRepo == nil || s.memoryRepo == nil || s.sessionRepo == nil {
+		return domain.MemoryConsolidation{}, ErrConsolidationNotConfigured
+	}
+	cloneProfileID, err := s.resolveProfileID(ctx, session)
+	if err != nil {
+		return domain.MemoryConsolidation{}, err
+	}
+	if cloneProfileID == "" {
+		return domain.MemoryConsolidation{}, nil
+	}
+	profileID, err := uuid.Parse(cloneProfileID)
+	if err != nil {
+		return domain.MemoryConsolidation{}, fmt.Errorf("parse clone profile id: %w", err)
+	}
+
+	// La reclamacion es atomica: de dos hooks concurrentes solo uno resume la sesion.
+	claimed, err := s.sessionRepo.ClaimConsolidation(ctx, session.ID, time.Now().UTC())
+	if err != nil {
+		return domain.MemoryConsolidation{}, fmt.Errorf("claim session consolidation: %w", err)
+	}
+	if !claimed {
+		return domain.MemoryConsolidation{}, nil
+	}
+
+	result, err := s.consolidate(ctx, session, profileID)
+	if err != nil {
+		// Sin resumen guardado la sesion vuelve a quedar pendiente para un proximo intento.
+		if rerr := s.sessionRepo.ReleaseConsolidation(context.WithoutCancel(ctx), session.ID); rerr != nil && s.logger != nil {
+			s.logger.Warn("release session consolidation failed", zap.Error(rerr), zap.String("session_id", session.ID))
+		}
+		return domain.MemoryConsolidation{}, err
+	}
+	return result, nil
+}
+
+// resolveProfileID devuelve el clon de la sesion o, si se creo sin clon, el clon por defecto del usuario.
+// Devuelve "" si no hay ninguno.
+func (s *ConsolidationService) resolveProfileID(ctx context.Context, session domain.Session) (string, error) {
+	if id := strings.TrimSpace(session.CloneProfileID); id != "" {
+		return id, nil
+	}
+	if s.profileRepo == nil || session.UserID == "" {
+		return "", nil
+	}
+	profile, err := s.profileRepo.GetByUserID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("get default profile: %w", err)
+	}
+	return profile.ID, nil
+}
+
+func (s *ConsolidationService) consolidate(ctx context.Context, session domain.Session, profileID uuid.UUID) (domain.MemoryConsolidation, error) {
+	messages, err := s.messageRepo.ListBySessionID(ctx, session.ID)
+	if err != nil {
+		return domain.MemoryConsolidation{}, fmt.Errorf("list session messages: %w", err)
+	}
+	transcript, happenedAt := buildConsolidationTranscript(messages)
+	if transcript == "" {
+		return domain.MemoryConsolidation{}, nil
+	}
+	if happenedAt.IsZero() {
+		happenedAt = time.Now().UTC()
+	}
+
+	output, err := s.summarize(ctx, transcript)
+	if err != nil {
+		return domain.MemoryConsolidation{}, err
+	}
+
+	summary := strings.TrimSpace(output.Summary)
+	if shift := strings.TrimSpace(output.EmotionalShift); shift != "" && summary != "" {
+		summary += "\nCambio en la dinamica: " + shift
+	}
+	if summary == "" {
+		return domain.MemoryConsolidation{}, errors.New("consolidation: empty summary")
+	}
+
+	// El resumen se liga al personaje que encarnaba el usuario; los hechos pueden ser de cualquiera.
+	var speakingAs *uuid.UUID
+	if id, err := uuid.Parse(session.SpeakingAsCharacterID); err == nil {
+		speakingAs = &id
+	}
+
+	sessionID := session.ID
+	if err := s.storeMemory(ctx, profileID, speakingAs, &sessionID, summary, domain.MemoryKindEpisodic, consolidationSummaryImportance, happenedAt); err != nil {
+		return domain.MemoryConsolidation{}, fmt.Errorf("store summary memory: %w", err)
+	}
+
+	result := domain.MemoryConsolidation{Summary: summary}
+	for _, fact := range normalizeExtractedFacts(output.ExtractedFacts) {
+		if err := s.storeMemory(ctx, profileID, nil, &sessionID, fact, domain.MemoryKindSemantic, consolidationFactImportance, happenedAt); err != nil {
+			// Un hecho perdido no invalida el resumen ya guardado.
+			if s.logger != nil {
+				s.logger.Warn("store fact memory failed", zap.Error(err), zap.String("session_id", session.ID))
+			}
+			continue
+		}
+		result.NewFacts = append(result.NewFacts, fact)
+	}
+	if s.factService != nil && len(output.Facts) > 0 {
+		if _, err := s.factService.RecordFacts(ctx, profileID, output.Facts, FactSource{SessionID: session.ID}); err != nil && s.logger != nil {
+			s.logger.Warn("record session facts failed", zap.Error(err), zap.String("session_id", session.ID))
+		}
+	}
+	return result, nil
+}
+
+func (s *ConsolidationService) summarize(ctx context.Context, transcript string) (domain.NarrativeOutput, error) {
+	prompt := `Eres la memoria de un clon conversacional. Lee la conversacion entre el usuario y el clon y consolidala.
+- "summary": resumen narrativo en tercera persona de lo sucedido (2-4 oraciones).
+- "extracted_facts": lista de hechos concretos y duraderos sobre el usuario o su entorno (nombres, gustos, planes, datos). Un hecho por elemento, sin repetir el resumen. Lista vacia si no hay.
+- "emotional_shift": como cambio la dinamica emocional entre ambos (una frase, o vacio si no cambio).
+- "facts": los mismos hechos como tripletas sujeto/predicado/objeto con confianza 0-1 (ej: "usuario" / "tiene un perro llamado" / "Toby"). "single_valued" es true solo si el predicado admite un unico valor a la vez (vive en, trabaja en, su pareja es).
+Devuelve SOLO un JSON con este formato:
+{"summary": "...", "extracted_facts": ["..."], "emotional_shift": "...", "facts": [{"subject": "...", "predicate": "...", "object": "...", "confidence": 0.9, "single_valued": false}]}
+
+Conversacion:
+` + transcript
+
+	raw, err := s.llmClient.Generate(ctx, prompt)
+	if err != nil {
+		return domain.NarrativeOutput{}, fmt.Errorf("llm generate: %w", err)
+	}
+
+	cleaned := cleanLLMJSONResponse(raw)
+	if obj := extractFirstJSONObject(cleaned); obj != "" {
+		cleaned = obj
+	}
+
+	var output domain.NarrativeOutput
+	if err := json.Unmarshal([]byte(cleaned), &output); err != nil {
+		return domain.NarrativeOutput{}, fmt.Errorf("parse consolidation response: %w", err)
+	}
+	return output, nil
+}
+
+func (s *ConsolidationService) storeMemory(ctx context.Context, profileID uuid.UUID, relatedCharacterID *uuid.UUID, sessionID *string, content, kind string, importance int, happenedAt time.Time) error {
+	embed, err := s.llmClient.CreateEmbedding(ctx, content)
+	if err != nil {
+		return fmt.Errorf("create embedding: %w", err)
+	}
+
+	now := time.Now().UTC()
+	return s.memoryRepo.Create(ctx, domain.NarrativeMemory{
+		ID:                 uuid.New(),
+		CloneProfileID:     profileID,
+		RelatedCharacterID: relatedCharacterID,
+		Content:            content,
+		Embedding:          pgvector.NewVector(embed),
+		Importance:         importance,
+		EmotionalWeight:    5,
+		EmotionalIntensity: 10,
+		EmotionCategory:    "NEUTRAL",
+		SentimentLabel:     "NEUTRAL",
+		Kind:               kind,
+		SourceSessionID:    sessionID,
+		HappenedAt:         happenedAt,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	})
+}
+
+// buildConsolidationTranscript arma el dialogo legible y devuelve la hora del ultimo mensaje.
+func buildConsolidationTranscript(messages []domain.Message) (string, time.Time) {
+	if len(messages) > consolidationMaxMessages {
+		messages = messages[len(messages)-consolidationMaxMessages:]
+	}
+
+	var (
+		b    strings.Builder
+		last time.Time
+	)
+	for _, m := range messages {
+		content := strings.TrimSpace(m.Content)
+		if content == "" {
+			continue
+		}
+		speaker := "Usuario"
+		switch m.Role {
+		case "clone":
+			speaker = "Clon"
+		case "system":
+			continue
+		}
+		b.WriteString(speaker)
+		b.WriteString(": ")
+		b.WriteString(content)
+		b.WriteString("\n")
+		if m.CreatedAt.After(last) {
+			last = m.CreatedAt
+		}
+	}
+	return strings.TrimSpace(b.String()), last
+}
+
+func normalizeExtractedFacts(facts []string) []string {
+	seen := make(map[string]struct{}, len(facts))
+	var out []string
+	for _, f := range facts {
+		f = strings.TrimSpace(f)
+		key := strings.ToLower(f)
+		if f == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, f)
+		if len(out) == consolidationMaxFacts {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/llm"
+)
+
+type consolidationFakeMemoryRepo struct {
+	actionFakeMemoryRepo
+	stored []domain.NarrativeMemory
+}
+
+func (f *consolidationFakeMemoryRepo) Create(_ context.Context, m domain.NarrativeMemory) error {
+	f.stored = append(f.stored, m)
+	return nil
+}
+
+func TestConsolidationService_StoresSummaryAndFactsWithProvenance(t *testing.T) {
+	profileID := uuid.New()
+	last := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	messages := &mockMessageRepo{msgs: []domain.Message{
+		{Role: "user", Content: "me mude a Rosario con Lucia", CreatedAt: last.Add(-time.Minute)},
+		{Role: "clone", Content: "que cambio grande", CreatedAt: last},
+	}}
+	llmClient := &llm.MockClient{
+		Response:  "```json\n{\"summary\": \"El usuario conto que se mudo.\", \"extracted_facts\": [\"Vive en Rosario\", \" vive en rosario \", \"Su pareja es Lucia\", \"\"], \"emotional_shift\": \"mas cercania\"}\n```",
+		Embedding: []float32{0.1, 0.2},
+	}
+	memories := &consolidationFakeMemoryRepo{}
+	svc := NewConsolidationService(llmClient, messages, memories, &relFakeSessionRepo{}, zap.NewNop())
+
+	session := domain.Session{ID: "session-1", CloneProfileID: profileID.String()}
+	got, err := svc.ConsolidateSession(context.Background(), session)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(got.NewFacts) != 2 || got.NewFacts[0] != "Vive en Rosario" || got.NewFacts[1] != "Su pareja es Lucia" {
+		t.Fatalf("unexpected facts: %+v", got.NewFacts)
+	}
+	if len(memories.stored) != 3 {
+		t.Fatalf("expected summary + 2 facts, got %d memories", len(memories.stored))
+	}
+	summary := memories.stored[0]
+	if summary.Kind != domain.MemoryKindEpisodic || summary.Content != "El usuario conto que se mudo.\nCambio en la dinamica: mas cercania" {
+		t.Fatalf("unexpected summary memory: %+v", summary)
+	}
+	for _, m := range memories.stored {
+		if m.SourceSessionID == nil || *m.SourceSessionID != "session-1" || m.CloneProfileID != profileID || !m.HappenedAt.Equal(last) {
+			t.Fatalf("memory missing provenance: %+v", m)
+		}
+	}
+	if memories.stored[1].Kind != domain.MemoryKindSemantic || memories.stored[2].Kind != domain.MemoryKindSemantic {
+		t.Fatalf("expected facts stored as semantic memories")
+	}
+}
+
+func TestConsolidationService_RecordsStructuredFacts(t *testing.T) {
+	profileID := uuid.New()
+	messages := &mockMessageRepo{msgs: []domain.Message{{Role: "user", Content: "tengo un perro, Toby", CreatedAt: time.Now()}}}
+	llmClient := &llm.MockClient{
+		Response:  `{"summary": "El usuario hablo de su perro.", "extracted_facts": ["Tiene un perro llamado Toby"], "facts": [{"subject": "usuario", "predicate": "tiene un perro llamado", "object": "Toby", "confidence": 0.9}]}`,
+		Embedding: []float32{1},
+	}
+	facts := &fakeFactRepo{}
+	svc := NewConsolidationService(llmClient, messages, &consolidationFakeMemoryRepo{}, &relFakeSessionRepo{}, zap.NewNop())
+	svc.SetFactService(NewFactService(facts, nil))
+
+	if _, err := svc.ConsolidateSession(context.Background(), domain.Session{ID: "session-1", CloneProfileID: profileID.String()}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(facts.facts) != 1 || facts.facts[0].Object != "Toby" || facts.facts[0].CloneProfileID != profileID {
+		t.Fatalf("expected structured fact to be stored, got %+v", facts.facts)
+	}
+	if src := facts.facts[0].SourceSessionID; src == nil || *src != "session-1" {
+		t.Fatalf("expected session provenance, got %v", src)
+	}
+}
+
+func TestConsolidationService_SkipsAlreadyConsolidatedAndLegacySessions(t *testing.T) {
+	llmClient := &llm.MockClient{Response: `{"summary": "x"}`, Embedding: []float32{1}}
+	messages := &mockMessageRepo{msgs: []domain.Message{{Role: "user", Content: "hola"}}}
+
+	memories := &consolidationFakeMemoryRepo{}
+	sessions := &relFakeSessionRepo{consolidated: map[string]bool{"s1": true}}
+	svc := NewConsolidationService(llmClient, messages, memories, sessions, zap.NewNop())
+	if _, err := svc.ConsolidateSession(context.Background(), domain.Session{ID: "s1", CloneProfileID: uuid.NewString()}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.ConsolidateSession(context.Background(), domain.Session{ID: "s2"}); err != nil {
+		t.Fatalf("expected legacy session to be skipped, got %v", err)
+	}
+	if len(memories.stored) != 0 {
+		t.Fatalf("expected no new memories, got %d", len(memories.stored))
+	}
+}
+
+func TestConsolidationService_FallsBackToUserDefaultClone(t *testing.T) {
+	profileID := uuid.New()
+	messages := &mockMessageRepo{msgs: []domain.Message{{Role: "user", Content: "hola", CreatedAt: time.Now()}}}
+	memories := &consolidationFakeMemoryRepo{}
+	profiles := &mockCloneProfileRepo{profile: domain.CloneProfile{ID: profileID.String(), UserID: "u1"}}
+	svc := NewConsolidationService(&llm.MockClient{Response: `{"summary": "Hablaron."}`, Embedding: []float32{1}}, messages, memories, &relFakeSessionRepo{}, zap.NewNop())
+	svc.SetProfileRepository(profiles)
+
+	if _, err := svc.ConsolidateSession(context.Background(), domain.Session{ID: "s1", UserID: "u1"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profiles.lastGetByUser != "u1" {
+		t.Fatalf("expected default clone lookup for u1, got %q", profiles.lastGetByUser)
+	}
+	if len(memories.stored) != 1 || memories.stored[0].CloneProfileID != profileID {
+		t.Fatalf("expected summary stored on the default clone, got %+v", memories.stored)
+	}
+}
+
+func TestConsolidationService_ClaimsSessionOnce(t *testing.T) {
+	profileID := uuid.NewString()
+	messages := &mockMessageRepo{msgs: []domain.Message{{Role: "user", Content: "hola"}}}
+	memories := &consolidationFakeMemoryRepo{}
+	sessions := &relFakeSessionRepo{}
+	failing := NewConsolidationService(&llm.MockClient{Err: errors.New("llm down")}, messages, memories, sessions, zap.NewNop())
+	if _, err := failing.ConsolidateSession(context.Background(), domain.Session{ID: "s1", CloneProfileID: profileID}); err == nil {
+		t.Fatalf("expected summarize error")
+	}
+	if sessions.consolidated["s1"] || sessions.released != 1 {
+		t.Fatalf("expected failed consolidation to release its claim, got %+v", sessions)
+	}
+
+	svc := NewConsolidationService(&llm.MockClient{Response: `{"summary": "Hablaron."}`, Embedding: []float32{1}}, messages, memories, sessions, zap.NewNop())
+	for i := 0; i < 2; i++ {
+		if _, err := svc.ConsolidateSession(context.Background(), domain.Session{ID: "s1", CloneProfileID: profileID}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if len(memories.stored) != 1 {
+		t.Fatalf("expected a single summary for repeated hooks, got %d memories", len(memories.stored))
+	}
+}
+
+func TestSessionServiceCloseIdle_FiresHooksWithReason(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &relFakeSessionRepo{idle: []domain.Session{
+		{ID: "idle", ExpiresAt: now.Add(time.Hour)},
+		{ID: "vencida", ExpiresAt: now.Add(-time.Hour)},
+	}}
+	svc := NewSessionService(repo, zap.NewNop())
+	reasons := map[string]string{}
+	svc.OnSessionEnd(func(_ context.Context, s domain.Session, reason string) error {
+		if s.ClosedAt == nil {
+			t.Fatalf("hook received an open session")
+		}
+		reasons[s.ID] = reason
+		return nil
+	})
+
+	n, err := svc.CloseIdle(context.Background(), 30*time.Minute)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 sessions closed, got %d (%v)", n, err)
+	}
+	if reasons["idle"] != SessionEndReasonIdle || reasons["vencida"] != SessionEndReasonExpired {
+		t.Fatalf("unexpected reasons: %v", reasons)
+	}
+}
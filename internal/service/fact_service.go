@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+)
+
+const (
+	// maxFactsInContext limita cuantos hechos entran en [HECHOS CONOCIDOS].
+	maxFactsInContext = 20
+	// defaultFactConfidence se usa cuando el LLM no informa confianza.
+	defaultFactConfidence = 0.6
+	maxFactFieldLength    = 200
+)
+
+var ErrFactServiceNotConfigured = errors.New("fact service not configured")
+
+// FactSource identifica de donde salio un lote de hechos: un mensaje (analisis por turno)
+// o una sesion consolidada. Los campos vacios se guardan como NULL.
+type FactSource struct {
+	MessageID string
+	SessionID string
+}
+
+// FactRecordResult resume que paso con un lote de hechos extraidos.
+type FactRecordResult struct {
+	Created      int
+	Reinforced   int
+	Contradicted int
+}
+
+// FactService mantiene el almacen de hechos semanticos del clon: deduplica, refuerza la confianza
+// de lo que se repite y retira los hechos viejos que un hecho nuevo contradice.
+type FactService struct {
+	repo   repository.FactRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewFactService(repo repository.FactRepository, logger *zap.Logger) *FactService {
+	return &FactService{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// RecordFacts persiste los hechos extraidos. Para cada uno busca los hechos vigentes con el mismo
+// sujeto y predicado: si alguno tiene el mismo objeto se refuerza su confianza; si no, el hecho nuevo
+// se crea y, si su predicado es de valor unico, los vigentes con otro objeto quedan contradichos por el
+// ("vive en Madrid" -> "vive en Rosario"). Los predicados que se acumulan conviven ("tiene un perro llamado").
+func (s *FactService) RecordFacts(ctx context.Context, profileID uuid.UUID, facts []domain.ExtractedFact, source FactSource) (FactRecordResult, error) {
+	var result FactRecordResult
+	if s == nil || s.repo == nil {
+		return result, ErrFactServiceNotConfigured
+	}
+	if profileID == uuid.Nil {
+		return result, nil
+	}
+
+	now := s.now().UTC()
+	seen := make(map[string]struct{}, len(facts))
+	for _, extracted := range facts {
+		fact, ok := normalizeExtractedFact(extracted)
+		if !ok {
+			continue
+		}
+		objectKey := NormalizeCharacterName(fact.Object)
+		batchKey := fact.SubjectKey + "|" + fact.PredicateKey + "|" + objectKey
+		if _, dup := seen[batchKey]; dup {
+			continue
+		}
+		seen[batchKey] = struct{}{}
+
+		existing, err := s.repo.FindActiveByKey(ctx, profileID, fact.SubjectKey, fact.PredicateKey)
+		if err != nil {
+			return result, fmt.Errorf("find facts: %w", err)
+		}
+
+		var same *domain.Fact
+		var conflicting []domain.Fact
+		for i := range existing {
+			if NormalizeCharacterName(existing[i].Object) == objectKey {
+				same = &existing[i]
+				continue
+			}
+			if extracted.SingleValued {
+				conflicting = append(conflicting, existing[i])
+			}
+		}
+
+		if same != nil {
+			if err := s.repo.Reinforce(ctx, same.ID, reinforceFactConfidence(same.Confidence, fact.Confidence), now); err != nil {
+				return result, fmt.Errorf("reinforce fact: %w", err)
+			}
+			result.Reinforced++
+			continue
+		}
+
+		fact.ID = uuid.New()
+		fact.CloneProfileID = profileID
+		fact.SourceMessageID = optionalString(source.MessageID)
+		fact.SourceSessionID = optionalString(source.SessionID)
+		fact.CreatedAt = now
+		fact.UpdatedAt = now
+		if len(conflicting) == 0 {
+			if err := s.repo.Create(ctx, fact); err != nil {
+				return result, fmt.Errorf("create fact: %w", err)
+			}
+			result.Created++
+			continue
+		}
+
+		// El hecho nuevo y el retiro de los que contradice van juntos: nunca quedan dos vigentes.
+		contradicted := make([]uuid.UUID, 0, len(conflicting))
+		for _, old := range conflicting {
+			contradicted = append(contradicted, old.ID)
+		}
+		if err := s.repo.CreateContradicting(ctx, fact, contradicted, now); err != nil {
+			return result, fmt.Errorf("create contradicting fact: %w", err)
+		}
+		result.Created++
+
+		for _, old := range conflicting {
+			result.Contradicted++
+			if s.logger != nil {
+				s.logger.Info("fact contradicted",
+					zap.String("profile_id", profileID.String()),
+					zap.String("old", formatFact(old)),
+					zap.String("new", formatFact(fact)),
+				)
+			}
+		}
+	}
+	return result, nil
+}
+
+// BuildFactsContext devuelve los hechos vigentes del clon como lineas "- sujeto predicado objeto",
+// listas para la seccion [HECHOS CONOCIDOS] del prompt. Vacio si no hay hechos.
+func (s *FactService) BuildFactsContext(ctx context.Context, profileID uuid.UUID) (string, error) {
+	if s == nil || s.repo == nil {
+		return "", ErrFactServiceNotConfigured
+	}
+	facts, err := s.repo.ListActive(ctx, profileID, maxFactsInContext)
+	if err != nil {
+		return "", fmt.Errorf("list facts: %w", err)
+	}
+
+	var b strings.Builder
+	for _, f := range facts {
+		b.WriteString("- ")
+		b.WriteString(formatFact(f))
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// normalizeExtractedFact recorta los campos y descarta tripletas incompletas.
+func normalizeExtractedFact(f domain.ExtractedFact) (domain.Fact, bool) {
+	subject := truncateFactField(f.Subject)
+	predicate := truncateFactField(f.Predicate)
+	object := strings.TrimSpace(f.Object)
+	if subject == "" || predicate == "" || object == "" {
+		return domain.Fact{}, false
+	}
+	subjectKey := NormalizeCharacterName(subject)
+	predicateKey := NormalizeCharacterName(predicate)
+	if subjectKey == "" || predicateKey == "" {
+		return domain.Fact{}, false
+	}
+
+	confidence := f.Confidence
+	if confidence <= 0 {
+		confidence = defaultFactConfidence
+	}
+	if confidence > 1 {
+		confidence = 1
+	}
+	return domain.Fact{
+		Subject:      subject,
+		Predicate:    predicate,
+		Object:       object,
+		SubjectKey:   subjectKey,
+		PredicateKey: predicateKey,
+		Confidence:   confidence,
+	}, true
+}
+
+// reinforceFactConfidence combina dos observaciones independientes del mismo hecho:
+// 1 - (1-a)(1-b). Nunca baja la confianza previa.
+func reinforceFactConfidence(current, observed float64) float64 {
+	return 1 - (1-current)*(1-observed)
+}
+
+func formatFact(f domain.Fact) string {
+	return strings.TrimSpace(f.Subject) + " " + strings.TrimSpace(f.Predicate) + " " + strings.TrimSpace(f.Object)
+}
+
+func truncateFactField(s string) string {
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > maxFactFieldLength {
+		s = strings.TrimSpace(string(r[:maxFactFieldLength]))
+	}
+	return s
+}
+
+func optionalString(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/domain"
+)
+
+// fakeFactRepo guarda los hechos en memoria, con la misma semantica de vigencia que facts.
+type fakeFactRepo struct {
+	facts []domain.Fact
+}
+
+func (f *fakeFactRepo) Create(_ context.Context, fact domain.Fact) error {
+	f.facts = append(f.facts, fact)
+	return nil
+}
+
+func (f *fakeFactRepo) ListActive(_ context.Context, profileID uuid.UUID, limit int) ([]domain.Fact, error) {
+	var out []domain.Fact
+	for _, fact := range f.facts {
+		if fact.CloneProfileID == profileID && fact.ContradictedBy == nil {
+			out = append(out, fact)
+		}
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (f *fakeFactRepo) FindActiveByKey(_ context.Context, profileID uuid.UUID, subjectKey, predicateKey string) ([]domain.Fact, error) {
+	var out []domain.Fact
+	for _, fact := range f.facts {
+		if fact.CloneProfileID == profileID && fact.SubjectKey == subjectKey && fact.PredicateKey == predicateKey && fact.ContradictedBy == nil {
+			out = append(out, fact)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeFactRepo) Reinforce(_ context.Context, id uuid.UUID, confidence float64, at time.Time) error {
+	for i := range f.facts {
+		if f.facts[i].ID == id {
+			f.facts[i].Confidence = confidence
+			f.facts[i].UpdatedAt = at
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (f *fakeFactRepo) CreateContradicting(_ context.Context, fact domain.Fact, contradicted []uuid.UUID, at time.Time) error {
+	index := make(map[uuid.UUID]int, len(f.facts))
+	for i := range f.facts {
+		index[f.facts[i].ID] = i
+	}
+	for _, id := range contradicted {
+		if _, ok := index[id]; !ok {
+			return errors.New("not found")
+		}
+	}
+	for _, id := range contradicted {
+		by := fact.ID
+		f.facts[index[id]].ContradictedBy = &by
+		f.facts[index[id]].UpdatedAt = at
+	}
+	f.facts = append(f.facts, fact)
+	return nil
+}
+
+func TestFactService_RecordFactsReinforcesAndContradicts(t *testing.T) {
+	profileID := uuid.New()
+	repo := &fakeFactRepo{}
+	svc := NewFactService(repo, nil)
+
+	res, err := svc.RecordFacts(context.Background(), profileID, []domain.ExtractedFact{
+		{Subject: "usuario", Predicate: "tiene un perro llamado", Object: "Toby", Confidence: 0.5},
+		{Subject: "Usuario", Predicate: "vive en", Object: "Madrid"},
+		{Subject: "usuario", Predicate: "vive en", Object: "madrid"}, // repetido en el mismo lote
+		{Subject: "usuario", Predicate: "", Object: "nada"},
+	}, FactSource{MessageID: "msg-1"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Created != 2 || res.Reinforced != 0 || res.Contradicted != 0 {
+		t.Fatalf("unexpected first result: %+v", res)
+	}
+	if repo.facts[0].SourceMessageID == nil || *repo.facts[0].SourceMessageID != "msg-1" || repo.facts[0].SourceSessionID != nil {
+		t.Fatalf("expected message provenance, got %+v", repo.facts[0])
+	}
+	if repo.facts[1].Confidence != defaultFactConfidence {
+		t.Fatalf("expected default confidence, got %v", repo.facts[1].Confidence)
+	}
+
+	res, err = svc.RecordFacts(context.Background(), profileID, []domain.ExtractedFact{
+		{Subject: "usuario", Predicate: "tiene un perro llamado", Object: " toby ", Confidence: 0.5},
+		{Subject: "usuario", Predicate: "vive en", Object: "Rosario", Confidence: 0.9, SingleValued: true},
+	}, FactSource{SessionID: "session-1"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Created != 1 || res.Reinforced != 1 || res.Contradicted != 1 {
+		t.Fatalf("unexpected second result: %+v", res)
+	}
+	if repo.facts[0].Confidence != 0.75 {
+		t.Fatalf("expected reinforced confidence 0.75, got %v", repo.facts[0].Confidence)
+	}
+	if repo.facts[1].ContradictedBy == nil || *repo.facts[1].ContradictedBy != repo.facts[2].ID {
+		t.Fatalf("expected Madrid to be contradicted by Rosario, got %+v", repo.facts[1])
+	}
+
+	ctxText, err := svc.BuildFactsContext(context.Background(), profileID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ctxText != "- usuario tiene un perro llamado Toby\n- usuario vive en Rosario" {
+		t.Fatalf("unexpected facts context: %q", ctxText)
+	}
+}
+
+func TestFactService_MultiValuedPredicatesAccumulate(t *testing.T) {
+	profileID := uuid.New()
+	repo := &fakeFactRepo{}
+	svc := NewFactService(repo, nil)
+
+	for _, dog := range []string{"Toby", "Luna"} {
+		if _, err := svc.RecordFacts(context.Background(), profileID, []domain.ExtractedFact{
+			{Subject: "usuario", Predicate: "tiene un perro llamado", Object: dog, Confidence: 0.8},
+		}, FactSource{}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if len(repo.facts) != 2 || repo.facts[0].ContradictedBy != nil {
+		t.Fatalf("a second dog must not contradict the first, got %+v", repo.facts)
+	}
+}
+
+func TestFactService_NotConfigured(t *testing.T) {
+	var svc *FactService
+	if _, err := svc.RecordFacts(context.Background(), uuid.New(), nil, FactSource{}); !errors.Is(err, ErrFactServiceNotConfigured) {
+		t.Fatalf("expected ErrFactServiceNotConfigured, got %v", err)
+	}
+	if _, err := svc.BuildFactsContext(context.Background(), uuid.New()); !errors.Is(err, ErrFactServiceNotConfigured) {
+		t.Fatalf("expected ErrFactServiceNotConfigured, got %v", err)
+	}
+}
+
+func TestBuildClonePromptWithFacts_InjectsKnownFacts(t *testing.T) {
+	builder := ClonePromptBuilder{}
+	prompt := builder.BuildClonePromptWithFacts(&domain.CloneProfile{Name: "X"}, nil, "", "", "- usuario tiene un perro llamado Toby", "hola", false)
+	if !strings.Contains(prompt, "[HECHOS CONOCIDOS]") || !strings.Contains(prompt, "- usuario tiene un perro llamado Toby") {
+		t.Fatalf("expected known facts section, got %q", prompt)
+	}
+	if strings.Index(prompt, "[HECHOS CONOCIDOS]") > strings.Index(prompt, "MENSAJE DEL USUARIO") {
+		t.Fatalf("expected facts before the user message")
+	}
+
+	if prompt := builder.BuildClonePrompt(&domain.CloneProfile{Name: "X"}, nil, "", "", "hola", false); strings.Contains(prompt, "HECHOS CONOCIDOS") {
+		t.Fatalf("expected no facts section without facts")
+	}
+}
@@ -25,6 +25,7 @@ var (
 
 // ParseLLMResponseSafe intenta parsear la respuesta del LLM como JSON de manera robusta.
 // Regla: nunca devolvemos inner_monologue en fallback.
+// Los deltas de vinculo y new_state solo se devuelven cuando el JSON parsea completo.
 func (LLMResponseParser) ParseLLMResponseSafe(raw string) (domain.LLMResponse, bool) {
 	cleaned := CleanLLMJSONResponse(raw)
 
@@ -52,10 +53,21 @@ func (LLMResponseParser) ParseLLMResponseSafe(raw string) (domain.LLMResponse, b
 
 		pub = UnescapeMaybeDoubleEscaped(pub)
 
-		return domain.LLMResponse{
+		resp := domain.LLMResponse{
 			PublicResponse: pub,
 			InnerMonologue: "",
-		}, true
+			NewState:       strings.TrimSpace(tmp.NewState),
+		}
+		if tmp.TrustDelta != nil {
+			resp.TrustDelta = *tmp.TrustDelta
+		}
+		if tmp.IntimacyDelta != nil {
+			resp.IntimacyDelta = *tmp.IntimacyDelta
+		}
+		if tmp.RespectDelta != nil {
+			resp.RespectDelta = *tmp.RespectDelta
+		}
+		return resp, true
 	}
 
 	if jsonObj != "" {
@@ -77,6 +89,11 @@ func (LLMResponseParser) ParseLLMResponseSafe(raw string) (domain.LLMResponse, b
 		return domain.LLMResponse{PublicResponse: pr}, true
 	}
 
+	// Respuesta cortada a mitad de public_response: rescatamos lo que alcanzo a llegar.
+	if pr := parseTruncatedPublicResponse(raw); pr != "" {
+		return domain.LLMResponse{PublicResponse: pr}, true
+	}
+
 	fallback := SanitizeFallbackPublicText(raw)
 	if strings.TrimSpace(fallback) == "" {
 		return domain.LLMResponse{}, false
@@ -209,3 +226,11 @@ func unescapeMinimalEscapes(s string) string {
 	)
 	return replacer.Replace(s)
 }
+
+// parseTruncatedPublicResponse pasa la respuesta por el parser incremental y devuelve el public_response
+// parcial. Los deltas se ignoran: solo valen cuando el JSON llega completo.
+func parseTruncatedPublicResponse(raw string) string {
+	p := NewLLMStreamParser()
+	p.Feed(raw)
+	return strings.TrimSpace(p.Result().PublicResponse)
+}
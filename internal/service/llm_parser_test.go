@@ -44,3 +44,21 @@ func TestJSONUnmarshalLLMResponse_SetsOutput(t *testing.T) {
 		t.Fatalf("expected redacted inner_monologue, got %q", out.InnerMonologue)
 	}
 }
+
+func TestParseLLMResponseSafe_KeepsRelationshipDeltas(t *testing.T) {
+	parser := LLMResponseParser{}
+	raw := `{"inner_monologue":"secreto","public_response":"hola","trust_delta":-3.5,"intimacy_delta":2,"respect_delta":0,"new_state":" dolido "}`
+	resp, ok := parser.ParseLLMResponseSafe(raw)
+	if !ok {
+		t.Fatalf("expected ok=true")
+	}
+	if resp.TrustDelta != -3.5 || resp.IntimacyDelta != 2 || resp.RespectDelta != 0 {
+		t.Fatalf("unexpected deltas: %+v", resp)
+	}
+	if resp.NewState != "dolido" {
+		t.Fatalf("expected trimmed new_state, got %q", resp.NewState)
+	}
+	if resp.InnerMonologue != "" {
+		t.Fatalf("expected redacted inner_monologue, got %q", resp.InnerMonologue)
+	}
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"clone-llm/internal/domain"
+)
+
+// LLMStreamEventType identifica el tipo de evento emitido por LLMStreamParser.
+type LLMStreamEventType int
+
+const (
+	// LLMStreamPublicDelta trae un fragmento nuevo de public_response.
+	LLMStreamPublicDelta LLMStreamEventType = iota + 1
+	// LLMStreamNumber trae un campo numerico completo (trust_delta, intimacy_delta, respect_delta).
+	LLMStreamNumber
+	// LLMStreamText trae un campo de texto permitido completo (new_state).
+	LLMStreamText
+)
+
+// LLMStreamEvent es un evento de campo detectado en la salida parcial del LLM.
+type LLMStreamEvent struct {
+	Type  LLMStreamEventType
+	Field string
+	Text  string
+	Value float64
+}
+
+var (
+	streamNumericFields = map[string]struct{}{
+		"trust_delta":    {},
+		"intimacy_delta": {},
+		"respect_delta":  {},
+	}
+	streamTextFields = map[string]struct{}{
+		"new_state": {},
+	}
+)
+
+type streamState int
+
+const (
+	streamScan streamState = iota
+	streamKey
+	streamString
+	streamNumber
+	streamDone
+)
+
+type streamStringMode int
+
+const (
+	streamSkip streamStringMode = iota
+	streamPublic
+	streamText
+)
+
+// LLMStreamParser es un tokenizer reanudable para la salida JSON del clon.
+// Se alimenta con fragmentos arbitrarios (cortes en medio de claves, escapes o runes UTF-8)
+// y emite eventos solo para campos de una lista blanca del objeto de primer nivel:
+// inner_monologue y cualquier otro campo se consumen sin exponerse nunca.
+// public_response se desescapa igual que ParseLLMResponseSafe (incluido el doble escape) y se recorta.
+type LLMStreamParser struct {
+	state     streamState
+	depth     int
+	topObject bool
+	expectKey bool
+	field     string // clave de primer nivel cuyo valor esta por llegar
+	awaiting  bool
+
+	mode          streamStringMode
+	escaped       bool
+	inHex         bool
+	hex           []byte
+	highSurrogate rune
+	buf           strings.Builder // clave, texto o numero en curso
+
+	publicSeen   bool
+	started      bool   // ya se emitio el primer caracter no blanco
+	pendingSlash bool   // barra de un posible doble escape
+	spaces       []byte // blancos retenidos hasta ver mas contenido
+	ready        []byte // bytes de public_response listos para emitir
+	carry        []byte // rune UTF-8 incompleto del fragmento anterior
+
+	events []LLMStreamEvent
+	public strings.Builder
+	result domain.LLMResponse
+}
+
+// NewLLMStreamParser crea un parser vacio listo para recibir fragmentos.
+func NewLLMStreamParser() *LLMStreamParser {
+	return &LLMStreamParser{}
+}
+
+// Feed consume un fragmento crudo y devuelve los eventos completados con el.
+func (p *LLMStreamParser) Feed(chunk string) []LLMStreamEvent {
+	p.events = nil
+	for i := 0; i < len(chunk); i++ {
+		p.step(chunk[i])
+	}
+	p.flushPublic(false)
+	return p.events
+}
+
+// Result devuelve lo acumulado hasta el momento. InnerMonologue queda siempre vacio.
+func (p *LLMStreamParser) Result() domain.LLMResponse {
+	resp := p.result
+	resp.PublicResponse = p.public.String()
+	resp.InnerMonologue = ""
+	return resp
+}
+
+// Complete indica si el objeto JSON de primer nivel ya cerro.
+func (p *LLMStreamParser) Complete() bool {
+	return p.state == streamDone
+}
+
+func (p *LLMStreamParser) step(b byte) {
+	switch p.state {
+	case streamDone:
+		return
+	case streamKey:
+		out, end := p.decode(b, nil)
+		p.buf.Write(out)
+		if end {
+			p.field = strings.ToLower(strings.TrimSpace(p.buf.String()))
+			p.awaiting = true
+			p.expectKey = false
+			p.state = streamScan
+		}
+	case streamString:
+		p.stepString(b)
+	case streamNumber:
+		if isNumberByte(b) {
+			p.buf.WriteByte(b)
+			return
+		}
+		p.finishNumber()
+		p.state = streamScan
+		p.scan(b)
+	default:
+		p.scan(b)
+	}
+}
+
+func (p *LLMStreamParser) scan(b byte) {
+	switch b {
+	case ' ', '\n', '\r', '\t', ':':
+		return
+	case '{', '[':
+		if p.depth == 0 {
+			if b != '{' {
+				// Solo nos interesa un objeto de primer nivel.
+				return
+			}
+			p.topObject = true
+		}
+		p.depth++
+		p.expectKey = b == '{' && p.depth == 1
+		p.awaiting = false
+	case '}', ']':
+		if p.depth == 0 {
+			return
+		}
+		p.depth--
+		p.awaiting = false
+		if p.depth == 0 && p.topObject {
+			p.state = streamDone
+		}
+	case ',':
+		if p.depth == 1 {
+			p.expectKey = true
+		}
+		p.awaiting = false
+	case '"':
+		p.startString()
+	default:
+		if p.depth == 1 && p.awaiting && isNumberByte(b) {
+			if _, ok := streamNumericFields[p.field]; ok {
+				p.buf.Reset()
+				p.buf.WriteByte(b)
+				p.state = streamNumber
+				p.awaiting = false
+				return
+			}
+		}
+		p.awaiting = false
+	}
+}
+
+func (p *LLMStreamParser) startString() {
+	switch {
+	case p.depth == 0:
+		// Prosa fuera del objeto: no hay strings que rastrear.
+		return
+	case p.depth == 1 && p.expectKey:
+		p.buf.Reset()
+		p.state = streamKey
+		return
+	}
+
+	p.mode = streamSkip
+	if p.depth == 1 && p.awaiting {
+		if p.field == "public_response" && !p.publicSeen {
+			p.mode = streamPublic
+			p.publicSeen = true
+		} else if _, ok := streamTextFields[p.field]; ok {
+			p.mode = streamText
+			p.buf.Reset()
+		}
+	}
+	p.awaiting = false
+	p.state = streamString
+}
+
+func (p *LLMStreamParser) stepString(b byte) {
+	var scratch [utf8.UTFMax]byte
+	out, end := p.decode(b, scratch[:0])
+	switch p.mode {
+	case streamPublic:
+		p.pushPublic(out)
+		if end {
+			p.flushPublic(true)
+		}
+	case streamText:
+		p.buf.Write(out)
+		if end {
+			text := strings.TrimSpace(p.buf.String())
+			p.result.NewState = text
+			p.events = append(p.events, LLMStreamEvent{Type: LLMStreamText, Field: p.field, Text: text})
+		}
+	}
+	if end {
+		p.state = streamScan
+	}
+}
+
+// decode aplica las reglas de escape de un string JSON a un byte y devuelve lo decodificado.
+// end es true cuando el byte es la comilla de cierre.
+func (p *LLMStreamParser) decode(b byte, out []byte) ([]byte, bool) {
+	if p.inHex {
+		p.hex = append(p.hex, b)
+		if len(p.hex) < 4 {
+			return out, false
+		}
+		p.inHex = false
+		v, err := strconv.ParseUint(string(p.hex), 16, 32)
+		p.hex = p.hex[:0]
+		if err != nil {
+			return utf8.AppendRune(out, utf8.RuneError), false
+		}
+		r := rune(v)
+		if utf16.IsSurrogate(r) {
+			if p.highSurrogate == 0 {
+				p.highSurrogate = r
+				return out, false
+			}
+			r = utf16.DecodeRune(p.highSurrogate, r)
+		}
+		p.highSurrogate = 0
+		return utf8.AppendRune(out, r), false
+	}
+	if p.escaped {
+		p.escaped = false
+		switch b {
+		case 'n':
+			return append(out, '\n'), false
+		case 't':
+			return append(out, '\t'), false
+		case 'r':
+			return append(out, '\r'), false
+		case 'b', 'f':
+			return out, false
+		case 'u':
+			p.inHex = true
+			return out, false
+		default:
+			return append(out, b), false
+		}
+	}
+	switch b {
+	case '\\':
+		p.escaped = true
+		return out, false
+	case '"':
+		p.highSurrogate = 0
+		return out, true
+	default:
+		return append(out, b), false
+	}
+}
+
+// pushPublic aplica el segundo nivel de escape (texto doble-escapado) y el recorte de blancos.
+func (p *LLMStreamParser) pushPublic(decoded []byte) {
+	for _, c := range decoded {
+		if p.pendingSlash {
+			p.pendingSlash = false
+			switch c {
+			case 'n':
+				p.pushTrimmed('\n')
+			case 't':
+				p.pushTrimmed('\t')
+			case 'r':
+				p.pushTrimmed('\r')
+			case '"', '\\':
+				p.pushTrimmed(c)
+			default:
+				p.pushTrimmed('\\')
+				p.pushTrimmed(c)
+			}
+			continue
+		}
+		if c == '\\' {
+			p.pendingSlash = true
+			continue
+		}
+		p.pushTrimmed(c)
+	}
+}
+
+func (p *LLMStreamParser) pushTrimmed(c byte) {
+	if isASCIISpace(c) {
+		if p.started {
+			p.spaces = append(p.spaces, c)
+		}
+		return
+	}
+	p.started = true
+	p.ready = append(p.ready, p.spaces...)
+	p.spaces = p.spaces[:0]
+	p.ready = append(p.ready, c)
+}
+
+// flushPublic emite los bytes listos como evento. Retiene un rune UTF-8 cortado salvo al cerrar el string,
+// donde tambien se descartan blancos finales y una barra huerfana.
+func (p *LLMStreamParser) flushPublic(final bool) {
+	out := p.ready
+	if len(p.carry) > 0 {
+		out = append(p.carry, out...)
+		p.carry = nil
+	}
+	p.ready = nil
+
+	cut := len(out)
+	for i := len(out) - 1; i >= 0 && i >= len(out)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(out[i]) {
+			if !utf8.FullRune(out[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	if cut < len(out) {
+		if !final {
+			p.carry = append([]byte(nil), out[cut:]...)
+		}
+		out = out[:cut]
+	}
+	if final {
+		p.spaces = nil
+		p.pendingSlash = false
+	}
+	if len(out) == 0 {
+		return
+	}
+
+	text := string(out)
+	p.public.WriteString(text)
+	p.events = append(p.events, LLMStreamEvent{Type: LLMStreamPublicDelta, Field: "public_response", Text: text})
+}
+
+func (p *LLMStreamParser) finishNumber() {
+	v, err := strconv.ParseFloat(p.buf.String(), 64)
+	if err != nil {
+		return
+	}
+	switch p.field {
+	case "trust_delta":
+		p.result.TrustDelta = v
+	case "intimacy_delta":
+		p.result.IntimacyDelta = v
+	case "respect_delta":
+		p.result.RespectDelta = v
+	}
+	p.events = append(p.events, LLMStreamEvent{Type: LLMStreamNumber, Field: p.field, Value: v})
+}
+
+func isNumberByte(b byte) bool {
+	return (b >= '0' && b <= '9') || b == '-' || b == '+' || b == '.' || b == 'e' || b == 'E'
+}
+
+func isASCIISpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\r' || b == '\t'
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// streamSeeds reutiliza los casos de llm_parser_test.go y clone_service_test.go.
+var streamSeeds = []string{
+	`{"inner_monologue":"x","public_response":"Dijo: \"hola\" y luego \\ fin","trust_delta":0,"intimacy_delta":0,"respect_delta":0}`,
+	`{"inner_monologue":"...","public_response":"Ah, \"amigos nuevos\", 隅eh? 隅Quiゼnes son?"}`,
+	`{"public_response":"Linea1\\nLinea2 con \\\\ ruta","trust_delta":0}`,
+	`{"public_response":"Ah, \"amigos nuevos}`,
+	`{"inner_monologue":"secreto","public_response":"hola","trust_delta":-3.5,"intimacy_delta":2,"respect_delta":0,"new_state":" dolido "}`,
+	"```json\n{\"inner_monologue\": \"no digas \\\"public_response\\\": nada\", \"public_response\": \"  café 😀 \\ud83d\\ude00  \"}\n```",
+	`"{\"inner_monologue\": \"secreto\", \"public_response\": \"hola\"}"`,
+}
+
+func feedStream(raw string, size int) (string, *LLMStreamParser) {
+	p := NewLLMStreamParser()
+	var out strings.Builder
+	if size <= 0 {
+		size = 1
+	}
+	for start := 0; start < len(raw); start += size {
+		end := start + size
+		if end > len(raw) {
+			end = len(raw)
+		}
+		for _, ev := range p.Feed(raw[start:end]) {
+			if ev.Type == LLMStreamPublicDelta {
+				out.WriteString(ev.Text)
+			}
+		}
+	}
+	return out.String(), p
+}
+
+func TestLLMStreamParser_MatchesSafeParserOnCompleteJSON(t *testing.T) {
+	for _, raw := range streamSeeds {
+		want, ok := DefaultLLMResponseParser.ParseLLMResponseSafe(raw)
+		if !ok || strings.Contains(raw, `"{\"`) {
+			continue
+		}
+		for _, size := range []int{1, 2, 5, len(raw)} {
+			got, _ := feedStream(raw, size)
+			if got != want.PublicResponse {
+				t.Fatalf("chunk %d of %q: got %q want %q", size, raw, got, want.PublicResponse)
+			}
+		}
+	}
+}
+
+func TestLLMStreamParser_EmitsCompletedFields(t *testing.T) {
+	raw := `{"inner_monologue":"me duele","public_response":"hola","trust_delta":-3.5,"intimacy_delta":2,"new_state":" dolido "}`
+	p := NewLLMStreamParser()
+	var numbers, texts []LLMStreamEvent
+	for i := 0; i < len(raw); i++ {
+		for _, ev := range p.Feed(raw[i : i+1]) {
+			switch ev.Type {
+			case LLMStreamNumber:
+				numbers = append(numbers, ev)
+			case LLMStreamText:
+				texts = append(texts, ev)
+			}
+		}
+	}
+	if len(numbers) != 2 || numbers[0].Field != "trust_delta" || numbers[0].Value != -3.5 || numbers[1].Value != 2 {
+		t.Fatalf("unexpected numeric events: %+v", numbers)
+	}
+	if len(texts) != 1 || texts[0].Text != "dolido" {
+		t.Fatalf("unexpected text events: %+v", texts)
+	}
+	if !p.Complete() {
+		t.Fatalf("expected parser to report complete object")
+	}
+	res := p.Result()
+	if res.PublicResponse != "hola" || res.TrustDelta != -3.5 || res.NewState != "dolido" || res.InnerMonologue != "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestLLMStreamParser_TruncatedNumberIsNotEmitted(t *testing.T) {
+	p := NewLLMStreamParser()
+	evs := p.Feed(`{"public_response":"hola","trust_delta":-1`)
+	for _, ev := range evs {
+		if ev.Type == LLMStreamNumber {
+			t.Fatalf("truncated number must not be emitted: %+v", ev)
+		}
+	}
+	if p.Complete() {
+		t.Fatalf("truncated object must not be complete")
+	}
+}
+
+func TestLLMStreamParser_IgnoresNestedAndRepeatedKeys(t *testing.T) {
+	raw := `{"meta": {"public_response": "secreto"}, "public_response": "visible", "public_response": "otra"}`
+	if got, _ := feedStream(raw, 3); got != "visible" {
+		t.Fatalf("expected only first top-level public_response, got %q", got)
+	}
+}
+
+func TestParseLLMResponseSafe_RescuesTruncatedPublicResponse(t *testing.T) {
+	raw := `{"inner_monologue":"estoy harto","public_response":"Mira, no es tan simple porque`
+	resp, ok := DefaultLLMResponseParser.ParseLLMResponseSafe(raw)
+	if !ok || resp.PublicResponse != "Mira, no es tan simple porque" {
+		t.Fatalf("expected truncated public response, got ok=%v %q", ok, resp.PublicResponse)
+	}
+}
+
+func FuzzLLMStreamParser_ChunkingInvariant(f *testing.F) {
+	for _, seed := range streamSeeds {
+		f.Add(seed, uint8(1))
+		f.Add(seed, uint8(7))
+	}
+	f.Fuzz(func(t *testing.T, raw string, size uint8) {
+		whole, _ := feedStream(raw, len(raw))
+		chunked, _ := feedStream(raw, int(size))
+		if whole != chunked {
+			t.Fatalf("chunking changed output: whole=%q chunked=%q", whole, chunked)
+		}
+		if utf8.ValidString(raw) && !utf8.ValidString(chunked) {
+			t.Fatalf("emitted invalid utf8 from valid input: %q", chunked)
+		}
+	})
+}
+
+func FuzzLLMStreamParser_NeverLeaksInnerMonologue(f *testing.F) {
+	f.Add("pensamiento \"oculto\"", "hola \\n amigo", 10, false)
+	f.Add("a\\\\b", "Ah, \"amigos nuevos\"", 0, true)
+	f.Add("", "", 3, false)
+	f.Fuzz(func(t *testing.T, inner, public string, cut int, doubleEscape bool) {
+		const marker = "SECRETO-INTERNO"
+		public = strings.ReplaceAll(public, marker, "")
+		payload, err := json.Marshal(map[string]any{
+			"inner_monologue": marker + inner + marker,
+			"public_response": public,
+			"trust_delta":     1,
+		})
+		if err != nil {
+			t.Skip()
+		}
+		raw := string(payload)
+		if doubleEscape {
+			quoted, _ := json.Marshal(raw)
+			raw = string(quoted)
+		}
+		full, _ := feedStream(raw, 1)
+		if cut < 0 {
+			cut = -cut
+		}
+		if len(raw) > 0 {
+			raw = raw[:cut%(len(raw)+1)]
+		}
+		truncated, _ := feedStream(raw, 2)
+
+		for _, got := range []string{full, truncated} {
+			if strings.Contains(got, marker) {
+				t.Fatalf("inner_monologue leaked: %q", got)
+			}
+		}
+		if !strings.HasPrefix(full, truncated) {
+			t.Fatalf("truncated output %q is not a prefix of full output %q", truncated, full)
+		}
+		if resp, _ := DefaultLLMResponseParser.ParseLLMResponseSafe(raw); strings.Contains(resp.PublicResponse, marker) {
+			t.Fatalf("safe parser leaked inner_monologue on truncated input: %q", resp.PublicResponse)
+		}
+	})
+}
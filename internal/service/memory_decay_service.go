@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/repository"
+)
+
+const (
+	defaultMemoryHalfLife         = 30 * 24 * time.Hour
+	defaultMemoryArchiveThreshold = 2
+	memoryDecayBatch              = 200
+	// traumaIntensityThreshold coincide con el umbral de trauma de shouldSkipTrauma (escala 0-100).
+	traumaIntensityThreshold = 60
+)
+
+var ErrMemoryDecayNotConfigured = errors.New("memory decay service not configured")
+
+// MemoryDecayConfig define cuan rapido se olvida cada categoria emocional.
+type MemoryDecayConfig struct {
+	// DefaultHalfLife aplica a las categorias sin vida media propia.
+	DefaultHalfLife time.Duration
+	// HalfLives por categoria emocional (clave en mayusculas, p.ej. "ALEGRIA").
+	HalfLives map[string]time.Duration
+	// ArchiveBelow archiva las memorias cuya importancia decaida queda por debajo de este valor.
+	ArchiveBelow int
+}
+
+// MemoryDecayResult resume una pasada del job.
+type MemoryDecayResult struct {
+	Scanned  int
+	Decayed  int
+	Archived int
+	// Skipped cuenta las memorias que cambiaron entre la lectura y la escritura; se reintentan en la proxima pasada.
+	Skipped int
+}
+
+// MemoryDecayService degrada importancia y peso emocional de las memorias con una vida media
+// por categoria y archiva las que quedan triviales. Las memorias nucleares (is_core) y las de
+// trauma (categoria negativa de alta intensidad) nunca decaen. Evocar una memoria la refresca:
+// la vida media corre desde la ultima evocacion y se alarga con cada una.
+type MemoryDecayService struct {
+	repo   repository.MemoryDecayRepository
+	cfg    MemoryDecayConfig
+	logger *zap.Logger
+	cache  NarrativeCache
+	now    func() time.Time
+}
+
+func NewMemoryDecayService(repo repository.MemoryDecayRepository, cfg MemoryDecayConfig, logger *zap.Logger) *MemoryDecayService {
+	if cfg.DefaultHalfLife <= 0 {
+		cfg.DefaultHalfLife = defaultMemoryHalfLife
+	}
+	if cfg.ArchiveBelow <= 0 {
+		cfg.ArchiveBelow = defaultMemoryArchiveThreshold
+	}
+	halfLives := make(map[string]time.Duration, len(cfg.HalfLives))
+	for category, hl := range cfg.HalfLives {
+		if hl > 0 {
+			halfLives[strings.ToUpper(strings.TrimSpace(category))] = hl
+		}
+	}
+	cfg.HalfLives = halfLives
+	return &MemoryDecayService{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// SetCache registra el cache narrativo a invalidar para los clones cuyas memorias cambiaron.
+func (s *MemoryDecayService) SetCache(cache NarrativeCache) { s.cache = cache }
+
+// RunOnce recorre todas las memorias activas y aplica el decaimiento acumulado hasta ahora.
+func (s *MemoryDecayService) RunOnce(ctx context.Context) (MemoryDecayResult, error) {
+	var result MemoryDecayResult
+	if s == nil || s.repo == nil {
+		return result, ErrMemoryDecayNotConfigured
+	}
+
+	now := s.now().UTC()
+	touched := make(map[uuid.UUID]struct{})
+	after := uuid.Nil
+	for {
+		candidates, err := s.repo.ListDecayCandidates(ctx, after, memoryDecayBatch)
+		if err != nil {
+			return result, fmt.Errorf("list decay candidates: %w", err)
+		}
+		for _, c := range candidates {
+			result.Scanned++
+			update, changed := s.decay(c, now)
+			if !changed {
+				continue
+			}
+			applied, err := s.repo.ApplyDecay(ctx, update)
+			if err != nil {
+				return result, fmt.Errorf("apply decay: %w", err)
+			}
+			if !applied {
+				result.Skipped++
+				continue
+			}
+			touched[c.CloneProfileID] = struct{}{}
+			if update.ArchivedAt != nil {
+				result.Archived++
+			} else {
+				result.Decayed++
+			}
+		}
+		if len(candidates) < memoryDecayBatch {
+			break
+		}
+		after = candidates[len(candidates)-1].ID
+	}
+
+	if s.cache != nil {
+		for profileID := range touched {
+			s.cache.InvalidateProfile(profileID)
+		}
+	}
+	return result, nil
+}
+
+// Run ejecuta RunOnce cada interval hasta que se cancele ctx.
+func (s *MemoryDecayService) Run(ctx context.Context, interval time.Duration) {
+	if s == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := s.RunOnce(ctx)
+			if err != nil && s.logger != nil {
+				s.logger.Warn("memory decay failed", zap.Error(err))
+			} else if res.Decayed+res.Archived > 0 && s.logger != nil {
+				s.logger.Info("memories decayed", zap.Int("scanned", res.Scanned), zap.Int("decayed", res.Decayed), zap.Int("archived", res.Archived), zap.Int("skipped", res.Skipped))
+			}
+		}
+	}
+}
+
+// decay calcula los pesos de la memoria a partir de su punto de partida:
+// valor = base * 0.5^(edad/vida_media), con minimo 1. Devuelve false si no hay nada que persistir.
+func (s *MemoryDecayService) decay(c repository.DecayCandidate, now time.Time) (repository.DecayUpdate, bool) {
+	if isTraumaCategory(c.EmotionCategory, c.EmotionalIntensity) {
+		return repository.DecayUpdate{}, false
+	}
+
+	baseImportance, baseWeight, anchor := c.Importance, c.EmotionalWeight, c.CreatedAt
+	if c.BaseImportance != nil {
+		baseImportance = *c.BaseImportance
+	}
+	if c.BaseWeight != nil {
+		baseWeight = *c.BaseWeight
+	}
+	if c.AnchorAt != nil {
+		anchor = *c.AnchorAt
+	}
+	if c.LastRecalledAt != nil && c.LastRecalledAt.After(anchor) {
+		// Recordar es reconsolidar: la memoria vuelve a su vividez de partida.
+		anchor = *c.LastRecalledAt
+	}
+
+	age := now.Sub(anchor)
+	if age <= 0 {
+		return repository.DecayUpdate{}, false
+	}
+	halfLife := s.halfLife(c.EmotionCategory).Hours() * recallHalfLifeMultiplier(c.RecallCount)
+	factor := math.Pow(0.5, age.Hours()/halfLife)
+
+	update := repository.DecayUpdate{
+		ID:              c.ID,
+		Importance:      decayedValue(baseImportance, factor),
+		EmotionalWeight: decayedValue(baseWeight, factor),
+		BaseImportance:  baseImportance,
+		BaseWeight:      baseWeight,
+		AnchorAt:        anchor,
+		PrevImportance:  c.Importance,
+		PrevAnchorAt:    c.AnchorAt,
+	}
+	// Solo se archiva lo que efectivamente se olvido: una memoria nacida trivial no desaparece al instante.
+	if update.Importance < s.cfg.ArchiveBelow && update.Importance < baseImportance {
+		archivedAt := now
+		update.ArchivedAt = &archivedAt
+		return update, true
+	}
+	return update, update.Importance != c.Importance || update.EmotionalWeight != c.EmotionalWeight
+}
+
+func (s *MemoryDecayService) halfLife(category string) time.Duration {
+	if hl, ok := s.cfg.HalfLives[strings.ToUpper(strings.TrimSpace(category))]; ok {
+		return hl
+	}
+	return s.cfg.DefaultHalfLife
+}
+
+// recallHalfLifeMultiplier alarga la vida media con las evocaciones: x2 con una, x3 con tres, x4 con siete.
+func recallHalfLifeMultiplier(recallCount int) float64 {
+	if recallCount <= 0 {
+		return 1
+	}
+	return 1 + math.Log2(1+float64(recallCount))
+}
+
+func decayedValue(base int, factor float64) int {
+	v := int(math.Round(float64(base) * factor))
+	if v < 1 {
+		return 1
+	}
+	return v
+}
+
+// isTraumaCategory marca como trauma las memorias negativas de alta intensidad.
+func isTraumaCategory(category string, intensity int) bool {
+	return isNegativeCategory(category) && normalizeIntensity(intensity) >= traumaIntensityThreshold
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/repository"
+)
+
+type fakeDecayRepo struct {
+	candidates []repository.DecayCandidate
+	updates    map[uuid.UUID]repository.DecayUpdate
+	// importance simula ediciones concurrentes: la importancia actual de la fila, si difiere de la leida.
+	importance map[uuid.UUID]int
+}
+
+func (f *fakeDecayRepo) ListDecayCandidates(_ context.Context, afterID uuid.UUID, limit int) ([]repository.DecayCandidate, error) {
+	var out []repository.DecayCandidate
+	for _, c := range f.candidates {
+		if afterID != uuid.Nil && c.ID.String() <= afterID.String() {
+			continue
+		}
+		out = append(out, c)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeDecayRepo) ApplyDecay(_ context.Context, update repository.DecayUpdate) (bool, error) {
+	if current, ok := f.importance[update.ID]; ok && current != update.PrevImportance {
+		return false, nil
+	}
+	if f.updates == nil {
+		f.updates = make(map[uuid.UUID]repository.DecayUpdate)
+	}
+	f.updates[update.ID] = update
+	return true, nil
+}
+
+func TestMemoryDecayService_RunOnce(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	profileID := uuid.New()
+	day := 24 * time.Hour
+
+	joy := repository.DecayCandidate{ID: uuid.New(), CloneProfileID: profileID, Importance: 8, EmotionalWeight: 6, EmotionCategory: "ALEGRIA", CreatedAt: now.Add(-20 * day)}
+	trivial := repository.DecayCandidate{ID: uuid.New(), CloneProfileID: profileID, Importance: 4, EmotionalWeight: 2, EmotionCategory: "NEUTRAL", CreatedAt: now.Add(-60 * day)}
+	trauma := repository.DecayCandidate{ID: uuid.New(), CloneProfileID: profileID, Importance: 9, EmotionalWeight: 9, EmotionalIntensity: 90, EmotionCategory: "MIEDO", CreatedAt: now.Add(-400 * day)}
+	fresh := repository.DecayCandidate{ID: uuid.New(), CloneProfileID: profileID, Importance: 1, EmotionalWeight: 1, EmotionCategory: "NEUTRAL", CreatedAt: now.Add(-time.Hour)}
+	base := 8
+	anchor := now.Add(-10 * day)
+	decayed := repository.DecayCandidate{ID: uuid.New(), CloneProfileID: profileID, Importance: 6, EmotionalWeight: 6, EmotionCategory: "ALEGRIA", BaseImportance: &base, BaseWeight: &base, AnchorAt: &anchor, CreatedAt: now.Add(-300 * day)}
+
+	repo := &fakeDecayRepo{candidates: []repository.DecayCandidate{joy, trivial, trauma, fresh, decayed}}
+	svc := NewMemoryDecayService(repo, MemoryDecayConfig{
+		DefaultHalfLife: 30 * day,
+		HalfLives:       map[string]time.Duration{"neutral": 14 * day, "Alegria": 20 * day},
+	}, nil)
+	svc.now = func() time.Time { return now }
+	cache := NewMemoryNarrativeCache(10, time.Hour)
+	cache.SetEvocation(evocationCacheKey(profileID, "hola"), "x")
+	svc.SetCache(cache)
+
+	res, err := svc.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Scanned != 5 || res.Decayed != 1 || res.Archived != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	// Una vida media de alegria: 8 -> 4, 6 -> 3, partiendo de created_at.
+	if u := repo.updates[joy.ID]; u.Importance != 4 || u.EmotionalWeight != 3 || u.BaseImportance != 8 || !u.AnchorAt.Equal(joy.CreatedAt) || u.ArchivedAt != nil {
+		t.Fatalf("unexpected joy update: %+v", u)
+	}
+	if u := repo.updates[trivial.ID]; u.ArchivedAt == nil {
+		t.Fatalf("expected trivial memory to be archived, got %+v", u)
+	}
+	if _, ok := repo.updates[trauma.ID]; ok {
+		t.Fatalf("trauma memories must never decay")
+	}
+	if _, ok := repo.updates[fresh.ID]; ok {
+		t.Fatalf("memories born trivial must not be archived right away")
+	}
+	// Desde el ancla previa: 8 * 0.5^(10/20) = 5.66 -> 6, igual a lo guardado.
+	if u, ok := repo.updates[decayed.ID]; ok {
+		t.Fatalf("expected no write when values are unchanged, got %+v", u)
+	}
+	if _, ok := cache.GetEvocation(evocationCacheKey(profileID, "hola")); ok {
+		t.Fatalf("expected profile cache to be invalidated")
+	}
+}
+
+func TestMemoryDecayService_SkipsConcurrentlyEditedMemories(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	edited := repository.DecayCandidate{ID: uuid.New(), CloneProfileID: uuid.New(), Importance: 8, EmotionalWeight: 6, EmotionCategory: "ALEGRIA", CreatedAt: now.Add(-60 * day)}
+
+	// Entre la lectura y la escritura alguien subio la importancia a 9 (edicion o fusion de un duplicado).
+	repo := &fakeDecayRepo{candidates: []repository.DecayCandidate{edited}, importance: map[uuid.UUID]int{edited.ID: 9}}
+	svc := NewMemoryDecayService(repo, MemoryDecayConfig{DefaultHalfLife: 30 * day}, nil)
+	svc.now = func() time.Time { return now }
+
+	res, err := svc.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Skipped != 1 || res.Decayed != 0 || res.Archived != 0 {
+		t.Fatalf("expected the edited memory to be skipped, got %+v", res)
+	}
+	if len(repo.updates) != 0 {
+		t.Fatalf("expected no write over the concurrent edit, got %+v", repo.updates)
+	}
+}
+
+func TestMemoryDecayService_NotConfigured(t *testing.T) {
+	var svc *MemoryDecayService
+	if _, err := svc.RunOnce(context.Background()); err != ErrMemoryDecayNotConfigured {
+		t.Fatalf("expected ErrMemoryDecayNotConfigured, got %v", err)
+	}
+}
+
+func TestMemoryDecayService_RecallKeepsMemoriesVivid(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	recalledAt := now.Add(-2 * day)
+	base, anchor := 8, now.Add(-90*day)
+
+	// Decaida a 2 hace tiempo, pero evocada hace dos dias: vuelve casi a su valor de partida.
+	recalled := repository.DecayCandidate{ID: uuid.New(), CloneProfileID: uuid.New(), Importance: 2, EmotionalWeight: 2, EmotionCategory: "NEUTRAL",
+		BaseImportance: &base, BaseWeight: &base, AnchorAt: &anchor, RecallCount: 3, LastRecalledAt: &recalledAt, CreatedAt: anchor}
+	// Misma edad sin evocaciones: se archiva.
+	forgotten := repository.DecayCandidate{ID: uuid.New(), CloneProfileID: uuid.New(), Importance: 8, EmotionalWeight: 8, EmotionCategory: "NEUTRAL", CreatedAt: anchor}
+
+	repo := &fakeDecayRepo{candidates: []repository.DecayCandidate{recalled, forgotten}}
+	svc := NewMemoryDecayService(repo, MemoryDecayConfig{DefaultHalfLife: 14 * day}, nil)
+	svc.now = func() time.Time { return now }
+
+	if _, err := svc.RunOnce(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if u := repo.updates[recalled.ID]; u.Importance != 8 || u.ArchivedAt != nil || !u.AnchorAt.Equal(recalledAt) {
+		t.Fatalf("expected recalled memory to be refreshed, got %+v", u)
+	}
+	if u := repo.updates[forgotten.ID]; u.ArchivedAt == nil {
+		t.Fatalf("expected unrecalled memory to be archived, got %+v", u)
+	}
+	if recallHalfLifeMultiplier(0) != 1 || recallHalfLifeMultiplier(3) != 3 {
+		t.Fatalf("unexpected half-life multipliers")
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// foldAccents pliega las letras acentuadas mas comunes del espanol (y vecinas) a su base ASCII.
+var foldAccents = map[rune]rune{
+	'á': 'a', 'à': 'a', 'ä': 'a', 'â': 'a', 'ã': 'a',
+	'é': 'e', 'è': 'e', 'ë': 'e', 'ê': 'e',
+	'í': 'i', 'ì': 'i', 'ï': 'i', 'î': 'i',
+	'ó': 'o', 'ò': 'o', 'ö': 'o', 'ô': 'o', 'õ': 'o',
+	'ú': 'u', 'ù': 'u', 'ü': 'u', 'û': 'u',
+	'ñ': 'n', 'ç': 'c',
+}
+
+// diminutiveSuffixes son los sufijos con los que se forman diminutivos de nombres ("Anita", "Juancito").
+// -illo/-illa quedan fuera a proposito: "Ana" terminaria detectada en "anillo".
+var diminutiveSuffixes = []string{"ito", "ita", "itos", "itas", "cito", "cita", "ecito", "ecita"}
+
+// minDiminutiveBase evita que nombres muy cortos ("Al", "Bo") generen diminutivos ambiguos.
+const minDiminutiveBase = 3
+
+// minDiminutiveStem es el largo minimo de la raiz cuando el sufijo se pega al nombre entero:
+// "Juan" -> "Juanito" vale, pero "Sol" no debe detectarse en "solito" ni "Luz" en "luzcita".
+// Las raices que salen de quitar la vocal final ("Ana" -> "an" + "ita") no tienen ese minimo.
+const minDiminutiveStem = 4
+
+// NormalizeCharacterName devuelve la forma plegada de un nombre o apodo:
+// minusculas, sin tildes y con las palabras separadas por un unico espacio.
+func NormalizeCharacterName(name string) string {
+	return strings.Join(nameTokens(name), " ")
+}
+
+// nameTokens pliega el texto y lo corta en palabras (letras y digitos); el resto actua como separador.
+func nameTokens(text string) []string {
+	var (
+		tokens []string
+		b      strings.Builder
+	)
+	flush := func() {
+		if b.Len() > 0 {
+			tokens = append(tokens, b.String())
+			b.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		if folded, ok := foldAccents[r]; ok {
+			r = folded
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			continue
+		}
+		flush()
+	}
+	flush()
+	return tokens
+}
+
+// mentionsName indica si la secuencia de palabras de name aparece completa en msgTokens.
+// La ultima palabra del nombre tambien acepta su diminutivo ("mama" -> "mamita").
+func mentionsName(msgTokens []string, name string) bool {
+	nameToks := nameTokens(name)
+	if len(nameToks) == 0 || len(nameToks) > len(msgTokens) {
+		return false
+	}
+	for start := 0; start+len(nameToks) <= len(msgTokens); start++ {
+		matched := true
+		for i, nt := range nameToks {
+			mt := msgTokens[start+i]
+			if mt == nt {
+				continue
+			}
+			if i == len(nameToks)-1 && isDiminutiveOf(mt, nt) {
+				continue
+			}
+			matched = false
+			break
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isDiminutiveOf reconoce diminutivos regulares: Ana->Anita, Juan->Juanito/Juancito, Carlos->Carlitos, Mama->Mamita.
+func isDiminutiveOf(word, name string) bool {
+	if len(name) < minDiminutiveBase || len(word) <= len(name) {
+		return false
+	}
+	var bases []string
+	if len(name) >= minDiminutiveStem {
+		bases = append(bases, name)
+	}
+	if last := name[len(name)-1]; last == 'a' || last == 'o' || last == 'e' {
+		bases = append(bases, name[:len(name)-1])
+	}
+	if strings.HasSuffix(name, "os") || strings.HasSuffix(name, "as") {
+		bases = append(bases, name[:len(name)-2])
+	}
+	for _, base := range bases {
+		if len(base) < minDiminutiveBase-1 || !strings.HasPrefix(word, base) {
+			continue
+		}
+		suffix := word[len(base):]
+		for _, s := range diminutiveSuffixes {
+			if suffix == s {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/domain"
+)
+
+func TestDetectActiveCharacters_FuzzyNames(t *testing.T) {
+	mama := domain.Character{ID: uuid.New(), Name: "Mamá", Aliases: []string{"mi vieja"}}
+	ana := domain.Character{ID: uuid.New(), Name: "Ana"}
+	juan := domain.Character{ID: uuid.New(), Name: "Juan Pablo", Aliases: []string{"JP"}}
+	carlos := domain.Character{ID: uuid.New(), Name: "Carlos"}
+	sol := domain.Character{ID: uuid.New(), Name: "Sol"}
+	ruben := domain.Character{ID: uuid.New(), Name: "Rubén"}
+	chars := []domain.Character{mama, ana, juan, carlos, sol, ruben}
+
+	cases := []struct {
+		msg  string
+		want []uuid.UUID
+	}{
+		{"hoy llamé a mama", []uuid.UUID{mama.ID}},
+		{"MAMÁ me retó", []uuid.UUID{mama.ID}},
+		{"mi vieja cocinó", []uuid.UUID{mama.ID}},
+		{"mamita querida", []uuid.UUID{mama.ID}},
+		{"compré una banana", nil},
+		{"le regalé un anillo", nil},
+		{"Anita, ¿vienes?", []uuid.UUID{ana.ID}},
+		{"vi a Juan ayer", nil},
+		{"vi a juan pablito ayer", []uuid.UUID{juan.ID}},
+		{"jp no contesta", []uuid.UUID{juan.ID}},
+		{"carlitos y ana", []uuid.UUID{ana.ID, carlos.ID}},
+		{"me quedé solito en casa", nil},
+		{"le di un besito a Sol", []uuid.UUID{sol.ID}},
+		{"rubencito no vino", []uuid.UUID{ruben.ID}},
+		{"rubito", nil},
+		{"", nil},
+	}
+	for _, tc := range cases {
+		got := detectActiveCharacters(chars, tc.msg)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%q: expected %d matches, got %+v", tc.msg, len(tc.want), got)
+		}
+		for i := range got {
+			if got[i].ID != tc.want[i] {
+				t.Fatalf("%q: unexpected match %q", tc.msg, got[i].Name)
+			}
+		}
+	}
+}
+
+func TestNormalizeCharacterName(t *testing.T) {
+	if got := NormalizeCharacterName("  Mi  Viéja,  Ñoña "); got != "mi vieja nona" {
+		t.Fatalf("unexpected normalized name %q", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	pgvector "github.com/pgvector/pgvector-go"
 
 	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
 )
 
 func (s *NarrativeService) CreateRelation(
@@ -53,9 +54,12 @@ func (s *NarrativeService) CreateRelation(
 	return s.characterRepo.Create(ctx, char)
 }
 
+// InjectMemory guarda un recuerdo del clon. relatedCharacterID (opcional) lo liga al personaje
+// del que trata, para recuperarlo con ListByCharacter y priorizarlo cuando ese personaje esta activo.
 func (s *NarrativeService) InjectMemory(
 	ctx context.Context,
 	profileID uuid.UUID,
+	relatedCharacterID *uuid.UUID,
 	content string,
 	importance, emotionalWeight, emotionalIntensity int,
 	emotionCategory string,
@@ -105,13 +109,20 @@ func (s *NarrativeService) InjectMemory(
 	}
 
 	now := time.Now().UTC()
+	vec := pgvector.NewVector(embed)
+	if merged, err := s.mergeDuplicate(ctx, profileID, relatedCharacterID, vec, importance, emotionalWeight, emotionalIntensity, category, now); err != nil {
+		return err
+	} else if merged {
+		return nil
+	}
+
 	mem := domain.NarrativeMemory{
 		ID:                 uuid.New(),
 		CloneProfileID:     profileID,
-		RelatedCharacterID: nil, // TODO: permitir asociarlo cuando haya parsing de interlocutor
+		RelatedCharacterID: relatedCharacterID,
 
 		Content:   text,
-		Embedding: pgvector.NewVector(embed),
+		Embedding: vec,
 
 		Importance:         importance,
 		EmotionalWeight:    emotionalWeight,
@@ -125,3 +136,120 @@ func (s *NarrativeService) InjectMemory(
 	}
 	return s.memoryRepo.Create(ctx, mem)
 }
+
+const (
+	// defaultMemoryDedupThreshold: por encima de esta similitud dos recuerdos se consideran el mismo.
+	defaultMemoryDedupThreshold = 0.92
+	// Refuerzo por repeticion al fusionar un casi-duplicado.
+	dedupImportanceBoost = 1
+	dedupIntensityBoost  = 10
+)
+
+// mergeDuplicate busca la memoria activa mas parecida del clon (ligada al mismo personaje) y, si supera
+// dedupThreshold, la refuerza con el recuerdo nuevo en lugar de duplicarla: toma el maximo de cada peso,
+// suma un refuerzo por repeticion y cuenta la ocurrencia. Devuelve true si hubo fusion.
+// Un fallo al buscar no pierde el recuerdo: se crea como nuevo.
+func (s *NarrativeService) mergeDuplicate(
+	ctx context.Context,
+	profileID uuid.UUID,
+	relatedCharacterID *uuid.UUID,
+	vec pgvector.Vector,
+	importance, emotionalWeight, emotionalIntensity int,
+	category string,
+	now time.Time,
+) (bool, error) {
+	if s.dedupThreshold <= 0 {
+		return false, nil
+	}
+	nearest, err := s.memoryRepo.FindNearest(ctx, profileID, relatedCharacterID, vec)
+	if err != nil || nearest == nil || nearest.Similarity < s.dedupThreshold {
+		return false, nil
+	}
+
+	mem := nearest.NarrativeMemory
+	mem.Importance = min(max(mem.Importance, importance)+dedupImportanceBoost, 10)
+	mem.EmotionalWeight = max(mem.EmotionalWeight, emotionalWeight)
+	mem.EmotionalIntensity = min(max(normalizeIntensity(mem.EmotionalIntensity), normalizeIntensity(emotionalIntensity))+dedupIntensityBoost, 100)
+	if strings.EqualFold(strings.TrimSpace(mem.EmotionCategory), "NEUTRAL") || strings.TrimSpace(mem.EmotionCategory) == "" {
+		mem.EmotionCategory = category
+		mem.SentimentLabel = category
+	}
+	mem.HappenedAt = now
+	mem.UpdatedAt = now
+
+	if err := s.memoryRepo.MergeOccurrence(ctx, mem); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// maxMemorySearchResults acota el k pedido desde la API de inspeccion.
+const maxMemorySearchResults = 50
+
+// SearchMemories busca memorias del clon por similitud con query, con el mismo ranking que usa
+// BuildNarrativeContext (similitud + refuerzo emocional - olvido), pero sin evocacion ni juez:
+// sirve para depurar que devuelve la recuperacion cruda.
+func (s *NarrativeService) SearchMemories(ctx context.Context, profileID uuid.UUID, query string, k int) ([]repository.ScoredMemory, error) {
+	if s == nil || s.memoryRepo == nil || s.llmClient == nil {
+		return nil, ErrNarrativeServiceNotConfigured
+	}
+	query = strings.TrimSpace(query)
+	if profileID == uuid.Nil || query == "" {
+		return nil, ErrNarrativeInvalidInput
+	}
+	if k <= 0 {
+		k = 5
+	}
+	if k > maxMemorySearchResults {
+		k = maxMemorySearchResults
+	}
+
+	embed, err := s.llmClient.CreateEmbedding(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return s.memoryRepo.Search(ctx, profileID, pgvector.NewVector(embed), k, defaultEmotionalWeightFactor)
+}
+
+// UpdateMemory persiste una memoria editada. Si el contenido cambio (reembed) recalcula el embedding
+// para que la busqueda refleje el texto nuevo.
+func (s *NarrativeService) UpdateMemory(ctx context.Context, memory domain.NarrativeMemory, reembed bool) (domain.NarrativeMemory, error) {
+	if s == nil || s.memoryRepo == nil || s.llmClient == nil {
+		return domain.NarrativeMemory{}, ErrNarrativeServiceNotConfigured
+	}
+	memory.Content = strings.TrimSpace(memory.Content)
+	if memory.ID == uuid.Nil || memory.Content == "" {
+		return domain.NarrativeMemory{}, ErrNarrativeInvalidInput
+	}
+
+	if reembed {
+		embed, err := s.llmClient.CreateEmbedding(ctx, memory.Content)
+		if err != nil {
+			return domain.NarrativeMemory{}, err
+		}
+		memory.Embedding = pgvector.NewVector(embed)
+		memory.EmbeddingModel = "" // el repositorio guarda el modelo configurado
+	}
+	memory.UpdatedAt = time.Now().UTC()
+
+	if err := s.memoryRepo.Update(ctx, memory); err != nil {
+		return domain.NarrativeMemory{}, err
+	}
+	s.invalidateCache(memory.CloneProfileID)
+	return memory, nil
+}
+
+// DeleteMemory borra una memoria del clon e invalida lo cacheado para el.
+func (s *NarrativeService) DeleteMemory(ctx context.Context, profileID, memoryID uuid.UUID) error {
+	if s == nil || s.memoryRepo == nil {
+		return ErrNarrativeServiceNotConfigured
+	}
+	if profileID == uuid.Nil || memoryID == uuid.Nil {
+		return ErrNarrativeInvalidInput
+	}
+	if err := s.memoryRepo.Delete(ctx, memoryID); err != nil {
+		return err
+	}
+	s.invalidateCache(profileID)
+	return nil
+}
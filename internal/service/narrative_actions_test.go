@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	pgvector "github.com/pgvector/pgvector-go"
@@ -29,6 +30,10 @@ func (f *actionFakeCharacterRepo) Update(context.Context, domain.Character) erro
 	return nil
 }
 
+func (f *actionFakeCharacterRepo) GetByID(context.Context, uuid.UUID) (*domain.Character, error) {
+	return nil, nil
+}
+
 func (f *actionFakeCharacterRepo) ListByProfileID(context.Context, uuid.UUID) ([]domain.Character, error) {
 	return nil, nil
 }
@@ -37,8 +42,15 @@ func (f *actionFakeCharacterRepo) FindByName(context.Context, uuid.UUID, string)
 	return nil, nil
 }
 
+func (f *actionFakeCharacterRepo) Delete(context.Context, uuid.UUID) error {
+	return nil
+}
+
 type actionFakeMemoryRepo struct {
 	created domain.NarrativeMemory
+	updated domain.NarrativeMemory
+	merged  *domain.NarrativeMemory
+	nearest *repository.ScoredMemory
 	err     error
 }
 
@@ -54,7 +66,7 @@ func (f *actionFakeMemoryRepo) Search(context.Context, uuid.UUID, pgvector.Vecto
 	return nil, nil
 }
 
-func (f *actionFakeMemoryRepo) ListByCharacter(context.Context, uuid.UUID) ([]domain.NarrativeMemory, error) {
+func (f *actionFakeMemoryRepo) ListByCharacter(context.Context, uuid.UUID, int) ([]domain.NarrativeMemory, error) {
 	return nil, nil
 }
 
@@ -62,6 +74,46 @@ func (f *actionFakeMemoryRepo) GetRecentHighImpactByProfile(context.Context, uui
 	return nil, nil
 }
 
+func (f *actionFakeMemoryRepo) ListBySourceSession(context.Context, string) ([]domain.NarrativeMemory, error) {
+	return nil, nil
+}
+
+func (f *actionFakeMemoryRepo) List(context.Context, uuid.UUID, repository.MemoryFilter) ([]domain.NarrativeMemory, error) {
+	return nil, nil
+}
+
+func (f *actionFakeMemoryRepo) GetByID(context.Context, uuid.UUID) (*domain.NarrativeMemory, error) {
+	return nil, nil
+}
+
+func (f *actionFakeMemoryRepo) Update(_ context.Context, m domain.NarrativeMemory) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.updated = m
+	return nil
+}
+
+func (f *actionFakeMemoryRepo) Delete(context.Context, uuid.UUID) error {
+	return nil
+}
+
+func (f *actionFakeMemoryRepo) MarkRecalled(context.Context, []uuid.UUID, time.Time) error {
+	return nil
+}
+
+func (f *actionFakeMemoryRepo) FindNearest(context.Context, uuid.UUID, *uuid.UUID, pgvector.Vector) (*repository.ScoredMemory, error) {
+	return f.nearest, nil
+}
+
+func (f *actionFakeMemoryRepo) MergeOccurrence(_ context.Context, m domain.NarrativeMemory) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.merged = &m
+	return nil
+}
+
 type actionFakeLLM struct {
 	embedding []float32
 	err       error
@@ -76,6 +128,10 @@ func (f actionFakeLLM) CreateEmbedding(context.Context, string) ([]float32, erro
 
 func (f actionFakeLLM) Generate(context.Context, string) (string, error) { return "", nil }
 
+func (f actionFakeLLM) GenerateStream(context.Context, string, func(string) error) (string, error) {
+	return "", nil
+}
+
 func TestCreateRelation_ValidationAndPersist(t *testing.T) {
 	profileID := uuid.New()
 	charRepo := &actionFakeCharacterRepo{}
@@ -118,7 +174,7 @@ func TestInjectMemory_ValidationAndClamps(t *testing.T) {
 		llmClient:  actionFakeLLM{embedding: []float32{1, 2, 3}},
 	}
 
-	if err := svc.InjectMemory(context.Background(), profileID, "  evento  ", -2, 99, 140, " "); err != nil {
+	if err := svc.InjectMemory(context.Background(), profileID, nil, "  evento  ", -2, 99, 140, " "); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -136,16 +192,84 @@ func TestInjectMemory_ValidationAndClamps(t *testing.T) {
 
 func TestInjectMemory_NotConfiguredInvalidOrEmptyInput(t *testing.T) {
 	var svc *NarrativeService
-	if err := svc.InjectMemory(context.Background(), uuid.New(), "x", 1, 1, 1, "IRA"); !errors.Is(err, ErrNarrativeServiceNotConfigured) {
+	if err := svc.InjectMemory(context.Background(), uuid.New(), nil, "x", 1, 1, 1, "IRA"); !errors.Is(err, ErrNarrativeServiceNotConfigured) {
 		t.Fatalf("expected ErrNarrativeServiceNotConfigured, got %v", err)
 	}
 
 	svc = &NarrativeService{memoryRepo: &actionFakeMemoryRepo{}, llmClient: actionFakeLLM{embedding: []float32{1}}}
-	if err := svc.InjectMemory(context.Background(), uuid.Nil, "x", 1, 1, 1, "IRA"); !errors.Is(err, ErrNarrativeInvalidInput) {
+	if err := svc.InjectMemory(context.Background(), uuid.Nil, nil, "x", 1, 1, 1, "IRA"); !errors.Is(err, ErrNarrativeInvalidInput) {
 		t.Fatalf("expected ErrNarrativeInvalidInput, got %v", err)
 	}
 
-	if err := svc.InjectMemory(context.Background(), uuid.New(), "   ", 1, 1, 1, "IRA"); err != nil {
+	if err := svc.InjectMemory(context.Background(), uuid.New(), nil, "   ", 1, 1, 1, "IRA"); err != nil {
 		t.Fatalf("expected no-op nil error for empty content, got %v", err)
 	}
 }
+
+func TestInjectMemory_MergesNearDuplicates(t *testing.T) {
+	profileID := uuid.New()
+	existing := domain.NarrativeMemory{ID: uuid.New(), CloneProfileID: profileID, Content: "me dejaste plantado", Importance: 6, EmotionalWeight: 7, EmotionalIntensity: 5, EmotionCategory: "NEUTRAL", OccurrenceCount: 1}
+	memRepo := &actionFakeMemoryRepo{nearest: &repository.ScoredMemory{NarrativeMemory: existing, Similarity: 0.95}}
+	svc := &NarrativeService{memoryRepo: memRepo, llmClient: actionFakeLLM{embedding: []float32{1}}}
+	svc.SetDedupThreshold(0.9)
+
+	if err := svc.InjectMemory(context.Background(), profileID, nil, "me dejaste plantado otra vez", 5, 4, 70, "TRISTEZA"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if memRepo.created.ID != uuid.Nil {
+		t.Fatalf("expected no new row for a near duplicate, got %+v", memRepo.created)
+	}
+	got := memRepo.merged
+	if got == nil || got.ID != existing.ID || got.Content != existing.Content {
+		t.Fatalf("expected merge into existing memory, got %+v", got)
+	}
+	// max(6,5)+1, max(7,4), max(50,70)+10; la categoria neutral adopta la nueva.
+	if got.Importance != 7 || got.EmotionalWeight != 7 || got.EmotionalIntensity != 80 || got.EmotionCategory != "TRISTEZA" {
+		t.Fatalf("unexpected merged values: %+v", got)
+	}
+
+	// Por debajo del umbral se crea una memoria nueva.
+	memRepo.merged = nil
+	memRepo.nearest.Similarity = 0.8
+	if err := svc.InjectMemory(context.Background(), profileID, nil, "otra cosa", 5, 5, 50, "IRA"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if memRepo.merged != nil || memRepo.created.Content != "otra cosa" {
+		t.Fatalf("expected a new memory below the threshold, merged=%+v created=%+v", memRepo.merged, memRepo.created)
+	}
+
+	// Con la fusion desactivada nunca se consulta al vecino.
+	memRepo.nearest.Similarity = 1
+	svc.SetDedupThreshold(0)
+	if err := svc.InjectMemory(context.Background(), profileID, nil, "me dejaste plantado", 5, 5, 50, "IRA"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if memRepo.merged != nil {
+		t.Fatalf("expected no merge when dedup is disabled")
+	}
+}
+
+func TestUpdateMemory_ReembedsOnlyWhenRequested(t *testing.T) {
+	memRepo := &actionFakeMemoryRepo{}
+	svc := &NarrativeService{memoryRepo: memRepo, llmClient: actionFakeLLM{embedding: []float32{4, 5}}}
+	memory := domain.NarrativeMemory{ID: uuid.New(), Content: " texto nuevo ", Embedding: pgvector.NewVector([]float32{1})}
+
+	if _, err := svc.UpdateMemory(context.Background(), memory, false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := memRepo.updated.Embedding.Slice(); len(got) != 1 || memRepo.updated.Content != "texto nuevo" {
+		t.Fatalf("expected embedding untouched and trimmed content, got %v %q", got, memRepo.updated.Content)
+	}
+
+	if _, err := svc.UpdateMemory(context.Background(), memory, true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := memRepo.updated.Embedding.Slice(); len(got) != 2 {
+		t.Fatalf("expected recomputed embedding, got %v", got)
+	}
+
+	memory.Content = "  "
+	if _, err := svc.UpdateMemory(context.Background(), memory, true); !errors.Is(err, ErrNarrativeInvalidInput) {
+		t.Fatalf("expected ErrNarrativeInvalidInput for empty content, got %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultNarrativeCacheSize = 1000
+	defaultNarrativeCacheTTL  = time.Hour
+	// narrativeCacheKeySep separa el profileID del resto de la clave (ver evocationCacheKey/judgeCacheKey).
+	narrativeCacheKeySep = "||"
+)
+
+// NarrativeCacheStats son los contadores de aciertos/fallos por tipo de consulta.
+type NarrativeCacheStats struct {
+	EvocationHits   uint64 `json:"evocation_hits"`
+	EvocationMisses uint64 `json:"evocation_misses"`
+	JudgeHits       uint64 `json:"judge_hits"`
+	JudgeMisses     uint64 `json:"judge_misses"`
+}
+
+func evocationCacheKey(profileID uuid.UUID, userMessage string) string {
+	return profileID.String() + narrativeCacheKeySep + "ev" + narrativeCacheKeySep + userMessage
+}
+
+func judgeCacheKey(profileID uuid.UUID, userMessage, memoryContent string) string {
+	return profileID.String() + narrativeCacheKeySep + "j" + narrativeCacheKeySep + userMessage + narrativeCacheKeySep + memoryContent
+}
+
+// cacheKeyProfile devuelve el profileID con el que empieza la clave.
+func cacheKeyProfile(key string) string {
+	profile, _, _ := strings.Cut(key, narrativeCacheKeySep)
+	return profile
+}
+
+type narrativeCacheCounters struct {
+	evHits, evMisses, judgeHits, judgeMisses atomic.Uint64
+}
+
+func (c *narrativeCacheCounters) countEvocation(hit bool) {
+	if hit {
+		c.evHits.Add(1)
+	} else {
+		c.evMisses.Add(1)
+	}
+}
+
+func (c *narrativeCacheCounters) countJudge(hit bool) {
+	if hit {
+		c.judgeHits.Add(1)
+	} else {
+		c.judgeMisses.Add(1)
+	}
+}
+
+func (c *narrativeCacheCounters) Stats() NarrativeCacheStats {
+	return NarrativeCacheStats{
+		EvocationHits:   c.evHits.Load(),
+		EvocationMisses: c.evMisses.Load(),
+		JudgeHits:       c.judgeHits.Load(),
+		JudgeMisses:     c.judgeMisses.Load(),
+	}
+}
+
+// memoryNarrativeCache es un LRU acotado en proceso con TTL por entrada.
+type memoryNarrativeCache struct {
+	narrativeCacheCounters
+
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // frente = usado mas recientemente
+	items    map[string]*list.Element
+	now      func() time.Time
+}
+
+type narrativeCacheEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// NewMemoryNarrativeCache crea el cache en proceso. capacity <= 0 o ttl <= 0 usan los valores por defecto.
+func NewMemoryNarrativeCache(capacity int, ttl time.Duration) NarrativeCache {
+	if capacity <= 0 {
+		capacity = defaultNarrativeCacheSize
+	}
+	if ttl <= 0 {
+		ttl = defaultNarrativeCacheTTL
+	}
+	return &memoryNarrativeCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (c *memoryNarrativeCache) GetEvocation(key string) (string, bool) {
+	val, ok := c.get(key)
+	c.countEvocation(ok)
+	return val, ok
+}
+
+func (c *memoryNarrativeCache) SetEvocation(key, val string) { c.set(key, val) }
+
+func (c *memoryNarrativeCache) GetJudge(key string) (bool, bool) {
+	val, ok := c.get(key)
+	c.countJudge(ok)
+	return val == "1", ok
+}
+
+func (c *memoryNarrativeCache) SetJudge(key string, val bool) { c.set(key, boolCacheValue(val)) }
+
+// InvalidateProfile descarta todas las entradas del clon.
+func (c *memoryNarrativeCache) InvalidateProfile(profileID uuid.UUID) {
+	prefix := profileID.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, el := range c.items {
+		if cacheKeyProfile(key) == prefix {
+			c.order.Remove(el)
+			delete(c.items, key)
+		}
+	}
+}
+
+// Len devuelve la cantidad de entradas (incluidas las vencidas aun no desalojadas).
+func (c *memoryNarrativeCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *memoryNarrativeCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	entry := el.Value.(*narrativeCacheEntry)
+	if c.now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *memoryNarrativeCache) set(key, val string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*narrativeCacheEntry)
+		entry.value = val
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&narrativeCacheEntry{key: key, value: val, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*narrativeCacheEntry).key)
+	}
+}
+
+// redisNarrativeCache comparte el cache entre instancias. Los errores de Redis cuentan como fallo
+// de cache: el turno sigue llamando al LLM en lugar de romperse.
+type redisNarrativeCache struct {
+	narrativeCacheCounters
+
+	client redisCacheClient
+	ttl    time.Duration
+	prefix string
+}
+
+type redisCacheClient interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// NewRedisNarrativeCache crea el cache sobre el cliente Redis ya configurado. Devuelve nil si no hay cliente.
+func NewRedisNarrativeCache(client *redis.Client, ttl time.Duration) NarrativeCache {
+	if client == nil {
+		return nil
+	}
+	if ttl <= 0 {
+		ttl = defaultNarrativeCacheTTL
+	}
+	return &redisNarrativeCache{
+		client: client,
+		ttl:    ttl,
+		prefix: "narrative:cache:",
+	}
+}
+
+func (c *redisNarrativeCache) GetEvocation(key string) (string, bool) {
+	val, ok := c.get(key)
+	c.countEvocation(ok)
+	return val, ok
+}
+
+func (c *redisNarrativeCache) SetEvocation(key, val string) { c.set(key, val) }
+
+func (c *redisNarrativeCache) GetJudge(key string) (bool, bool) {
+	val, ok := c.get(key)
+	c.countJudge(ok)
+	return val == "1", ok
+}
+
+func (c *redisNarrativeCache) SetJudge(key string, val bool) { c.set(key, boolCacheValue(val)) }
+
+// InvalidateProfile borra las claves del clon recorriendo su prefijo con SCAN (no bloquea Redis como KEYS).
+func (c *redisNarrativeCache) InvalidateProfile(profileID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	match := c.prefix + profileID.String() + ":*"
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, match, 200).Result()
+		if err != nil {
+			return
+		}
+		if len(keys) > 0 {
+			if err := c.client.Del(ctx, keys...).Err(); err != nil {
+				return
+			}
+		}
+		if next == 0 {
+			return
+		}
+		cursor = next
+	}
+}
+
+// redisKey agrupa por clon y hashea el resto: mensajes y memorias pueden ser largos.
+func (c *redisNarrativeCache) redisKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return c.prefix + cacheKeyProfile(key) + ":" + hex.EncodeToString(sum[:])
+}
+
+func (c *redisNarrativeCache) get(key string) (string, bool) {
+	if c == nil || c.client == nil {
+		return "", false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	val, err := c.client.Get(ctx, c.redisKey(key)).Result()
+	if err != nil {
+		return "", false
+	}
+	return val, true
+}
+
+func (c *redisNarrativeCache) set(key, val string) {
+	if c == nil || c.client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = c.client.Set(ctx, c.redisKey(key), val, c.ttl).Err()
+}
+
+func boolCacheValue(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
+
+// LogNarrativeCacheStats registra los aciertos/fallos del cache cada interval hasta que se cancele ctx.
+// Solo escribe si hubo consultas desde el ultimo registro.
+func LogNarrativeCacheStats(ctx context.Context, cache NarrativeCache, logger *zap.Logger, interval time.Duration) {
+	if cache == nil || logger == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last NarrativeCacheStats
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := cache.Stats()
+			if stats == last {
+				continue
+			}
+			last = stats
+			logger.Info("narrative cache stats",
+				zap.Uint64("evocation_hits", stats.EvocationHits),
+				zap.Uint64("evocation_misses", stats.EvocationMisses),
+				zap.Uint64("judge_hits", stats.JudgeHits),
+				zap.Uint64("judge_misses", stats.JudgeMisses),
+			)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMemoryNarrativeCache_LRUAndTTL(t *testing.T) {
+	cache := NewMemoryNarrativeCache(2, time.Minute).(*memoryNarrativeCache)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	profileID := uuid.New()
+
+	a, b, c := evocationCacheKey(profileID, "a"), evocationCacheKey(profileID, "b"), evocationCacheKey(profileID, "c")
+	cache.SetEvocation(a, "A")
+	cache.SetEvocation(b, "B")
+	if _, ok := cache.GetEvocation(a); !ok {
+		t.Fatalf("expected hit for a")
+	}
+	cache.SetEvocation(c, "C") // desaloja b, el menos usado
+	if _, ok := cache.GetEvocation(b); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	if v, ok := cache.GetEvocation(c); !ok || v != "C" {
+		t.Fatalf("expected hit for c, got %q %v", v, ok)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := cache.GetEvocation(a); ok {
+		t.Fatalf("expected a to expire")
+	}
+
+	stats := cache.Stats()
+	if stats.EvocationHits != 2 || stats.EvocationMisses != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMemoryNarrativeCache_InvalidateProfile(t *testing.T) {
+	cache := NewMemoryNarrativeCache(10, time.Minute).(*memoryNarrativeCache)
+	mine, other := uuid.New(), uuid.New()
+
+	cache.SetEvocation(evocationCacheKey(mine, "hola"), "saludo")
+	cache.SetJudge(judgeCacheKey(mine, "hola", "memoria"), true)
+	cache.SetJudge(judgeCacheKey(other, "hola", "memoria"), false)
+
+	cache.InvalidateProfile(mine)
+	if cache.Len() != 1 {
+		t.Fatalf("expected only the other profile to remain, got %d entries", cache.Len())
+	}
+	if v, ok := cache.GetJudge(judgeCacheKey(other, "hola", "memoria")); !ok || v {
+		t.Fatalf("expected cached false verdict for other profile, got %v %v", v, ok)
+	}
+	if stats := cache.Stats(); stats.JudgeHits != 1 || stats.JudgeMisses != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+// fakeRedisCache implementa redisCacheClient sobre un mapa.
+type fakeRedisCache struct {
+	data map[string]string
+	ttl  time.Duration
+}
+
+func (f *fakeRedisCache) Get(ctx context.Context, key string) *redis.StringCmd {
+	cmd := redis.NewStringCmd(ctx)
+	if v, ok := f.data[key]; ok {
+		cmd.SetVal(v)
+	} else {
+		cmd.SetErr(redis.Nil)
+	}
+	return cmd
+}
+
+func (f *fakeRedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	f.data[key] = value.(string)
+	f.ttl = expiration
+	return redis.NewStatusCmd(ctx)
+}
+
+func (f *fakeRedisCache) Scan(ctx context.Context, _ uint64, match string, _ int64) *redis.ScanCmd {
+	prefix := strings.TrimSuffix(match, "*")
+	var keys []string
+	for k := range f.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	cmd := redis.NewScanCmd(ctx, nil)
+	cmd.SetVal(keys, 0)
+	return cmd
+}
+
+func (f *fakeRedisCache) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	for _, k := range keys {
+		delete(f.data, k)
+	}
+	return redis.NewIntCmd(ctx)
+}
+
+func TestRedisNarrativeCache_RoundTripAndInvalidate(t *testing.T) {
+	client := &fakeRedisCache{data: map[string]string{}}
+	cache := &redisNarrativeCache{client: client, ttl: 30 * time.Minute, prefix: "narrative:cache:"}
+	mine, other := uuid.New(), uuid.New()
+
+	evKey := evocationCacheKey(mine, strings.Repeat("mensaje largo ", 50))
+	if _, ok := cache.GetEvocation(evKey); ok {
+		t.Fatalf("expected miss on empty cache")
+	}
+	cache.SetEvocation(evKey, "recuerdo")
+	cache.SetJudge(judgeCacheKey(mine, "hola", "memoria"), true)
+	cache.SetJudge(judgeCacheKey(other, "hola", "memoria"), true)
+
+	if v, ok := cache.GetEvocation(evKey); !ok || v != "recuerdo" {
+		t.Fatalf("expected evocation hit, got %q %v", v, ok)
+	}
+	if client.ttl != 30*time.Minute {
+		t.Fatalf("expected ttl to be forwarded, got %v", client.ttl)
+	}
+	for k := range client.data {
+		if len(k) > 120 {
+			t.Fatalf("expected hashed redis key, got %q", k)
+		}
+	}
+
+	cache.InvalidateProfile(mine)
+	if len(client.data) != 1 {
+		t.Fatalf("expected only the other profile's key to remain, got %v", client.data)
+	}
+	if v, ok := cache.GetJudge(judgeCacheKey(other, "hola", "memoria")); !ok || !v {
+		t.Fatalf("expected judge hit for other profile, got %v %v", v, ok)
+	}
+
+	stats := cache.Stats()
+	if stats.EvocationHits != 1 || stats.EvocationMisses != 1 || stats.JudgeHits != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestNarrativeService_InvalidatesCacheOnlyOnEditOrDelete(t *testing.T) {
+	profileID := uuid.New()
+	key := evocationCacheKey(profileID, "hola")
+	cache := NewMemoryNarrativeCache(10, time.Minute)
+	cache.SetEvocation(key, "saludo")
+	svc := &NarrativeService{memoryRepo: &actionFakeMemoryRepo{}, llmClient: actionFakeLLM{embedding: []float32{1}}}
+	svc.SetCache(cache)
+	ctx := context.Background()
+
+	if err := svc.InjectMemory(ctx, profileID, nil, "evento nuevo", 5, 5, 50, "ALEGRIA"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := cache.GetEvocation(key); !ok {
+		t.Fatalf("a new memory must not invalidate the cache")
+	}
+
+	if err := svc.DeleteMemory(ctx, profileID, uuid.New()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := cache.GetEvocation(key); ok {
+		t.Fatalf("expected cache to be invalidated after a delete")
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/domain"
+)
+
+// ResolveInterlocutor decide de que personaje trata el mensaje, en este orden:
+//  1. un personaje nombrado en el mensaje (detectActiveCharacters),
+//  2. el personaje "hablando como" fijado en la sesion,
+//  3. un personaje aludido por su relacion ("mi jefe", "mi hermanita"); si la relacion es de
+//     varios personajes, el LLM elige entre ellos,
+//  4. si nada de lo anterior resuelve pero el mensaje alude a alguien por pronombre ("ella siempre
+//     me hace esto", "le dije que no"), el LLM elige entre todos los personajes del clon.
+//
+// Devuelve nil si ninguno aplica. Sin pistas en el mensaje no se consulta al LLM: seria una llamada
+// sincronica mas en cada turno. El fallback del LLM es best-effort y sus errores se ignoran.
+func (s *NarrativeService) ResolveInterlocutor(ctx context.Context, profileID uuid.UUID, userMessage string, speakingAsID *uuid.UUID) (*domain.Character, error) {
+	if s == nil || s.characterRepo == nil {
+		return nil, ErrNarrativeServiceNotConfigured
+	}
+	if profileID == uuid.Nil {
+		return nil, ErrNarrativeInvalidInput
+	}
+
+	chars, err := s.characterRepo.ListByProfileID(ctx, profileID)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveInterlocutor(ctx, chars, userMessage, speakingAsID), nil
+}
+
+// resolveInterlocutor aplica la precedencia de ResolveInterlocutor sobre personajes ya listados.
+func (s *NarrativeService) resolveInterlocutor(ctx context.Context, chars []domain.Character, userMessage string, speakingAsID *uuid.UUID) *domain.Character {
+	if len(chars) == 0 {
+		return nil
+	}
+
+	if active := detectActiveCharacters(chars, userMessage); len(active) > 0 {
+		return &active[0]
+	}
+
+	if speakingAsID != nil {
+		for i := range chars {
+			if chars[i].ID == *speakingAsID {
+				return &chars[i]
+			}
+		}
+	}
+
+	candidates := charactersByRelation(chars, userMessage)
+	switch {
+	case len(candidates) == 1:
+		return &candidates[0]
+	case s.judgeLLM() == nil:
+		return nil
+	case len(candidates) > 1:
+		return s.guessInterlocutor(ctx, candidates, userMessage)
+	case mentionsSomeone(userMessage):
+		return s.guessInterlocutor(ctx, chars, userMessage)
+	}
+	return nil
+}
+
+// personPronouns son pronombres de segunda y tercera persona que aluden a alguien sin nombrarlo.
+// "el" solo cuenta con tilde: sin ella es el articulo.
+var personPronouns = map[string]struct{}{
+	"él": {}, "ella": {}, "ellos": {}, "ellas": {}, "le": {}, "les": {},
+	"vos": {}, "usted": {}, "ustedes": {},
+}
+
+// mentionsSomeone es el filtro barato antes del fallback del LLM sobre todos los personajes:
+// el mensaje tiene que aludir a alguien con un pronombre.
+func mentionsSomeone(userMessage string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToLower(userMessage), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if _, ok := personPronouns[word]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// minRelationWordLength descarta articulos y preposiciones de relaciones compuestas ("amiga de la infancia").
+const minRelationWordLength = 4
+
+// charactersByRelation devuelve los personajes cuya relacion aparece en el mensaje. Basta una palabra
+// significativa de la relacion ("amiga" en "mejor amiga"); tambien acepta diminutivos ("hermanita").
+func charactersByRelation(chars []domain.Character, userMessage string) []domain.Character {
+	msgTokens := nameTokens(userMessage)
+	if len(msgTokens) == 0 {
+		return nil
+	}
+	var out []domain.Character
+	for _, c := range chars {
+		for _, word := range nameTokens(c.Relation) {
+			if len(word) >= minRelationWordLength && mentionsName(msgTokens, word) {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// guessInterlocutor le pide al LLM que elija un personaje de la lista. Solo acepta nombres exactos de la lista.
+func (s *NarrativeService) guessInterlocutor(ctx context.Context, chars []domain.Character, userMessage string) *domain.Character {
+	var list strings.Builder
+	for _, c := range chars {
+		fmt.Fprintf(&list, "- %s (%s)\n", c.Name, c.Relation)
+	}
+
+	prompt := `Personajes conocidos en la vida del clon:
+` + list.String() + `
+Mensaje del usuario: "` + strings.TrimSpace(userMessage) + `"
+
+¿El mensaje trata sobre alguno de estos personajes (o lo dice alguno de ellos), aunque no lo nombre directamente?
+Responde SOLO un JSON: {"character": "<nombre exacto de la lista o vacio si no aplica>"}`
+
+	raw, err := s.judgeLLM().Generate(ctx, prompt)
+	if err != nil {
+		return nil
+	}
+	cleaned := cleanLLMJSONResponse(raw)
+	if obj := extractFirstJSONObject(cleaned); obj != "" {
+		cleaned = obj
+	}
+
+	var out struct {
+		Character string `json:"character"`
+	}
+	if err := json.Unmarshal([]byte(cleaned), &out); err != nil {
+		return nil
+	}
+	name := strings.TrimSpace(out.Character)
+	if name == "" {
+		return nil
+	}
+	for i := range chars {
+		if strings.EqualFold(strings.TrimSpace(chars[i].Name), name) {
+			return &chars[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/domain"
+)
+
+// interlocutorFakeLLM responde al prompt de ResolveInterlocutor con un nombre fijo.
+type interlocutorFakeLLM struct {
+	fakeLLM
+	guess string
+}
+
+func (f interlocutorFakeLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, `"character"`) {
+		return `{"character": "` + f.guess + `"}`, nil
+	}
+	return f.fakeLLM.Generate(ctx, prompt)
+}
+
+// characterMemoryRepo devuelve memorias por personaje ademas de las del fakeMemoryRepo.
+type characterMemoryRepo struct {
+	fakeMemoryRepo
+	byCharacter map[uuid.UUID][]domain.NarrativeMemory
+}
+
+func (f characterMemoryRepo) ListByCharacter(_ context.Context, characterID uuid.UUID, _ int) ([]domain.NarrativeMemory, error) {
+	return f.byCharacter[characterID], nil
+}
+
+func TestResolveInterlocutor_Precedence(t *testing.T) {
+	profileID := uuid.New()
+	ana := domain.Character{ID: uuid.New(), CloneProfileID: profileID, Name: "Ana", Relation: "hermana"}
+	beto := domain.Character{ID: uuid.New(), CloneProfileID: profileID, Name: "Beto", Relation: "jefe"}
+	svc := &NarrativeService{
+		characterRepo: &fakeCharacterRepo{chars: []domain.Character{ana, beto}},
+		memoryRepo:    fakeMemoryRepo{},
+		llmClient:     interlocutorFakeLLM{guess: "beto"},
+	}
+	ctx := context.Background()
+
+	got, err := svc.ResolveInterlocutor(ctx, profileID, "hoy hable con Ana", &beto.ID)
+	if err != nil || got == nil || got.ID != ana.ID {
+		t.Fatalf("expected named character to win, got %+v (%v)", got, err)
+	}
+
+	got, _ = svc.ResolveInterlocutor(ctx, profileID, "no me escuchas nunca", &ana.ID)
+	if got == nil || got.ID != ana.ID {
+		t.Fatalf("expected speaking-as character, got %+v", got)
+	}
+
+	got, _ = svc.ResolveInterlocutor(ctx, profileID, "mi jefe me volvio a gritar en la oficina", nil)
+	if got == nil || got.ID != beto.ID {
+		t.Fatalf("expected relation cue to pick Beto, got %+v", got)
+	}
+
+	svc.llmClient = interlocutorFakeLLM{guess: "Carlos"}
+	if got, _ := svc.ResolveInterlocutor(ctx, profileID, "algo sin nombre", nil); got != nil {
+		t.Fatalf("expected nil without cues, got %+v", got)
+	}
+}
+
+func TestResolveInterlocutor_GuessOnlyWithCues(t *testing.T) {
+	profileID := uuid.New()
+	ana := domain.Character{ID: uuid.New(), CloneProfileID: profileID, Name: "Ana", Relation: "mejor amiga"}
+	lucia := domain.Character{ID: uuid.New(), CloneProfileID: profileID, Name: "Lucia", Relation: "amiga de la infancia"}
+	beto := domain.Character{ID: uuid.New(), CloneProfileID: profileID, Name: "Beto", Relation: "jefe"}
+	judge := &recordingGenerator{reply: `{"character": "Lucia"}`}
+	svc := &NarrativeService{
+		characterRepo: &fakeCharacterRepo{chars: []domain.Character{ana, lucia, beto}},
+		memoryRepo:    fakeMemoryRepo{},
+		llmClient:     fakeLLM{},
+	}
+	svc.SetJudgeClient(judge)
+	ctx := context.Background()
+
+	if got, _ := svc.ResolveInterlocutor(ctx, profileID, "me volvio a gritar en la oficina", nil); got != nil {
+		t.Fatalf("expected nil without cues, got %+v", got)
+	}
+	if len(judge.prompts) != 0 {
+		t.Fatalf("expected no LLM call without cues, got %d", len(judge.prompts))
+	}
+
+	got, _ := svc.ResolveInterlocutor(ctx, profileID, "mi amiga no me contesta", nil)
+	if got == nil || got.ID != lucia.ID {
+		t.Fatalf("expected LLM to pick Lucia, got %+v", got)
+	}
+	if len(judge.prompts) != 1 || strings.Contains(judge.prompts[0], "Beto") {
+		t.Fatalf("expected one LLM call limited to the candidates, got %q", judge.prompts)
+	}
+
+	judge.reply = `{"character": "Beto"}`
+	got, _ = svc.ResolveInterlocutor(ctx, profileID, "Ella siempre me hace esto", nil)
+	if got == nil || got.ID != beto.ID {
+		t.Fatalf("expected LLM fallback over all characters on a pronoun, got %+v", got)
+	}
+	if len(judge.prompts) != 2 || !strings.Contains(judge.prompts[1], "Ana") || !strings.Contains(judge.prompts[1], "Beto") {
+		t.Fatalf("expected a second LLM call listing every character, got %q", judge.prompts)
+	}
+}
+
+func TestBuildNarrativeContextFor_IncludesInterlocutorMemories(t *testing.T) {
+	profileID := uuid.New()
+	ana := domain.Character{ID: uuid.New(), CloneProfileID: profileID, Name: "Ana", Relationship: domain.RelationshipVectors{Trust: 50, Intimacy: 50, Respect: 50}}
+	memory := domain.NarrativeMemory{ID: uuid.New(), CloneProfileID: profileID, RelatedCharacterID: &ana.ID, Content: "Ana me presto plata", EmotionCategory: "ALEGRIA", EmotionalIntensity: 40}
+
+	svc := &NarrativeService{
+		characterRepo: &fakeCharacterRepo{chars: []domain.Character{ana}},
+		memoryRepo:    characterMemoryRepo{byCharacter: map[uuid.UUID][]domain.NarrativeMemory{ana.ID: {memory}}},
+		llmClient:     fakeLLM{},
+	}
+
+	text, err := svc.BuildNarrativeContextFor(context.Background(), profileID, "estoy cansado", &ana)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(text, "Ana me presto plata") {
+		t.Fatalf("expected interlocutor memory in context, got %q", text)
+	}
+
+	text, _ = svc.BuildNarrativeContext(context.Background(), profileID, "estoy cansado")
+	if strings.Contains(text, "Ana me presto plata") {
+		t.Fatalf("memory must not be forced without an active character, got %q", text)
+	}
+}
@@ -77,3 +77,19 @@ Ejemplos (responde exactamente el JSON):
 Usuario: %q
 Memoria: %q
 `
+
+const reflectionPromptTemplate = `
+Eres la voz interior de un clon conversacional. Lee estos recuerdos recientes sobre %s y saca conclusiones de mas alto nivel sobre la relacion: patrones, sospechas, creencias ("siento que Juan me evita cuando hablo de su trabajo").
+
+Reglas:
+- Como maximo %d conclusiones, en primera persona y en una sola oracion cada una.
+- No repitas un recuerdo: cada conclusion debe sintetizar al menos dos.
+- No inventes hechos que no esten en los recuerdos. Si no hay ningun patron, devuelve una lista vacia.
+- "sources": numeros de los recuerdos en los que se apoya la conclusion.
+- "importance": 1-10. "emotion_category": emocion dominante (ALEGRIA, TRISTEZA, IRA, MIEDO, NEUTRAL, ...).
+
+Recuerdos:
+%s
+Devuelve SOLO un JSON con este formato:
+{"insights": [{"content": "...", "sources": [1, 3], "importance": 7, "emotion_category": "TRISTEZA"}]}
+`
@@ -53,19 +53,25 @@ func hasNegationSemantic(msgLower string) bool {
 	return false
 }
 
+// detectActiveCharacters devuelve los personajes nombrados en el mensaje por su nombre o alguno de sus apodos.
+// La comparacion ignora mayusculas y tildes, respeta limites de palabra ("Ana" no matchea "banana")
+// y acepta diminutivos regulares ("Anita", "mamita").
 func detectActiveCharacters(chars []domain.Character, userMessage string) []domain.Character {
 	var out []domain.Character
-	msg := strings.ToLower(strings.TrimSpace(userMessage))
-	if msg == "" {
+	msgTokens := nameTokens(userMessage)
+	if len(msgTokens) == 0 {
 		return out
 	}
 	for _, c := range chars {
-		name := strings.ToLower(strings.TrimSpace(c.Name))
-		if name == "" {
+		if mentionsName(msgTokens, c.Name) {
+			out = append(out, c)
 			continue
 		}
-		if strings.Contains(msg, name) {
-			out = append(out, c)
+		for _, alias := range c.Aliases {
+			if mentionsName(msgTokens, alias) {
+				out = append(out, c)
+				break
+			}
 		}
 	}
 	return out
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	pgvector "github.com/pgvector/pgvector-go"
@@ -21,6 +23,11 @@ const (
 	workingMemoryMinImportance         = 8
 	workingMemoryMinEmotionalIntensity = 60
 	maxTotalMemoriesInContext          = 7
+	// Refuerzo de similitud/score para memorias ligadas al personaje activo del turno.
+	activeCharacterMemoryBoost = 0.10
+	activeCharacterMemoryLimit = 2
+	// activeCharacterMemoryFetch deja margen para las memorias traumaticas que se descartan en turnos benignos.
+	activeCharacterMemoryFetch = 6
 )
 
 type llmClientWithEmbedding interface {
@@ -28,18 +35,34 @@ type llmClientWithEmbedding interface {
 	Generate(ctx context.Context, prompt string) (string, error)
 }
 
+// llmGenerator es lo que necesitan las llamadas auxiliares (evocacion, juez), que pueden ir a otro modelo.
+type llmGenerator interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// NarrativeCache guarda las respuestas de evocacion y juez del LLM entre turnos.
+// Las claves empiezan con el profileID, lo que permite invalidar un clon entero.
 type NarrativeCache interface {
 	GetEvocation(key string) (string, bool)
 	SetEvocation(key, val string)
 	GetJudge(key string) (bool, bool)
 	SetJudge(key string, val bool)
+	// InvalidateProfile descarta lo cacheado para el clon (p.ej. al cambiar sus memorias).
+	InvalidateProfile(profileID uuid.UUID)
+	Stats() NarrativeCacheStats
 }
 
 type NarrativeService struct {
 	characterRepo repository.CharacterRepository
 	memoryRepo    repository.MemoryRepository
 	llmClient     llmClientWithEmbedding
-	cache         NarrativeCache
+	// evocationClient y judgeClient (opcionales) sirven la evocacion y el juez/interlocutor con un
+	// modelo mas barato; sin ellos se usa llmClient.
+	evocationClient llmGenerator
+	judgeClient     llmGenerator
+	cache           NarrativeCache
+	// dedupThreshold: similitud coseno a partir de la cual InjectMemory fusiona en una memoria existente (0 = desactivado).
+	dedupThreshold float64
 }
 
 var (
@@ -49,6 +72,44 @@ var (
 
 func (s *NarrativeService) SetCache(cache NarrativeCache) { s.cache = cache }
 
+// SetEvocationClient usa client para generar la evocacion de cada turno.
+func (s *NarrativeService) SetEvocationClient(client llmGenerator) { s.evocationClient = client }
+
+// SetJudgeClient usa client para el juez de memorias y la deteccion del interlocutor.
+func (s *NarrativeService) SetJudgeClient(client llmGenerator) { s.judgeClient = client }
+
+func (s *NarrativeService) evocationLLM() llmGenerator {
+	if s.evocationClient != nil {
+		return s.evocationClient
+	}
+	return s.llmClient
+}
+
+func (s *NarrativeService) judgeLLM() llmGenerator {
+	if s.judgeClient != nil {
+		return s.judgeClient
+	}
+	return s.llmClient
+}
+
+// SetDedupThreshold fija la similitud (0-1) a partir de la cual un recuerdo nuevo se fusiona con el
+// mas parecido ya guardado en lugar de crear otra fila. Valores <= 0 desactivan la fusion.
+func (s *NarrativeService) SetDedupThreshold(threshold float64) {
+	if threshold > 1 {
+		threshold = 1
+	}
+	s.dedupThreshold = threshold
+}
+
+// invalidateCache descarta evocaciones y veredictos del juez del clon tras editar o borrar una memoria.
+// Agregar memorias no invalida: la evocacion depende solo del mensaje y un recuerdo nuevo no cambia
+// los veredictos ya cacheados sobre los demas.
+func (s *NarrativeService) invalidateCache(profileID uuid.UUID) {
+	if s.cache != nil {
+		s.cache.InvalidateProfile(profileID)
+	}
+}
+
 func NewNarrativeService(
 	characterRepo repository.CharacterRepository,
 	memoryRepo repository.MemoryRepository,
@@ -58,16 +119,40 @@ func NewNarrativeService(
 		characterRepo: characterRepo,
 		memoryRepo:    memoryRepo,
 		llmClient:     llmClient,
+
+		dedupThreshold: defaultMemoryDedupThreshold,
 	}
 }
 
 func (s *NarrativeService) BuildNarrativeContext(ctx context.Context, profileID uuid.UUID, userMessage string) (string, error) {
+	return s.BuildNarrativeContextFor(ctx, profileID, userMessage, nil)
+}
+
+// BuildNarrativeContextFor arma el contexto narrativo priorizando las memorias del interlocutor
+// resuelto para el turno (ver ResolveInterlocutor), ademas de los personajes nombrados en el mensaje.
+func (s *NarrativeService) BuildNarrativeContextFor(ctx context.Context, profileID uuid.UUID, userMessage string, interlocutor *domain.Character) (string, error) {
 	if s == nil || s.characterRepo == nil || s.memoryRepo == nil || s.llmClient == nil {
 		return "", ErrNarrativeServiceNotConfigured
 	}
 	if profileID == uuid.Nil {
 		return "", ErrNarrativeInvalidInput
 	}
+	chars, err := s.characterRepo.ListByProfileID(ctx, profileID)
+	if err != nil {
+		return "", err
+	}
+	return s.buildNarrativeContext(ctx, profileID, userMessage, interlocutor, chars)
+}
+
+// buildNarrativeContext es BuildNarrativeContextFor con los personajes del clon ya listados,
+// para que el turno del chat los consulte una sola vez.
+func (s *NarrativeService) buildNarrativeContext(ctx context.Context, profileID uuid.UUID, userMessage string, interlocutor *domain.Character, chars []domain.Character) (string, error) {
+	if s == nil || s.memoryRepo == nil || s.llmClient == nil {
+		return "", ErrNarrativeServiceNotConfigured
+	}
+	if profileID == uuid.Nil {
+		return "", ErrNarrativeInvalidInput
+	}
 
 	userMessage = strings.TrimSpace(userMessage)
 
@@ -102,18 +187,21 @@ func (s *NarrativeService) BuildNarrativeContext(ctx context.Context, profileID
 		judgeCache = make(map[string]bool)
 	}
 
-	chars, err := s.characterRepo.ListByProfileID(ctx, profileID)
-	if err != nil {
-		return "", err
-	}
-
 	active := detectActiveCharacters(chars, userMessage)
+	if interlocutor != nil && !containsCharacter(active, interlocutor.ID) {
+		active = append([]domain.Character{*interlocutor}, active...)
+	}
+	// focus son los personajes realmente en juego (no el fallback a todos los vinculos).
+	focus := make(map[uuid.UUID]struct{}, len(active))
+	for _, c := range active {
+		focus[c.ID] = struct{}{}
+	}
 	if len(active) == 0 {
 		active = chars
 	}
 
 	// FIX #2: cache keys incluyen profileID (evita contaminacion cross-user)
-	evKey := profileID.String() + "||ev||" + userMessage
+	evKey := evocationCacheKey(profileID, userMessage)
 
 	searchQuery, ok := "", false
 	if useCache {
@@ -155,6 +243,12 @@ func (s *NarrativeService) BuildNarrativeContext(ctx context.Context, profileID
 			return "", err
 		}
 
+		for i := range scored {
+			if isFocusMemory(scored[i].NarrativeMemory, focus) {
+				scored[i].Similarity += activeCharacterMemoryBoost
+				scored[i].Score += activeCharacterMemoryBoost
+			}
+		}
 		sort.Slice(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
 
 		const upperSim = 0.62
@@ -198,7 +292,7 @@ func (s *NarrativeService) BuildNarrativeContext(ctx context.Context, profileID
 			}
 
 			// FIX #2: cache key del juez tambien incluye profileID
-			jKey := profileID.String() + "||j||" + userMessage + "||" + sm.Content
+			jKey := judgeCacheKey(profileID, userMessage, sm.Content)
 
 			var use bool
 			var found bool
@@ -238,8 +332,34 @@ func (s *NarrativeService) BuildNarrativeContext(ctx context.Context, profileID
 		return "", err
 	}
 
-	allMemories := mergeDedupMemories(workingMemories, memories)
+	// Memorias recientes del personaje activo: entran aunque la evocacion no las haya encontrado.
+	var characterMemories []domain.NarrativeMemory
+	for _, c := range active {
+		if _, ok := focus[c.ID]; !ok {
+			continue
+		}
+		list, err := s.memoryRepo.ListByCharacter(ctx, c.ID, activeCharacterMemoryFetch)
+		if err != nil {
+			return "", err
+		}
+		added := 0
+		for _, m := range list {
+			if added >= activeCharacterMemoryLimit {
+				break
+			}
+			if isBenign && shouldSkipTrauma(m) {
+				continue
+			}
+			characterMemories = append(characterMemories, m)
+			added++
+		}
+	}
+
+	allMemories := mergeDedupMemories(workingMemories, characterMemories)
+	allMemories = mergeDedupMemories(allMemories, memories)
+	allMemories = preferReflections(allMemories)
 	allMemories = limitMemories(allMemories, maxTotalMemoriesInContext)
+	s.markRecalled(ctx, recalledMemories(memories, allMemories))
 
 	if len(allMemories) > 0 {
 		sort.Slice(allMemories, func(i, j int) bool {
@@ -249,8 +369,12 @@ func (s *NarrativeService) BuildNarrativeContext(ctx context.Context, profileID
 		var lines []string
 		sectionTitle := resolveSectionTitle(isBenign, allMemories)
 		for _, m := range allMemories {
+			format := "- [TEMA: %s | Hace %s] %s"
+			if m.Kind == domain.MemoryKindReflection {
+				format = "- [REFLEXION | TEMA: %s | Hace %s] %s"
+			}
 			lines = append(lines, fmt.Sprintf(
-				"- [TEMA: %s | Hace %s] %s",
+				format,
 				strings.ToUpper(m.EmotionCategory),
 				humanizeRelative(m.HappenedAt),
 				m.Content,
@@ -309,6 +433,38 @@ func (s *NarrativeService) BuildNarrativeContext(ctx context.Context, profileID
 
 // --- helpers y servicios auxiliares ---
 
+// markRecalled registra en un solo lote las memorias evocadas en el turno.
+// Es best-effort: un fallo del refuerzo no debe romper la respuesta del clon.
+func (s *NarrativeService) markRecalled(ctx context.Context, memories []domain.NarrativeMemory) {
+	if len(memories) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(memories))
+	for _, m := range memories {
+		ids = append(ids, m.ID)
+	}
+	if err := s.memoryRepo.MarkRecalled(ctx, ids, time.Now().UTC()); err != nil {
+		log.Printf("warning: mark recalled memories: %v", err)
+	}
+}
+
+// recalledMemories devuelve las memorias evocadas (por evocacion/similitud) que llegaron al contexto.
+// La working memory y las memorias forzadas por el personaje activo entran siempre: contarlas
+// como evocaciones las reforzaria en cada turno y nunca decaerian.
+func recalledMemories(evoked, inContext []domain.NarrativeMemory) []domain.NarrativeMemory {
+	kept := make(map[uuid.UUID]struct{}, len(inContext))
+	for _, m := range inContext {
+		kept[m.ID] = struct{}{}
+	}
+	var out []domain.NarrativeMemory
+	for _, m := range evoked {
+		if _, ok := kept[m.ID]; ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
 func mergeDedupMemories(primary, secondary []domain.NarrativeMemory) []domain.NarrativeMemory {
 	seen := make(map[uuid.UUID]struct{})
 	out := make([]domain.NarrativeMemory, 0, len(primary)+len(secondary))
@@ -330,6 +486,59 @@ func mergeDedupMemories(primary, secondary []domain.NarrativeMemory) []domain.Na
 	return out
 }
 
+// preferReflections pone las reflexiones primero (para que sobrevivan al recorte de contexto)
+// y descarta los episodios crudos que ya estan resumidos por una reflexion presente.
+func preferReflections(memories []domain.NarrativeMemory) []domain.NarrativeMemory {
+	covered := make(map[uuid.UUID]struct{})
+	reflections := 0
+	for _, m := range memories {
+		if m.Kind != domain.MemoryKindReflection {
+			continue
+		}
+		reflections++
+		for _, id := range m.SourceMemoryIDs {
+			covered[id] = struct{}{}
+		}
+	}
+	if reflections == 0 {
+		return memories
+	}
+
+	out := make([]domain.NarrativeMemory, 0, len(memories))
+	for _, m := range memories {
+		if m.Kind == domain.MemoryKindReflection {
+			out = append(out, m)
+		}
+	}
+	for _, m := range memories {
+		if m.Kind == domain.MemoryKindReflection {
+			continue
+		}
+		if _, ok := covered[m.ID]; ok {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func containsCharacter(chars []domain.Character, id uuid.UUID) bool {
+	for _, c := range chars {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func isFocusMemory(m domain.NarrativeMemory, focus map[uuid.UUID]struct{}) bool {
+	if m.RelatedCharacterID == nil {
+		return false
+	}
+	_, ok := focus[*m.RelatedCharacterID]
+	return ok
+}
+
 func limitMemories(memories []domain.NarrativeMemory, n int) []domain.NarrativeMemory {
 	if n <= 0 || len(memories) <= n {
 		return memories
@@ -346,7 +555,7 @@ func (s *NarrativeService) generateEvocation(ctx context.Context, userMessage st
 		return ""
 	}
 
-	resp, err := s.llmClient.Generate(ctx, fmt.Sprintf(evocationPromptTemplate, userMessage))
+	resp, err := s.evocationLLM().Generate(ctx, fmt.Sprintf(evocationPromptTemplate, userMessage))
 	if err == nil {
 		clean := strings.TrimSpace(resp)
 		if clean != "" {
@@ -354,7 +563,7 @@ func (s *NarrativeService) generateEvocation(ctx context.Context, userMessage st
 		}
 	}
 
-	resp, err = s.llmClient.Generate(ctx, fmt.Sprintf(evocationFallbackPrompt, userMessage))
+	resp, err = s.evocationLLM().Generate(ctx, fmt.Sprintf(evocationFallbackPrompt, userMessage))
 	if err != nil {
 		return ""
 	}
@@ -365,7 +574,7 @@ func (s *NarrativeService) judgeMemory(ctx context.Context, userMessage, memoryC
 	if s == nil || s.llmClient == nil {
 		return false, "", ErrNarrativeServiceNotConfigured
 	}
-	resp, err := s.llmClient.Generate(ctx, fmt.Sprintf(rerankJudgePrompt, userMessage, memoryContent))
+	resp, err := s.judgeLLM().Generate(ctx, fmt.Sprintf(rerankJudgePrompt, userMessage, memoryContent))
 	if err != nil {
 		return false, "", err
 	}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	pgvector "github.com/pgvector/pgvector-go"
 
 	"clone-llm/internal/domain"
@@ -234,6 +235,9 @@ func (f fakeLLM) Generate(ctx context.Context, prompt string) (string, error) {
 	}
 	return "evocacion simple", nil
 }
+func (f fakeLLM) GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error) {
+	return f.Generate(ctx, prompt)
+}
 
 type fakeCharacterRepo struct {
 	chars []domain.Character
@@ -247,6 +251,15 @@ func (f *fakeCharacterRepo) Create(ctx context.Context, character domain.Charact
 
 func (f *fakeCharacterRepo) Update(ctx context.Context, character domain.Character) error { return nil }
 
+func (f *fakeCharacterRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Character, error) {
+	for i := range f.chars {
+		if f.chars[i].ID == id {
+			return &f.chars[i], nil
+		}
+	}
+	return nil, nil
+}
+
 func (f *fakeCharacterRepo) ListByProfileID(ctx context.Context, profileID uuid.UUID) ([]domain.Character, error) {
 	return f.chars, nil
 }
@@ -260,6 +273,8 @@ func (f *fakeCharacterRepo) FindByName(ctx context.Context, profileID uuid.UUID,
 	return nil, nil
 }
 
+func (f *fakeCharacterRepo) Delete(context.Context, uuid.UUID) error { return nil }
+
 type fakeMemoryRepo struct {
 	wm     []domain.NarrativeMemory
 	search []repository.ScoredMemory
@@ -271,7 +286,7 @@ func (f fakeMemoryRepo) Search(ctx context.Context, profileID uuid.UUID, queryEm
 	return f.search, nil
 }
 
-func (f fakeMemoryRepo) ListByCharacter(ctx context.Context, characterID uuid.UUID) ([]domain.NarrativeMemory, error) {
+func (f fakeMemoryRepo) ListByCharacter(ctx context.Context, characterID uuid.UUID, limit int) ([]domain.NarrativeMemory, error) {
 	return nil, nil
 }
 
@@ -279,6 +294,30 @@ func (f fakeMemoryRepo) GetRecentHighImpactByProfile(ctx context.Context, profil
 	return f.wm, nil
 }
 
+func (f fakeMemoryRepo) ListBySourceSession(context.Context, string) ([]domain.NarrativeMemory, error) {
+	return nil, nil
+}
+
+func (f fakeMemoryRepo) List(context.Context, uuid.UUID, repository.MemoryFilter) ([]domain.NarrativeMemory, error) {
+	return nil, nil
+}
+
+func (f fakeMemoryRepo) GetByID(context.Context, uuid.UUID) (*domain.NarrativeMemory, error) {
+	return nil, pgx.ErrNoRows
+}
+
+func (f fakeMemoryRepo) Update(context.Context, domain.NarrativeMemory) error { return nil }
+
+func (f fakeMemoryRepo) Delete(context.Context, uuid.UUID) error { return nil }
+
+func (f fakeMemoryRepo) MarkRecalled(context.Context, []uuid.UUID, time.Time) error { return nil }
+
+func (f fakeMemoryRepo) FindNearest(context.Context, uuid.UUID, *uuid.UUID, pgvector.Vector) (*repository.ScoredMemory, error) {
+	return nil, nil
+}
+
+func (f fakeMemoryRepo) MergeOccurrence(context.Context, domain.NarrativeMemory) error { return nil }
+
 func newNarrativeServiceTestHarness(wm []domain.NarrativeMemory, search []repository.ScoredMemory) *NarrativeService {
 	charID := uuid.New()
 	charRepo := &fakeCharacterRepo{chars: []domain.Character{
@@ -308,3 +347,90 @@ func (f fakeSilentLLM) Generate(ctx context.Context, prompt string) (string, err
 	}
 	return "", nil
 }
+func (f fakeSilentLLM) GenerateStream(ctx context.Context, prompt string, onChunk func(string) error) (string, error) {
+	return f.Generate(ctx, prompt)
+}
+
+// recallMemoryRepo registra las llamadas a MarkRecalled.
+type recallMemoryRepo struct {
+	fakeMemoryRepo
+	calls [][]uuid.UUID
+}
+
+func (f *recallMemoryRepo) MarkRecalled(_ context.Context, ids []uuid.UUID, _ time.Time) error {
+	f.calls = append(f.calls, ids)
+	return nil
+}
+
+func TestBuildNarrativeContext_MarksOnlyEvokedMemoriesInOneBatch(t *testing.T) {
+	profileID := uuid.New()
+	now := time.Now()
+	wm := []domain.NarrativeMemory{
+		{ID: uuid.New(), CloneProfileID: profileID, Content: "Conflicto 1", EmotionCategory: "IRA", HappenedAt: now},
+		{ID: uuid.New(), CloneProfileID: profileID, Content: "Conflicto 2", EmotionCategory: "IRA", HappenedAt: now},
+	}
+	evoked := domain.NarrativeMemory{ID: uuid.New(), CloneProfileID: profileID, Content: "Paseo por la playa", EmotionCategory: "ALEGRIA", HappenedAt: now}
+	repo := &recallMemoryRepo{fakeMemoryRepo: fakeMemoryRepo{
+		wm:     wm,
+		search: []repository.ScoredMemory{{NarrativeMemory: evoked, Similarity: 0.9, Score: 0.9}},
+	}}
+	svc := &NarrativeService{characterRepo: &fakeCharacterRepo{}, memoryRepo: repo, llmClient: fakeLLM{}}
+
+	text, err := svc.BuildNarrativeContext(context.Background(), profileID, "que dia largo")
+	if err != nil {
+		t.Fatalf("BuildNarrativeContext returned error: %v", err)
+	}
+	if !strings.Contains(text, "Conflicto 1") || !strings.Contains(text, "Paseo por la playa") {
+		t.Fatalf("expected working and evoked memories in context, got %q", text)
+	}
+	if len(repo.calls) != 1 || len(repo.calls[0]) != 1 || repo.calls[0][0] != evoked.ID {
+		t.Fatalf("expected one batch with only the evoked memory, got %+v", repo.calls)
+	}
+
+	repo.calls = nil
+	repo.search = nil
+	if _, err := svc.BuildNarrativeContext(context.Background(), profileID, "que dia largo"); err != nil {
+		t.Fatalf("BuildNarrativeContext returned error: %v", err)
+	}
+	if len(repo.calls) != 0 {
+		t.Fatalf("working memory alone must not count as a recall, got %+v", repo.calls)
+	}
+}
+
+// recordingGenerator responde siempre reply y guarda los prompts recibidos.
+type recordingGenerator struct {
+	reply   string
+	prompts []string
+}
+
+func (g *recordingGenerator) Generate(_ context.Context, prompt string) (string, error) {
+	g.prompts = append(g.prompts, prompt)
+	return g.reply, nil
+}
+
+func TestNarrativeService_AuxiliaryCallsUseRoleClients(t *testing.T) {
+	ctx := context.Background()
+	svc := &NarrativeService{llmClient: fakeLLM{}}
+	if got := svc.generateEvocation(ctx, "me acorde de la playa"); got != "evocacion simple" {
+		t.Fatalf("expected fallback to main client, got %q", got)
+	}
+
+	evocation := &recordingGenerator{reply: "evocacion barata"}
+	judge := &recordingGenerator{reply: `{"use": false, "reason": "no aplica"}`}
+	svc.SetEvocationClient(evocation)
+	svc.SetJudgeClient(judge)
+
+	if got := svc.generateEvocation(ctx, "me acorde de la playa"); got != "evocacion barata" || len(evocation.prompts) != 1 {
+		t.Fatalf("expected evocation client, got %q (calls=%d)", got, len(evocation.prompts))
+	}
+	use, _, err := svc.judgeMemory(ctx, "me acorde de la playa", "Vacaciones en la costa")
+	if err != nil || use || len(judge.prompts) != 1 {
+		t.Fatalf("expected judge client verdict, got use=%v err=%v (calls=%d)", use, err, len(judge.prompts))
+	}
+
+	judge.reply = `{"character": "Ana"}`
+	chars := []domain.Character{{ID: uuid.New(), Name: "Ana", Relation: "hermana"}}
+	if got := svc.guessInterlocutor(ctx, chars, "me volvio a llamar"); got == nil || got.Name != "Ana" || len(judge.prompts) != 2 {
+		t.Fatalf("expected interlocutor guess from judge client, got %+v (calls=%d)", got, len(judge.prompts))
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+)
+
+// defaultBig5Value es el valor neutro que asumimos cuando un rasgo aun no fue inferido.
+const defaultBig5Value = 50
+
+// ProfileHydrator carga perfiles desde la base y completa Big5 a partir de los rasgos persistidos.
+type ProfileHydrator struct {
+	profileRepo repository.ProfileRepository
+	traitRepo   repository.TraitRepository
+}
+
+var ErrProfileHydratorNotConfigured = errors.New("profile hydrator not configured")
+
+func NewProfileHydrator(profileRepo repository.ProfileRepository, traitRepo repository.TraitRepository) *ProfileHydrator {
+	return &ProfileHydrator{
+		profileRepo: profileRepo,
+		traitRepo:   traitRepo,
+	}
+}
+
+// GetByID devuelve el perfil con Big5 hidratado y la lista completa de rasgos.
+func (h *ProfileHydrator) GetByID(ctx context.Context, id string) (domain.CloneProfile, []domain.Trait, error) {
+	if h == nil || h.profileRepo == nil || h.traitRepo == nil {
+		return domain.CloneProfile{}, nil, ErrProfileHydratorNotConfigured
+	}
+	profile, err := h.profileRepo.GetByID(ctx, id)
+	if err != nil {
+		return domain.CloneProfile{}, nil, err
+	}
+	return h.hydrate(ctx, profile)
+}
+
+// GetByUserID devuelve el perfil del usuario con Big5 hidratado y la lista completa de rasgos.
+func (h *ProfileHydrator) GetByUserID(ctx context.Context, userID string) (domain.CloneProfile, []domain.Trait, error) {
+	if h == nil || h.profileRepo == nil || h.traitRepo == nil {
+		return domain.CloneProfile{}, nil, ErrProfileHydratorNotConfigured
+	}
+	profile, err := h.profileRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return domain.CloneProfile{}, nil, err
+	}
+	return h.hydrate(ctx, profile)
+}
+
+func (h *ProfileHydrator) hydrate(ctx context.Context, profile domain.CloneProfile) (domain.CloneProfile, []domain.Trait, error) {
+	traits, err := h.traitRepo.FindByProfileID(ctx, profile.ID)
+	if err != nil {
+		return domain.CloneProfile{}, nil, fmt.Errorf("get traits: %w", err)
+	}
+	HydrateBig5(&profile, traits)
+	return profile, traits, nil
+}
+
+// HydrateBig5 vuelca los rasgos BIG_FIVE en profile.Big5.
+// Cada valor se pondera por su confianza contra el neutro (50): confianza 1 (o nula) respeta el valor,
+// confianza 0 lo deja en neutro. Los rasgos faltantes quedan en neutro.
+// Devuelve true si se encontro al menos un rasgo BIG_FIVE.
+func HydrateBig5(profile *domain.CloneProfile, traits []domain.Trait) bool {
+	if profile == nil {
+		return false
+	}
+
+	big5 := domain.Big5Profile{
+		Openness:          defaultBig5Value,
+		Conscientiousness: defaultBig5Value,
+		Extraversion:      defaultBig5Value,
+		Agreeableness:     defaultBig5Value,
+		Neuroticism:       defaultBig5Value,
+	}
+
+	found := false
+	for _, t := range traits {
+		if !strings.EqualFold(strings.TrimSpace(t.Category), domain.TraitCategoryBigFive) {
+			continue
+		}
+		value := weightTraitByConfidence(t.Value, t.Confidence)
+		switch strings.ToLower(strings.TrimSpace(t.Trait)) {
+		case "openness":
+			big5.Openness = value
+		case "conscientiousness":
+			big5.Conscientiousness = value
+		case "extraversion":
+			big5.Extraversion = value
+		case "agreeableness":
+			big5.Agreeableness = value
+		case "neuroticism":
+			big5.Neuroticism = value
+		default:
+			continue
+		}
+		found = true
+	}
+
+	profile.Big5 = big5
+	return found
+}
+
+func weightTraitByConfidence(value int, confidence *float64) int {
+	if value < 0 {
+		value = 0
+	}
+	if value > 100 {
+		value = 100
+	}
+	weight := 1.0
+	if confidence != nil {
+		weight = *confidence
+		if math.IsNaN(weight) || weight < 0 {
+			weight = 0
+		}
+		if weight > 1 {
+			weight = 1
+		}
+	}
+	return int(math.Round(defaultBig5Value + float64(value-defaultBig5Value)*weight))
+}
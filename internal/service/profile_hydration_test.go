@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"clone-llm/internal/domain"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestHydrateBig5_WeightsByConfidenceAndDefaults(t *testing.T) {
+	profile := domain.CloneProfile{ID: "p1"}
+	traits := []domain.Trait{
+		{Category: domain.TraitCategoryBigFive, Trait: "Neuroticism", Value: 90, Confidence: floatPtr(0.5)},
+		{Category: domain.TraitCategoryBigFive, Trait: "extraversion", Value: 20},
+		{Category: domain.TraitCategoryBigFive, Trait: "openness", Value: 100, Confidence: floatPtr(0)},
+		{Category: domain.TraitCategoryValues, Trait: "agreeableness", Value: 0},
+	}
+
+	if !HydrateBig5(&profile, traits) {
+		t.Fatalf("expected BIG_FIVE traits to be found")
+	}
+
+	want := domain.Big5Profile{
+		Openness:          50,
+		Conscientiousness: 50,
+		Extraversion:      20,
+		Agreeableness:     50,
+		Neuroticism:       70,
+	}
+	if profile.Big5 != want {
+		t.Fatalf("unexpected Big5: got %+v want %+v", profile.Big5, want)
+	}
+}
+
+func TestHydrateBig5_NoTraitsUsesNeutralDefaults(t *testing.T) {
+	profile := domain.CloneProfile{}
+	if HydrateBig5(&profile, nil) {
+		t.Fatalf("expected no BIG_FIVE traits")
+	}
+	if profile.Big5.Neuroticism != defaultBig5Value || profile.Big5.Openness != defaultBig5Value {
+		t.Fatalf("expected neutral defaults, got %+v", profile.Big5)
+	}
+	if HydrateBig5(nil, nil) {
+		t.Fatalf("nil profile must not report traits")
+	}
+}
+
+func TestProfileHydratorGetByUserID(t *testing.T) {
+	profileRepo := &mockCloneProfileRepo{profile: domain.CloneProfile{ID: "p1", Name: "Clon"}}
+	traitRepo := &mockCloneTraitRepo{traits: []domain.Trait{
+		{Category: domain.TraitCategoryBigFive, Trait: "neuroticism", Value: 10, Confidence: floatPtr(1)},
+	}}
+	hydrator := NewProfileHydrator(profileRepo, traitRepo)
+
+	profile, traits, err := hydrator.GetByUserID(context.Background(), "u1")
+	if err != nil {
+		t.Fatalf("GetByUserID returned error: %v", err)
+	}
+	if len(traits) != 1 || profile.Big5.Neuroticism != 10 {
+		t.Fatalf("expected hydrated neuroticism, got %+v (traits=%d)", profile.Big5, len(traits))
+	}
+	if profile.GetResilience() <= 0.5 {
+		t.Fatalf("expected low neuroticism to raise resilience, got %.2f", profile.GetResilience())
+	}
+
+	var nilHydrator *ProfileHydrator
+	if _, _, err := nilHydrator.GetByID(context.Background(), "p1"); err != ErrProfileHydratorNotConfigured {
+		t.Fatalf("expected ErrProfileHydratorNotConfigured, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgvector "github.com/pgvector/pgvector-go"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/repository"
+)
+
+const (
+	defaultReembedBatch = 100
+	// reembedMaxPasses acota cuantas veces se reintenta el cambio de columna si la API sigue creando
+	// memorias con el modelo viejo mientras corre el comando.
+	reembedMaxPasses = 5
+)
+
+var (
+	ErrReembedNotConfigured = errors.New("reembed service not configured")
+	// ErrEmbeddingDimensionMismatch indica que EMBEDDING_DIMENSIONS no coincide con la columna vectorial.
+	ErrEmbeddingDimensionMismatch = errors.New("embedding dimension does not match vector column")
+)
+
+// EmbeddingSpec es el modelo de embeddings configurado y la dimension de sus vectores.
+type EmbeddingSpec struct {
+	Model     string
+	Dimension int
+}
+
+// CheckEmbeddingStore valida al arrancar que la columna vectorial tenga la dimension configurada
+// (si no, ningun insert funcionaria) y devuelve cuantas memorias tienen embeddings de otro modelo:
+// siguen funcionando pero la busqueda las compara mal hasta correr cmd/reembed.
+func CheckEmbeddingStore(ctx context.Context, repo repository.MemoryEmbeddingRepository, spec EmbeddingSpec) (int, error) {
+	dim, err := repo.EmbeddingDimension(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("read embedding dimension: %w", err)
+	}
+	if dim != spec.Dimension {
+		return 0, fmt.Errorf("%w: column has %d, EMBEDDING_DIMENSIONS is %d (run cmd/reembed)", ErrEmbeddingDimensionMismatch, dim, spec.Dimension)
+	}
+	stale, err := repo.CountStaleEmbeddings(ctx, spec.Model)
+	if err != nil {
+		return 0, fmt.Errorf("count stale embeddings: %w", err)
+	}
+	return stale, nil
+}
+
+// ReembedResult resume una corrida del comando.
+type ReembedResult struct {
+	Reembedded int
+	Passes     int
+}
+
+// ReembedService regenera el embedding de todas las narrative_memories con el modelo configurado.
+// Trabaja por lotes sobre una columna auxiliar, asi la API puede seguir respondiendo con los
+// vectores viejos, y al final reemplaza la columna de una vez. Si se interrumpe, la proxima corrida
+// retoma las memorias que faltan.
+type ReembedService struct {
+	llmClient llmClientWithEmbedding
+	repo      repository.MemoryEmbeddingRepository
+	spec      EmbeddingSpec
+	batchSize int
+	logger    *zap.Logger
+}
+
+func NewReembedService(llmClient llmClientWithEmbedding, repo repository.MemoryEmbeddingRepository, spec EmbeddingSpec, batchSize int, logger *zap.Logger) *ReembedService {
+	if batchSize <= 0 {
+		batchSize = defaultReembedBatch
+	}
+	return &ReembedService{
+		llmClient: llmClient,
+		repo:      repo,
+		spec:      spec,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Run regenera los embeddings pendientes y reemplaza la columna. Un error de embedding corta la
+// corrida: lo ya procesado queda guardado.
+func (s *ReembedService) Run(ctx context.Context) (ReembedResult, error) {
+	var result ReembedResult
+	if s == nil || s.llmClient == nil || s.repo == nil || s.spec.Model == "" || s.spec.Dimension <= 0 {
+		return result, ErrReembedNotConfigured
+	}
+
+	if err := s.repo.PrepareReembed(ctx, s.spec.Dimension); err != nil {
+		return result, fmt.Errorf("prepare reembed: %w", err)
+	}
+
+	for result.Passes < reembedMaxPasses {
+		result.Passes++
+		n, err := s.reembedPending(ctx)
+		result.Reembedded += n
+		if err != nil {
+			return result, err
+		}
+
+		err = s.repo.FinishReembed(ctx, s.spec.Model)
+		if errors.Is(err, repository.ErrReembedPending) {
+			if s.logger != nil {
+				s.logger.Info("new memories arrived during reembed, running another pass", zap.Error(err))
+			}
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("finish reembed: %w", err)
+		}
+		return result, nil
+	}
+	return result, fmt.Errorf("%w after %d passes", repository.ErrReembedPending, result.Passes)
+}
+
+// reembedPending recorre por id las memorias sin embedding nuevo.
+func (s *ReembedService) reembedPending(ctx context.Context) (int, error) {
+	done := 0
+	afterID := uuid.Nil
+	for {
+		batch, err := s.repo.ListPendingReembed(ctx, s.spec.Model, afterID, s.batchSize)
+		if err != nil {
+			return done, fmt.Errorf("list pending reembed: %w", err)
+		}
+		for _, m := range batch {
+			embed, err := s.llmClient.CreateEmbedding(ctx, m.Content)
+			if err != nil {
+				return done, fmt.Errorf("create embedding for %s: %w", m.ID, err)
+			}
+			if len(embed) != s.spec.Dimension {
+				return done, fmt.Errorf("%w: model returned %d, expected %d", ErrEmbeddingDimensionMismatch, len(embed), s.spec.Dimension)
+			}
+			if err := s.repo.SetNextEmbedding(ctx, m.ID, m.Content, pgvector.NewVector(embed), s.spec.Model); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					// Se edito o se borro despues de listarla: si sigue existiendo, FinishReembed
+					// la reporta pendiente y la proxima pasada la embebe con el texto nuevo.
+					continue
+				}
+				return done, fmt.Errorf("store embedding for %s: %w", m.ID, err)
+			}
+			done++
+		}
+		if len(batch) > 0 && s.logger != nil {
+			s.logger.Info("reembed batch done", zap.Int("reembedded", done))
+		}
+		if len(batch) < s.batchSize {
+			return done, nil
+		}
+		afterID = batch[len(batch)-1].ID
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"clone-llm/internal/repository"
+)
+
+type fakeEmbeddingRepo struct {
+	dimension    int
+	stale        int
+	memories     []repository.ReembedCandidate // ordenadas por id
+	next         map[uuid.UUID]string          // id -> modelo del embedding nuevo
+	preparedDim  int
+	finished     bool
+	onFirstCheck func(f *fakeEmbeddingRepo) // simula memorias creadas durante el re-embedding
+	onFirstSet   func(f *fakeEmbeddingRepo) // simula una edicion entre el listado y el guardado
+	embedded     map[uuid.UUID]string       // id -> texto con el que se genero el embedding nuevo
+}
+
+func (f *fakeEmbeddingRepo) EmbeddingDimension(context.Context) (int, error) { return f.dimension, nil }
+
+func (f *fakeEmbeddingRepo) CountStaleEmbeddings(context.Context, string) (int, error) {
+	return f.stale, nil
+}
+
+func (f *fakeEmbeddingRepo) PrepareReembed(_ context.Context, dimension int) error {
+	f.preparedDim = dimension
+	return nil
+}
+
+func (f *fakeEmbeddingRepo) ListPendingReembed(_ context.Context, model string, afterID uuid.UUID, limit int) ([]repository.ReembedCandidate, error) {
+	var out []repository.ReembedCandidate
+	for _, m := range f.memories {
+		if afterID != uuid.Nil && m.ID.String() <= afterID.String() {
+			continue
+		}
+		if f.next[m.ID] == model {
+			continue
+		}
+		out = append(out, m)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEmbeddingRepo) SetNextEmbedding(_ context.Context, id uuid.UUID, content string, _ pgvector.Vector, model string) error {
+	if f.onFirstSet != nil {
+		hook := f.onFirstSet
+		f.onFirstSet = nil
+		hook(f)
+	}
+	for _, m := range f.memories {
+		if m.ID == id && m.Content == content {
+			f.next[id] = model
+			if f.embedded != nil {
+				f.embedded[id] = content
+			}
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+// edit simula PgMemoryRepository.Update durante un re-embedding: cambia el texto y descarta el embedding nuevo.
+func (f *fakeEmbeddingRepo) edit(i int, content string) {
+	f.memories[i].Content = content
+	delete(f.next, f.memories[i].ID)
+}
+
+func (f *fakeEmbeddingRepo) FinishReembed(_ context.Context, model string) error {
+	if f.onFirstCheck != nil {
+		hook := f.onFirstCheck
+		f.onFirstCheck = nil
+		hook(f)
+	}
+	for _, m := range f.memories {
+		if f.next[m.ID] != model {
+			return repository.ErrReembedPending
+		}
+	}
+	f.finished = true
+	return nil
+}
+
+func (f *fakeEmbeddingRepo) add(content string) {
+	f.memories = append(f.memories, repository.ReembedCandidate{ID: uuid.New(), Content: content})
+	sort.Slice(f.memories, func(i, j int) bool { return f.memories[i].ID.String() < f.memories[j].ID.String() })
+}
+
+func TestReembedService_ReembedsInBatchesAndRetriesNewMemories(t *testing.T) {
+	repo := &fakeEmbeddingRepo{next: map[uuid.UUID]string{}}
+	for _, c := range []string{"a", "b", "c", "d", "e"} {
+		repo.add(c)
+	}
+	repo.onFirstCheck = func(f *fakeEmbeddingRepo) { f.add("creada durante el re-embedding") }
+
+	svc := NewReembedService(actionFakeLLM{embedding: []float32{0.1, 0.2, 0.3}}, repo, EmbeddingSpec{Model: "nomic-embed-text", Dimension: 3}, 2, nil)
+	res, err := svc.Run(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if repo.preparedDim != 3 {
+		t.Fatalf("expected staging column with dimension 3, got %d", repo.preparedDim)
+	}
+	if res.Reembedded != 6 || res.Passes != 2 || !repo.finished {
+		t.Fatalf("unexpected result %+v (finished=%v)", res, repo.finished)
+	}
+}
+
+func TestReembedService_ReembedsMemoriesEditedMidway(t *testing.T) {
+	repo := &fakeEmbeddingRepo{next: map[uuid.UUID]string{}, embedded: map[uuid.UUID]string{}}
+	for _, c := range []string{"a", "b", "c"} {
+		repo.add(c)
+	}
+	repo.onFirstSet = func(f *fakeEmbeddingRepo) { f.edit(0, "editada") }
+
+	svc := NewReembedService(actionFakeLLM{embedding: []float32{0.1, 0.2, 0.3}}, repo, EmbeddingSpec{Model: "m", Dimension: 3}, 10, nil)
+	res, err := svc.Run(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Passes != 2 || !repo.finished {
+		t.Fatalf("expected a second pass for the edited memory, got %+v (finished=%v)", res, repo.finished)
+	}
+	if got := repo.embedded[repo.memories[0].ID]; got != "editada" {
+		t.Fatalf("expected the edited text to be embedded, got %q", got)
+	}
+}
+
+func TestReembedService_RejectsWrongDimension(t *testing.T) {
+	repo := &fakeEmbeddingRepo{next: map[uuid.UUID]string{}}
+	repo.add("a")
+
+	svc := NewReembedService(actionFakeLLM{embedding: []float32{0.1, 0.2}}, repo, EmbeddingSpec{Model: "m", Dimension: 3}, 10, nil)
+	if _, err := svc.Run(context.Background()); !errors.Is(err, ErrEmbeddingDimensionMismatch) {
+		t.Fatalf("expected ErrEmbeddingDimensionMismatch, got %v", err)
+	}
+	if repo.finished {
+		t.Fatalf("column must not be swapped after a failed pass")
+	}
+}
+
+func TestCheckEmbeddingStore(t *testing.T) {
+	repo := &fakeEmbeddingRepo{dimension: 1536, stale: 4}
+	if _, err := CheckEmbeddingStore(context.Background(), repo, EmbeddingSpec{Model: "nomic-embed-text", Dimension: 768}); !errors.Is(err, ErrEmbeddingDimensionMismatch) {
+		t.Fatalf("expected ErrEmbeddingDimensionMismatch, got %v", err)
+	}
+
+	stale, err := CheckEmbeddingStore(context.Background(), repo, EmbeddingSpec{Model: "nomic-embed-text", Dimension: 1536})
+	if err != nil || stale != 4 {
+		t.Fatalf("expected 4 stale memories, got %d %v", stale, err)
+	}
+}
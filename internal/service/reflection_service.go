@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+)
+
+const (
+	defaultReflectionWindow      = 7 * 24 * time.Hour
+	defaultReflectionMinMemories = 5
+	// reflectionMaxMemories acota cuantas memorias de un personaje entran a un mismo prompt.
+	reflectionMaxMemories = 20
+	// reflectionMaxInsights evita que una respuesta verborragica llene la memoria de conclusiones.
+	reflectionMaxInsights = 3
+)
+
+var ErrReflectionNotConfigured = errors.New("reflection service not configured")
+
+// ReflectionConfig define que memorias se agrupan para reflexionar.
+type ReflectionConfig struct {
+	// Window: solo se reflexiona sobre memorias creadas en esta ventana.
+	Window time.Duration
+	// MinMemories por personaje antes de pedir una reflexion.
+	MinMemories int
+}
+
+// ReflectionResult resume una pasada del job.
+type ReflectionResult struct {
+	Groups      int
+	Reflections int
+}
+
+// ReflectionService agrupa las memorias recientes de cada personaje y le pide al LLM conclusiones
+// de mas alto nivel ("siento que Juan me evita cuando hablo de su trabajo"). Cada conclusion se
+// guarda como memoria de tipo reflection que apunta a sus memorias de origen; la recuperacion
+// prefiere estas reflexiones a los episodios crudos que resumen.
+type ReflectionService struct {
+	llmClient     llmClientWithEmbedding
+	repo          repository.MemoryReflectionRepository
+	memoryRepo    repository.MemoryRepository
+	characterRepo repository.CharacterRepository
+	cfg           ReflectionConfig
+	logger        *zap.Logger
+	cache         NarrativeCache
+	now           func() time.Time
+}
+
+func NewReflectionService(
+	llmClient llmClientWithEmbedding,
+	repo repository.MemoryReflectionRepository,
+	memoryRepo repository.MemoryRepository,
+	characterRepo repository.CharacterRepository,
+	cfg ReflectionConfig,
+	logger *zap.Logger,
+) *ReflectionService {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultReflectionWindow
+	}
+	if cfg.MinMemories <= 0 {
+		cfg.MinMemories = defaultReflectionMinMemories
+	}
+	return &ReflectionService{
+		llmClient:     llmClient,
+		repo:          repo,
+		memoryRepo:    memoryRepo,
+		characterRepo: characterRepo,
+		cfg:           cfg,
+		logger:        logger,
+		now:           time.Now,
+	}
+}
+
+// SetCache registra el cache narrativo a invalidar para los clones que ganaron reflexiones.
+func (s *ReflectionService) SetCache(cache NarrativeCache) { s.cache = cache }
+
+// RunOnce reflexiona sobre cada personaje con suficientes memorias pendientes. Un grupo que falla
+// se registra y queda pendiente para la proxima pasada; no frena a los demas.
+func (s *ReflectionService) RunOnce(ctx context.Context) (ReflectionResult, error) {
+	var result ReflectionResult
+	if s == nil || s.llmClient == nil || s.repo == nil || s.memoryRepo == nil {
+		return result, ErrReflectionNotConfigured
+	}
+
+	now := s.now().UTC()
+	since := now.Add(-s.cfg.Window)
+	groups, err := s.repo.ListReflectionGroups(ctx, since, s.cfg.MinMemories)
+	if err != nil {
+		return result, fmt.Errorf("list reflection groups: %w", err)
+	}
+
+	for _, g := range groups {
+		created, err := s.reflectGroup(ctx, g, since, now)
+		result.Reflections += created
+		if created > 0 && s.cache != nil {
+			s.cache.InvalidateProfile(g.CloneProfileID)
+		}
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Warn("reflection failed", zap.Error(err), zap.String("profile_id", g.CloneProfileID.String()), zap.String("character_id", g.CharacterID.String()))
+			}
+			continue
+		}
+		result.Groups++
+	}
+	return result, nil
+}
+
+// Run ejecuta RunOnce cada interval hasta que se cancele ctx.
+func (s *ReflectionService) Run(ctx context.Context, interval time.Duration) {
+	if s == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := s.RunOnce(ctx)
+			if err != nil && s.logger != nil {
+				s.logger.Warn("reflection run failed", zap.Error(err))
+			} else if res.Reflections > 0 && s.logger != nil {
+				s.logger.Info("reflections created", zap.Int("groups", res.Groups), zap.Int("reflections", res.Reflections))
+			}
+		}
+	}
+}
+
+// reflectGroup pide las conclusiones de un personaje, las guarda y marca el lote como reflexionado.
+func (s *ReflectionService) reflectGroup(ctx context.Context, g repository.ReflectionGroup, since, now time.Time) (int, error) {
+	memories, err := s.repo.ListPendingReflection(ctx, g.CloneProfileID, g.CharacterID, since, reflectionMaxMemories)
+	if err != nil {
+		return 0, fmt.Errorf("list pending memories: %w", err)
+	}
+	if len(memories) < s.cfg.MinMemories {
+		return 0, nil
+	}
+
+	name := "esta persona"
+	if s.characterRepo != nil {
+		if char, err := s.characterRepo.GetByID(ctx, g.CharacterID); err == nil && char != nil && strings.TrimSpace(char.Name) != "" {
+			name = strings.TrimSpace(char.Name)
+		}
+	}
+
+	insights, err := s.generateInsights(ctx, name, memories)
+	if err != nil {
+		return 0, err
+	}
+
+	// Primero los embeddings: si alguno falla no se guardo nada y el lote se reintenta en la proxima pasada.
+	characterID := g.CharacterID
+	reflections := make([]domain.NarrativeMemory, 0, len(insights))
+	for _, in := range insights {
+		sources := resolveInsightSources(in.Sources, memories)
+		embed, err := s.llmClient.CreateEmbedding(ctx, in.Content)
+		if err != nil {
+			return 0, fmt.Errorf("create embedding: %w", err)
+		}
+		category := strings.ToUpper(strings.TrimSpace(in.EmotionCategory))
+		if category == "" {
+			category = "NEUTRAL"
+		}
+		importance, weight, intensity := reflectionWeights(sources)
+		if in.Importance > 0 {
+			importance = min(max(in.Importance, 1), 10)
+		}
+
+		ids := make([]uuid.UUID, 0, len(sources))
+		for _, m := range sources {
+			ids = append(ids, m.ID)
+		}
+		reflections = append(reflections, domain.NarrativeMemory{
+			ID:                 uuid.New(),
+			CloneProfileID:     g.CloneProfileID,
+			RelatedCharacterID: &characterID,
+			Content:            in.Content,
+			Embedding:          pgvector.NewVector(embed),
+			Importance:         importance,
+			EmotionalWeight:    weight,
+			EmotionalIntensity: intensity,
+			EmotionCategory:    category,
+			SentimentLabel:     category,
+			Kind:               domain.MemoryKindReflection,
+			SourceMemoryIDs:    ids,
+			HappenedAt:         now,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		})
+	}
+
+	created := 0
+	var storeErr error
+	for _, r := range reflections {
+		if err := s.memoryRepo.Create(ctx, r); err != nil {
+			storeErr = fmt.Errorf("store reflection: %w", err)
+			break
+		}
+		created++
+	}
+
+	// Todo el lote queda procesado aunque el LLM no haya sacado conclusiones o falle guardar alguna:
+	// reenviarlo duplicaria las reflexiones ya guardadas.
+	batch := make([]uuid.UUID, 0, len(memories))
+	for _, m := range memories {
+		batch = append(batch, m.ID)
+	}
+	if err := s.repo.MarkReflected(ctx, batch, now); err != nil {
+		return created, fmt.Errorf("mark reflected: %w", err)
+	}
+	return created, storeErr
+}
+
+// reflectionInsight es una conclusion tal como la devuelve el LLM; Sources son indices 1-based de las memorias.
+type reflectionInsight struct {
+	Content         string `json:"content"`
+	Sources         []int  `json:"sources"`
+	Importance      int    `json:"importance"`
+	EmotionCategory string `json:"emotion_category"`
+}
+
+func (s *ReflectionService) generateInsights(ctx context.Context, name string, memories []domain.NarrativeMemory) ([]reflectionInsight, error) {
+	var b strings.Builder
+	for i, m := range memories {
+		fmt.Fprintf(&b, "%d. [%s] %s\n", i+1, strings.ToUpper(strings.TrimSpace(m.EmotionCategory)), strings.TrimSpace(m.Content))
+	}
+
+	raw, err := s.llmClient.Generate(ctx, fmt.Sprintf(reflectionPromptTemplate, name, reflectionMaxInsights, b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("llm generate: %w", err)
+	}
+
+	cleaned := cleanLLMJSONResponse(raw)
+	if obj := extractFirstJSONObject(cleaned); obj != "" {
+		cleaned = obj
+	}
+	var out struct {
+		Insights []reflectionInsight `json:"insights"`
+	}
+	if err := json.Unmarshal([]byte(cleaned), &out); err != nil {
+		return nil, fmt.Errorf("parse reflection response: %w", err)
+	}
+
+	var insights []reflectionInsight
+	seen := make(map[string]struct{}, len(out.Insights))
+	for _, in := range out.Insights {
+		in.Content = strings.TrimSpace(in.Content)
+		key := strings.ToLower(in.Content)
+		if in.Content == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		insights = append(insights, in)
+		if len(insights) == reflectionMaxInsights {
+			break
+		}
+	}
+	return insights, nil
+}
+
+// resolveInsightSources traduce los indices citados por el LLM; si no cita ninguno valido,
+// la reflexion se apoya en todo el lote.
+func resolveInsightSources(indices []int, memories []domain.NarrativeMemory) []domain.NarrativeMemory {
+	var out []domain.NarrativeMemory
+	seen := make(map[int]struct{}, len(indices))
+	for _, idx := range indices {
+		if idx < 1 || idx > len(memories) {
+			continue
+		}
+		if _, ok := seen[idx]; ok {
+			continue
+		}
+		seen[idx] = struct{}{}
+		out = append(out, memories[idx-1])
+	}
+	if len(out) == 0 {
+		return memories
+	}
+	return out
+}
+
+// reflectionWeights hereda de las memorias de origen la importancia y el peso mas altos
+// y la intensidad promedio (escala 0-100).
+func reflectionWeights(sources []domain.NarrativeMemory) (importance, weight, intensity int) {
+	importance, weight = 1, 1
+	total := 0
+	for _, m := range sources {
+		importance = max(importance, m.Importance)
+		weight = max(weight, m.EmotionalWeight)
+		total += normalizeIntensity(m.EmotionalIntensity)
+	}
+	if len(sources) > 0 {
+		intensity = total / len(sources)
+	}
+	return min(importance, 10), min(weight, 10), intensity
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/llm"
+	"clone-llm/internal/repository"
+)
+
+type fakeReflectionRepo struct {
+	groups    []repository.ReflectionGroup
+	pending   []domain.NarrativeMemory
+	reflected []uuid.UUID
+}
+
+func (f *fakeReflectionRepo) ListReflectionGroups(context.Context, time.Time, int) ([]repository.ReflectionGroup, error) {
+	return f.groups, nil
+}
+
+func (f *fakeReflectionRepo) ListPendingReflection(context.Context, uuid.UUID, uuid.UUID, time.Time, int) ([]domain.NarrativeMemory, error) {
+	return f.pending, nil
+}
+
+func (f *fakeReflectionRepo) MarkReflected(_ context.Context, ids []uuid.UUID, _ time.Time) error {
+	f.reflected = append(f.reflected, ids...)
+	return nil
+}
+
+func TestReflectionService_RunOnceStoresReflectionsWithSources(t *testing.T) {
+	profileID, juanID := uuid.New(), uuid.New()
+	pending := []domain.NarrativeMemory{
+		{ID: uuid.New(), Content: "Juan cambio de tema cuando le pregunte por su trabajo", Importance: 6, EmotionalWeight: 5, EmotionalIntensity: 40, EmotionCategory: "TRISTEZA"},
+		{ID: uuid.New(), Content: "Juan no contesto cuando le hable de la oficina", Importance: 7, EmotionalWeight: 6, EmotionalIntensity: 60, EmotionCategory: "TRISTEZA"},
+		{ID: uuid.New(), Content: "Juan me invito a cenar", Importance: 4, EmotionalWeight: 3, EmotionalIntensity: 30, EmotionCategory: "ALEGRIA"},
+	}
+	repo := &fakeReflectionRepo{
+		groups:  []repository.ReflectionGroup{{CloneProfileID: profileID, CharacterID: juanID, Pending: 3}},
+		pending: pending,
+	}
+	memories := &consolidationFakeMemoryRepo{}
+	chars := &fakeCharacterRepo{chars: []domain.Character{{ID: juanID, CloneProfileID: profileID, Name: "Juan"}}}
+	llmClient := &llm.MockClient{
+		Response:  "```json\n{\"insights\": [{\"content\": \"Siento que Juan me evita cuando hablo de su trabajo\", \"sources\": [1, 2, 9], \"emotion_category\": \"tristeza\"}, {\"content\": \" \"}]}\n```",
+		Embedding: []float32{0.3},
+	}
+	svc := NewReflectionService(llmClient, repo, memories, chars, ReflectionConfig{MinMemories: 3}, nil)
+	cache := NewMemoryNarrativeCache(10, time.Hour)
+	cache.SetEvocation(evocationCacheKey(profileID, "hola"), "x")
+	svc.SetCache(cache)
+
+	res, err := svc.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Groups != 1 || res.Reflections != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	if len(memories.stored) != 1 {
+		t.Fatalf("expected one reflection, got %d", len(memories.stored))
+	}
+	r := memories.stored[0]
+	if r.Kind != domain.MemoryKindReflection || r.RelatedCharacterID == nil || *r.RelatedCharacterID != juanID || r.CloneProfileID != profileID {
+		t.Fatalf("unexpected reflection: %+v", r)
+	}
+	if len(r.SourceMemoryIDs) != 2 || r.SourceMemoryIDs[0] != pending[0].ID || r.SourceMemoryIDs[1] != pending[1].ID {
+		t.Fatalf("expected pointers to the cited memories, got %v", r.SourceMemoryIDs)
+	}
+	if r.Importance != 7 || r.EmotionalWeight != 6 || r.EmotionalIntensity != 50 || r.EmotionCategory != "TRISTEZA" {
+		t.Fatalf("unexpected reflection weights: %+v", r)
+	}
+	if len(repo.reflected) != 3 {
+		t.Fatalf("expected the whole batch to be marked reflected, got %d", len(repo.reflected))
+	}
+	if _, ok := cache.GetEvocation(evocationCacheKey(profileID, "hola")); ok {
+		t.Fatalf("expected profile cache to be invalidated")
+	}
+}
+
+// failingReflectionStore falla al guardar a partir de la reflexion numero failAt (1-based).
+type failingReflectionStore struct {
+	consolidationFakeMemoryRepo
+	failAt int
+}
+
+func (f *failingReflectionStore) Create(ctx context.Context, m domain.NarrativeMemory) error {
+	if len(f.stored)+1 >= f.failAt {
+		return errors.New("db down")
+	}
+	return f.consolidationFakeMemoryRepo.Create(ctx, m)
+}
+
+func TestReflectionService_MarksBatchEvenWhenStoringFails(t *testing.T) {
+	profileID, juanID := uuid.New(), uuid.New()
+	pending := []domain.NarrativeMemory{
+		{ID: uuid.New(), Content: "Juan llego tarde", EmotionCategory: "IRA"},
+		{ID: uuid.New(), Content: "Juan se olvido de mi cumpleanos", EmotionCategory: "TRISTEZA"},
+		{ID: uuid.New(), Content: "Juan no vino a la cena", EmotionCategory: "TRISTEZA"},
+	}
+	repo := &fakeReflectionRepo{
+		groups:  []repository.ReflectionGroup{{CloneProfileID: profileID, CharacterID: juanID, Pending: 3}},
+		pending: pending,
+	}
+	memories := &failingReflectionStore{failAt: 2}
+	llmClient := &llm.MockClient{
+		Response:  `{"insights": [{"content": "Juan no prioriza nuestros planes", "sources": [1, 3]}, {"content": "Me duele que Juan se olvide de mi", "sources": [2]}]}`,
+		Embedding: []float32{0.3},
+	}
+	svc := NewReflectionService(llmClient, repo, memories, nil, ReflectionConfig{MinMemories: 3}, nil)
+
+	res, err := svc.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Reflections != 1 || len(memories.stored) != 1 {
+		t.Fatalf("expected the first reflection to be stored, got %+v (%d stored)", res, len(memories.stored))
+	}
+	if len(repo.reflected) != 3 {
+		t.Fatalf("expected the batch to be marked so it is not reflected twice, got %d", len(repo.reflected))
+	}
+
+	// Si falla el embedding no se guarda nada y el lote queda pendiente para reintentar.
+	repo.reflected = nil
+	memories.stored = nil
+	svc.llmClient = embeddingErrorLLM{MockClient: llmClient}
+	if _, err := svc.RunOnce(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(memories.stored) != 0 || len(repo.reflected) != 0 {
+		t.Fatalf("expected nothing stored nor marked after an embedding failure, got %d stored, %d marked", len(memories.stored), len(repo.reflected))
+	}
+}
+
+// embeddingErrorLLM genera normalmente pero falla al crear embeddings.
+type embeddingErrorLLM struct {
+	*llm.MockClient
+}
+
+func (embeddingErrorLLM) CreateEmbedding(context.Context, string) ([]float32, error) {
+	return nil, errors.New("embeddings unavailable")
+}
+
+func TestReflectionService_PromptNamesCharacterAndNotConfigured(t *testing.T) {
+	var svc *ReflectionService
+	if _, err := svc.RunOnce(context.Background()); err != ErrReflectionNotConfigured {
+		t.Fatalf("expected ErrReflectionNotConfigured, got %v", err)
+	}
+
+	prompt := ""
+	llmClient := promptRecorderLLM{onGenerate: func(p string) { prompt = p }, response: `{"insights": []}`}
+	svc = NewReflectionService(llmClient, &fakeReflectionRepo{}, &consolidationFakeMemoryRepo{}, nil, ReflectionConfig{}, nil)
+	insights, err := svc.generateInsights(context.Background(), "Juan", []domain.NarrativeMemory{{Content: "Juan llego tarde", EmotionCategory: "ira"}})
+	if err != nil || len(insights) != 0 {
+		t.Fatalf("expected no insights and no error, got %v %v", insights, err)
+	}
+	if !strings.Contains(prompt, "sobre Juan") || !strings.Contains(prompt, "1. [IRA] Juan llego tarde") {
+		t.Fatalf("unexpected reflection prompt: %q", prompt)
+	}
+}
+
+func TestPreferReflections_DropsCoveredEpisodes(t *testing.T) {
+	raw1, raw2, other := domain.NarrativeMemory{ID: uuid.New()}, domain.NarrativeMemory{ID: uuid.New()}, domain.NarrativeMemory{ID: uuid.New()}
+	reflection := domain.NarrativeMemory{ID: uuid.New(), Kind: domain.MemoryKindReflection, SourceMemoryIDs: []uuid.UUID{raw1.ID, raw2.ID}}
+
+	got := preferReflections([]domain.NarrativeMemory{raw1, other, raw2, reflection})
+	if len(got) != 2 || got[0].ID != reflection.ID || got[1].ID != other.ID {
+		t.Fatalf("expected reflection first and covered episodes dropped, got %+v", got)
+	}
+
+	plain := []domain.NarrativeMemory{raw1, raw2}
+	if got := preferReflections(plain); len(got) != 2 || got[0].ID != raw1.ID {
+		t.Fatalf("expected memories untouched without reflections, got %+v", got)
+	}
+}
+
+// promptRecorderLLM devuelve una respuesta fija y entrega cada prompt a onGenerate.
+type promptRecorderLLM struct {
+	onGenerate func(string)
+	response   string
+}
+
+func (p promptRecorderLLM) Generate(_ context.Context, prompt string) (string, error) {
+	if p.onGenerate != nil {
+		p.onGenerate(prompt)
+	}
+	return p.response, nil
+}
+
+func (p promptRecorderLLM) CreateEmbedding(context.Context, string) ([]float32, error) {
+	return []float32{1}, nil
+}
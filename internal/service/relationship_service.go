@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+)
+
+const (
+	// maxRelationshipDeltaPerTurn limita cuanto puede moverse un vector en un solo turno.
+	maxRelationshipDeltaPerTurn = 10.0
+	// relationshipDampeningFactor define cuanto amortigua la resiliencia (1.0 = tanque no se mueve nada).
+	relationshipDampeningFactor = 0.5
+	maxBondStatusLength         = 120
+)
+
+// RelationshipService aplica los deltas de vinculo devueltos por el LLM al personaje activo y a la sesion.
+type RelationshipService struct {
+	characterRepo repository.CharacterRepository
+	sessionRepo   repository.SessionRepository
+	eventRepo     repository.RelationshipEventRepository
+}
+
+// RelationshipUpdate describe el cambio aplicado a un vinculo en un turno.
+type RelationshipUpdate struct {
+	CharacterID uuid.UUID
+	Before      domain.RelationshipVectors
+	After       domain.RelationshipVectors
+	BondStatus  string
+}
+
+var ErrRelationshipServiceNotConfigured = errors.New("relationship service not configured")
+
+func NewRelationshipService(
+	characterRepo repository.CharacterRepository,
+	sessionRepo repository.SessionRepository,
+	eventRepo repository.RelationshipEventRepository,
+) *RelationshipService {
+	return &RelationshipService{
+		characterRepo: characterRepo,
+		sessionRepo:   sessionRepo,
+		eventRepo:     eventRepo,
+	}
+}
+
+// ApplyTurn amortigua los deltas segun la resiliencia del clon y los persiste.
+// El personaje es opcional (si no hay vinculo activo solo se actualiza la sesion) y la sesion tambien.
+// messageID identifica el mensaje del clon que provoco el cambio (para el timeline).
+func (s *RelationshipService) ApplyTurn(
+	ctx context.Context,
+	profile *domain.CloneProfile,
+	character *domain.Character,
+	sessionID, messageID string,
+	resp domain.LLMResponse,
+) (*RelationshipUpdate, error) {
+	if s == nil || s.characterRepo == nil {
+		return nil, ErrRelationshipServiceNotConfigured
+	}
+
+	resilience := 0.5
+	if profile != nil {
+		resilience = profile.GetResilience()
+	}
+	trust := dampenRelationshipDelta(resp.TrustDelta, resilience)
+	intimacy := dampenRelationshipDelta(resp.IntimacyDelta, resilience)
+	respect := dampenRelationshipDelta(resp.RespectDelta, resilience)
+	newState := truncateBondStatus(resp.NewState)
+
+	hasDelta := trust != 0 || intimacy != 0 || respect != 0
+	if !hasDelta && newState == "" {
+		return nil, nil
+	}
+
+	var update *RelationshipUpdate
+	if character != nil && character.ID != uuid.Nil {
+		before := character.Relationship
+		after := applyRelationshipDelta(before, trust, intimacy, respect)
+
+		updated := *character
+		updated.Relationship = after
+		if newState != "" {
+			updated.BondStatus = newState
+		}
+		updated.UpdatedAt = time.Now().UTC()
+
+		if err := s.characterRepo.Update(ctx, updated); err != nil {
+			return nil, fmt.Errorf("update character relationship: %w", err)
+		}
+		*character = updated
+		update = &RelationshipUpdate{
+			CharacterID: updated.ID,
+			Before:      before,
+			After:       after,
+			BondStatus:  updated.BondStatus,
+		}
+
+		if err := s.RecordEvent(ctx, updated, before, domain.RelationshipCauseLLMDelta, messageID); err != nil {
+			return update, err
+		}
+	}
+
+	sessionID = strings.TrimSpace(sessionID)
+	if s.sessionRepo != nil && sessionID != "" && hasDelta {
+		session, err := s.sessionRepo.GetByID(ctx, sessionID)
+		if err != nil {
+			return update, fmt.Errorf("get session: %w", err)
+		}
+		rel := applyRelationshipDelta(session.Relationship, trust, intimacy, respect)
+		if err := s.sessionRepo.UpdateRelationship(ctx, sessionID, rel); err != nil {
+			return update, fmt.Errorf("update session relationship: %w", err)
+		}
+	}
+
+	return update, nil
+}
+
+// RecordEvent guarda en el timeline el paso de before a los vectores actuales del personaje.
+// Es no-op si no hay repositorio de eventos configurado.
+func (s *RelationshipService) RecordEvent(ctx context.Context, character domain.Character, before domain.RelationshipVectors, cause, messageID string) error {
+	if s == nil || s.eventRepo == nil {
+		return nil
+	}
+	after := character.Relationship
+	event := domain.RelationshipEvent{
+		ID:             uuid.New(),
+		CharacterID:    character.ID,
+		CloneProfileID: character.CloneProfileID,
+		MessageID:      strings.TrimSpace(messageID),
+		Cause:          cause,
+		Before:         before,
+		After:          after,
+		BondStatus:     character.BondStatus,
+		Dynamic:        deriveBondDynamics(after.Trust, after.Intimacy, after.Respect),
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		return fmt.Errorf("record relationship event: %w", err)
+	}
+	return nil
+}
+
+// dampenRelationshipDelta recorta el delta crudo y lo atenua segun la resiliencia (0.0-1.0).
+// Un clon resiliente cambia de opinion mas lento; uno fragil reacciona casi completo.
+func dampenRelationshipDelta(delta, resilience float64) int {
+	if math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return 0
+	}
+	if delta > maxRelationshipDeltaPerTurn {
+		delta = maxRelationshipDeltaPerTurn
+	}
+	if delta < -maxRelationshipDeltaPerTurn {
+		delta = -maxRelationshipDeltaPerTurn
+	}
+	if resilience < 0 {
+		resilience = 0
+	}
+	if resilience > 1 {
+		resilience = 1
+	}
+	return int(math.Round(delta * (1.0 - resilience*relationshipDampeningFactor)))
+}
+
+func applyRelationshipDelta(rel domain.RelationshipVectors, trust, intimacy, respect int) domain.RelationshipVectors {
+	return domain.RelationshipVectors{
+		Trust:    clampRelationshipValue(rel.Trust + trust),
+		Intimacy: clampRelationshipValue(rel.Intimacy + intimacy),
+		Respect:  clampRelationshipValue(rel.Respect + respect),
+	}
+}
+
+func clampRelationshipValue(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+func truncateBondStatus(state string) string {
+	state = strings.TrimSpace(state)
+	runes := []rune(state)
+	if len(runes) > maxBondStatusLength {
+		state = strings.TrimSpace(string(runes[:maxBondStatusLength]))
+	}
+	return state
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+)
+
+type relFakeCharacterRepo struct {
+	actionFakeCharacterRepo
+	updated []domain.Character
+	err     error
+}
+
+func (f *relFakeCharacterRepo) Update(_ context.Context, c domain.Character) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.updated = append(f.updated, c)
+	return nil
+}
+
+type relFakeSessionRepo struct {
+	session domain.Session
+	updated *domain.RelationshipVectors
+	idle    []domain.Session
+	closed  map[string]bool
+	// consolidated son las sesiones reclamadas por ClaimConsolidation; released cuenta las liberaciones.
+	consolidated map[string]bool
+	released     int
+}
+
+func (f *relFakeSessionRepo) Create(context.Context, domain.Session) error {
+	return errors.New("not implemented")
+}
+
+func (f *relFakeSessionRepo) GetByID(context.Context, string) (domain.Session, error) {
+	return f.session, nil
+}
+
+func (f *relFakeSessionRepo) ListByUserID(context.Context, string) ([]domain.Session, error) {
+	return []domain.Session{f.session}, nil
+}
+
+func (f *relFakeSessionRepo) UpdateRelationship(_ context.Context, _ string, rel domain.RelationshipVectors) error {
+	f.updated = &rel
+	return nil
+}
+
+func (f *relFakeSessionRepo) Close(_ context.Context, id string, closedAt time.Time) error {
+	if f.closed[id] {
+		return repository.ErrSessionAlreadyClosed
+	}
+	if f.closed == nil {
+		f.closed = map[string]bool{}
+	}
+	f.closed[id] = true
+	f.session.ClosedAt = &closedAt
+	return nil
+}
+
+func (f *relFakeSessionRepo) SetSpeakingAs(_ context.Context, _ string, characterID string) error {
+	f.session.SpeakingAsCharacterID = characterID
+	return nil
+}
+
+func (f *relFakeSessionRepo) ClaimConsolidation(_ context.Context, id string, _ time.Time) (bool, error) {
+	if f.consolidated[id] {
+		return false, nil
+	}
+	if f.consolidated == nil {
+		f.consolidated = map[string]bool{}
+	}
+	f.consolidated[id] = true
+	return true, nil
+}
+
+func (f *relFakeSessionRepo) ReleaseConsolidation(_ context.Context, id string) error {
+	delete(f.consolidated, id)
+	f.released++
+	return nil
+}
+
+func (f *relFakeSessionRepo) ListIdle(context.Context, time.Time, int) ([]domain.Session, error) {
+	return f.idle, nil
+}
+
+type relFakeEventRepo struct {
+	events []domain.RelationshipEvent
+}
+
+func (f *relFakeEventRepo) Create(_ context.Context, e domain.RelationshipEvent) error {
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *relFakeEventRepo) ListByCharacter(context.Context, uuid.UUID, int) ([]domain.RelationshipEvent, error) {
+	return f.events, nil
+}
+
+func TestDampenRelationshipDelta(t *testing.T) {
+	cases := []struct {
+		name       string
+		delta      float64
+		resilience float64
+		want       int
+	}{
+		{"fragil aplica completo", 8, 0, 8},
+		{"tanque aplica la mitad", 8, 1, 4},
+		{"recorta deltas enormes", 90, 0, 10},
+		{"recorta deltas negativos", -90, 0, -10},
+		{"resiliencia fuera de rango", -6, 3, -3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := dampenRelationshipDelta(tc.delta, tc.resilience); got != tc.want {
+				t.Fatalf("dampenRelationshipDelta(%v, %v) = %d, want %d", tc.delta, tc.resilience, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRelationshipServiceApplyTurn_UpdatesCharacterAndSession(t *testing.T) {
+	charRepo := &relFakeCharacterRepo{}
+	sessionRepo := &relFakeSessionRepo{session: domain.Session{ID: "s1", Relationship: domain.RelationshipVectors{Trust: 50, Intimacy: 50, Respect: 98}}}
+	eventRepo := &relFakeEventRepo{}
+	svc := NewRelationshipService(charRepo, sessionRepo, eventRepo)
+
+	// Neuroticismo 100 => resiliencia minima, los deltas pasan sin amortiguar.
+	profile := &domain.CloneProfile{Big5: domain.Big5Profile{Neuroticism: 100}}
+	char := &domain.Character{ID: uuid.New(), Name: "Juan", BondStatus: "estable", Relationship: domain.RelationshipVectors{Trust: 5, Intimacy: 70, Respect: 50}}
+
+	update, err := svc.ApplyTurn(context.Background(), profile, char, "s1", "m1", domain.LLMResponse{
+		TrustDelta:    -8,
+		IntimacyDelta: 4,
+		RespectDelta:  6,
+		NewState:      "  resentido  ",
+	})
+	if err != nil {
+		t.Fatalf("ApplyTurn returned error: %v", err)
+	}
+	if update == nil {
+		t.Fatalf("expected relationship update")
+	}
+
+	want := domain.RelationshipVectors{Trust: 0, Intimacy: 74, Respect: 56}
+	if update.After != want {
+		t.Fatalf("unexpected vectors: got %+v want %+v", update.After, want)
+	}
+	if len(charRepo.updated) != 1 || charRepo.updated[0].BondStatus != "resentido" {
+		t.Fatalf("expected character persisted with new bond status, got %+v", charRepo.updated)
+	}
+	if char.Relationship != want {
+		t.Fatalf("expected in-memory character to reflect update, got %+v", char.Relationship)
+	}
+	if sessionRepo.updated == nil || *sessionRepo.updated != (domain.RelationshipVectors{Trust: 42, Intimacy: 54, Respect: 100}) {
+		t.Fatalf("unexpected session vectors: %+v", sessionRepo.updated)
+	}
+	if len(eventRepo.events) != 1 {
+		t.Fatalf("expected one relationship event, got %d", len(eventRepo.events))
+	}
+	ev := eventRepo.events[0]
+	if ev.Cause != domain.RelationshipCauseLLMDelta || ev.MessageID != "m1" || ev.Before.Trust != 5 || ev.After != want {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	if !strings.Contains(ev.Dynamic, "CELOS PATOLOGICOS") {
+		t.Fatalf("expected dynamic derived from final vectors, got %q", ev.Dynamic)
+	}
+}
+
+func TestRelationshipServiceApplyTurn_NoDeltaIsNoop(t *testing.T) {
+	charRepo := &relFakeCharacterRepo{}
+	sessionRepo := &relFakeSessionRepo{}
+	svc := NewRelationshipService(charRepo, sessionRepo, nil)
+
+	update, err := svc.ApplyTurn(context.Background(), nil, &domain.Character{ID: uuid.New()}, "s1", "", domain.LLMResponse{TrustDelta: 0.2})
+	if err != nil || update != nil {
+		t.Fatalf("expected noop, got update=%+v err=%v", update, err)
+	}
+	if len(charRepo.updated) != 0 || sessionRepo.updated != nil {
+		t.Fatalf("expected no writes on noop turn")
+	}
+}
+
+func TestRelationshipServiceApplyTurn_PropagatesCharacterError(t *testing.T) {
+	charRepo := &relFakeCharacterRepo{err: errors.New("db down")}
+	svc := NewRelationshipService(charRepo, nil, nil)
+
+	_, err := svc.ApplyTurn(context.Background(), nil, &domain.Character{ID: uuid.New()}, "", "", domain.LLMResponse{TrustDelta: 5})
+	if err == nil {
+		t.Fatalf("expected error when character update fails")
+	}
+
+	var nilSvc *RelationshipService
+	if _, err := nilSvc.ApplyTurn(context.Background(), nil, nil, "", "", domain.LLMResponse{}); !errors.Is(err, ErrRelationshipServiceNotConfigured) {
+		t.Fatalf("expected ErrRelationshipServiceNotConfigured, got %v", err)
+	}
+}
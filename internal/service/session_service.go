@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/repository"
+)
+
+// Motivos con los que se invocan los hooks de fin de sesion.
+const (
+	SessionEndReasonClosed  = "closed"
+	SessionEndReasonExpired = "expired"
+	SessionEndReasonIdle    = "idle"
+)
+
+// idleSweepBatch limita cuantas sesiones inactivas se cierran por pasada.
+const idleSweepBatch = 50
+
+var (
+	ErrSessionServiceNotConfigured = errors.New("session service not configured")
+	ErrSessionClosed               = errors.New("session closed")
+	ErrSessionExpired              = errors.New("session expired")
+)
+
+// SessionEndHook se ejecuta una vez cuando una sesion termina (cierre explicito o expiracion detectada).
+// Corre dentro del request que provoco el cierre: si el trabajo es pesado (p.ej. resumir), debe lanzarlo en background.
+type SessionEndHook func(ctx context.Context, session domain.Session, reason string) error
+
+// SessionService centraliza el ciclo de vida de las sesiones: expiracion, cierre y hooks de fin.
+type SessionService struct {
+	repo   repository.SessionRepository
+	logger *zap.Logger
+	hooks  []SessionEndHook
+	now    func() time.Time
+}
+
+func NewSessionService(repo repository.SessionRepository, logger *zap.Logger) *SessionService {
+	return &SessionService{
+		repo:   repo,
+		logger: logger,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// OnSessionEnd registra un hook de fin de sesion. Los hooks se ejecutan en orden de registro.
+func (s *SessionService) OnSessionEnd(hook SessionEndHook) {
+	if s == nil || hook == nil {
+		return
+	}
+	s.hooks = append(s.hooks, hook)
+}
+
+// EnsureActive devuelve nil si la sesion acepta mensajes. Si esta vencida y aun no fue cerrada,
+// la cierra y dispara los hooks con motivo "expired" antes de devolver ErrSessionExpired; los
+// mensajes posteriores siguen recibiendo ErrSessionExpired.
+func (s *SessionService) EnsureActive(ctx context.Context, session *domain.Session) error {
+	if s == nil || s.repo == nil {
+		return ErrSessionServiceNotConfigured
+	}
+	if session == nil {
+		return ErrSessionClosed
+	}
+
+	switch session.Status(s.now()) {
+	case domain.SessionStatusClosed:
+		return ErrSessionClosed
+	case domain.SessionStatusExpired:
+		if session.ClosedAt != nil {
+			return ErrSessionExpired
+		}
+		if err := s.end(ctx, session, SessionEndReasonExpired); err != nil && !errors.Is(err, ErrSessionClosed) {
+			return err
+		}
+		return ErrSessionExpired
+	default:
+		return nil
+	}
+}
+
+// Close cierra la sesion explicitamente y dispara los hooks con motivo "closed".
+// Devuelve ErrSessionClosed si ya estaba cerrada.
+func (s *SessionService) Close(ctx context.Context, session *domain.Session) error {
+	if s == nil || s.repo == nil {
+		return ErrSessionServiceNotConfigured
+	}
+	if session == nil || session.ClosedAt != nil {
+		return ErrSessionClosed
+	}
+	return s.end(ctx, session, SessionEndReasonClosed)
+}
+
+// CloseIdle cierra las sesiones abiertas sin actividad desde hace idleFor y dispara sus hooks
+// (motivo "idle", o "expired" si ademas ya vencieron). Devuelve cuantas cerro.
+func (s *SessionService) CloseIdle(ctx context.Context, idleFor time.Duration) (int, error) {
+	if s == nil || s.repo == nil {
+		return 0, ErrSessionServiceNotConfigured
+	}
+	if idleFor <= 0 {
+		return 0, nil
+	}
+
+	now := s.now()
+	sessions, err := s.repo.ListIdle(ctx, now.Add(-idleFor), idleSweepBatch)
+	if err != nil {
+		return 0, fmt.Errorf("list idle sessions: %w", err)
+	}
+
+	closed := 0
+	for i := range sessions {
+		session := &sessions[i]
+		reason := SessionEndReasonIdle
+		if session.Status(now) == domain.SessionStatusExpired {
+			reason = SessionEndReasonExpired
+		}
+		if err := s.end(ctx, session, reason); err != nil {
+			if errors.Is(err, ErrSessionClosed) {
+				// Se cerro entre el listado y ahora; sus hooks ya corrieron en el otro cierre.
+				continue
+			}
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// RunIdleSweeper ejecuta CloseIdle cada interval hasta que se cancele ctx.
+func (s *SessionService) RunIdleSweeper(ctx context.Context, idleFor, interval time.Duration) {
+	if s == nil || idleFor <= 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.CloseIdle(ctx, idleFor); err != nil && s.logger != nil {
+				s.logger.Warn("idle session sweep failed", zap.Error(err))
+			} else if n > 0 && s.logger != nil {
+				s.logger.Info("idle sessions closed", zap.Int("count", n))
+			}
+		}
+	}
+}
+
+// end cierra la sesion y dispara los hooks solo si este llamado fue el que la cerro; si otro cierre
+// se adelanto devuelve ErrSessionClosed sin ejecutar hooks.
+func (s *SessionService) end(ctx context.Context, session *domain.Session, reason string) error {
+	closedAt := s.now()
+	if err := s.repo.Close(ctx, session.ID, closedAt); err != nil {
+		if errors.Is(err, repository.ErrSessionAlreadyClosed) {
+			return ErrSessionClosed
+		}
+		return fmt.Errorf("close session: %w", err)
+	}
+	session.ClosedAt = &closedAt
+
+	for _, hook := range s.hooks {
+		if err := hook(ctx, *session, reason); err != nil && s.logger != nil {
+			// Un hook fallido no revierte el cierre ni bloquea a los siguientes.
+			s.logger.Warn("session end hook failed", zap.Error(err), zap.String("session_id", session.ID), zap.String("reason", reason))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+)
+
+func TestSessionServiceEnsureActive_ExpiresAndFiresHookOnce(t *testing.T) {
+	repo := &relFakeSessionRepo{}
+	svc := NewSessionService(repo, zap.NewNop())
+	var reasons []string
+	svc.OnSessionEnd(func(_ context.Context, s domain.Session, reason string) error {
+		reasons = append(reasons, reason)
+		return nil
+	})
+
+	session := &domain.Session{ID: "s1", ExpiresAt: time.Now().UTC().Add(-time.Minute)}
+	if err := svc.EnsureActive(context.Background(), session); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired, got %v", err)
+	}
+	if session.ClosedAt == nil || repo.session.ClosedAt == nil {
+		t.Fatalf("expected expired session to be closed")
+	}
+	if err := svc.EnsureActive(context.Background(), session); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired on second check, got %v", err)
+	}
+	if got := session.Status(time.Now().UTC()); got != domain.SessionStatusExpired {
+		t.Fatalf("expected closed-on-expiry session to report %q, got %q", domain.SessionStatusExpired, got)
+	}
+	if len(reasons) != 1 || reasons[0] != SessionEndReasonExpired {
+		t.Fatalf("expected a single expired hook call, got %v", reasons)
+	}
+}
+
+func TestSessionServiceClose_HookErrorDoesNotBlockClose(t *testing.T) {
+	svc := NewSessionService(&relFakeSessionRepo{}, zap.NewNop())
+	calls := 0
+	svc.OnSessionEnd(func(context.Context, domain.Session, string) error {
+		calls++
+		return errors.New("summarizer down")
+	})
+	svc.OnSessionEnd(func(_ context.Context, _ domain.Session, reason string) error {
+		calls++
+		if reason != SessionEndReasonClosed {
+			t.Fatalf("unexpected reason %q", reason)
+		}
+		return nil
+	})
+
+	session := &domain.Session{ID: "s1", ExpiresAt: time.Now().UTC().Add(time.Hour)}
+	if err := svc.EnsureActive(context.Background(), session); err != nil {
+		t.Fatalf("expected active session, got %v", err)
+	}
+	if err := svc.Close(context.Background(), session); err != nil {
+		t.Fatalf("expected close to succeed, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected both hooks to run, got %d", calls)
+	}
+	if err := svc.Close(context.Background(), session); !errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("expected ErrSessionClosed when closing twice, got %v", err)
+	}
+}
+
+func TestSessionService_ConcurrentCloseFiresHooksOnce(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &relFakeSessionRepo{idle: []domain.Session{{ID: "s1", ExpiresAt: now.Add(time.Hour)}}}
+	svc := NewSessionService(repo, zap.NewNop())
+	calls := 0
+	svc.OnSessionEnd(func(context.Context, domain.Session, string) error {
+		calls++
+		return nil
+	})
+
+	// El usuario cierra la sesion con una copia cargada antes de que el barrido la cerrara.
+	stale := &domain.Session{ID: "s1", ExpiresAt: now.Add(time.Hour)}
+	if n, err := svc.CloseIdle(context.Background(), 30*time.Minute); err != nil || n != 1 {
+		t.Fatalf("expected sweeper to close the session, got %d (%v)", n, err)
+	}
+	if err := svc.Close(context.Background(), stale); !errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("expected ErrSessionClosed for the losing close, got %v", err)
+	}
+	if n, err := svc.CloseIdle(context.Background(), 30*time.Minute); err != nil || n != 0 {
+		t.Fatalf("expected already closed session to be skipped, got %d (%v)", n, err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected end hooks to run once, got %d", calls)
+	}
+}
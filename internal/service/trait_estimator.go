@@ -0,0 +1,83 @@
+package service
+
+import (
+	"math"
+
+	"clone-llm/internal/domain"
+)
+
+// defaultObservationConfidence se usa para ponderar observaciones que llegan sin confianza.
+const defaultObservationConfidence = 0.5
+
+// TraitEstimator combina observaciones sucesivas de un rasgo en una estimacion acumulada.
+// En lugar de pisar el valor con cada analisis, mueve la estimacion hacia la nueva observacion
+// con un paso proporcional a su confianza y limitado por la estabilidad del perfil.
+type TraitEstimator struct {
+	stability float64
+}
+
+// NewTraitEstimator crea un estimador con la estabilidad dada (0.0-1.0).
+// Valores fuera de rango caen al default del dominio.
+func NewTraitEstimator(stability float64) TraitEstimator {
+	if math.IsNaN(stability) || stability < 0 || stability > 1 {
+		stability = domain.DefaultTraitStability
+	}
+	return TraitEstimator{stability: stability}
+}
+
+// Estimate devuelve el rasgo actualizado tras incorporar la observacion (value, confidence).
+// Si current es nil, la observacion se toma tal cual como primera estimacion.
+func (e TraitEstimator) Estimate(current *domain.Trait, value int, confidence *float64) domain.Trait {
+	value = clampTraitValue(value)
+	q := observationWeight(confidence)
+
+	if current == nil {
+		return domain.Trait{
+			Value:            value,
+			Confidence:       confidence,
+			ObservationCount: 1,
+		}
+	}
+
+	next := *current
+	n := current.ObservationCount
+	if n <= 0 {
+		n = 1
+	}
+
+	// Media incremental ponderada por confianza, con un piso de reactividad que baja con la estabilidad:
+	// un perfil estable (1.0) converge como promedio; uno libre (0.0) sigue la ultima observacion.
+	alpha := math.Max(q/float64(n+1), q*(1-e.stability))
+	estimate := float64(current.Value) + alpha*float64(value-current.Value)
+	next.Value = clampTraitValue(int(math.Round(estimate)))
+
+	prevConfidence := observationWeight(current.Confidence)
+	aggregated := (prevConfidence*float64(n) + q) / float64(n+1)
+	next.Confidence = &aggregated
+	next.ObservationCount = n + 1
+	return next
+}
+
+func observationWeight(confidence *float64) float64 {
+	if confidence == nil {
+		return defaultObservationConfidence
+	}
+	c := *confidence
+	if math.IsNaN(c) || c < 0 {
+		return 0
+	}
+	if c > 1 {
+		return 1
+	}
+	return c
+}
+
+func clampTraitValue(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"clone-llm/internal/domain"
+	"clone-llm/internal/llm"
+)
+
+func TestTraitEstimator_FirstObservationIsTakenAsIs(t *testing.T) {
+	est := NewTraitEstimator(domain.DefaultTraitStability)
+	got := est.Estimate(nil, 130, floatPtr(0.9))
+	if got.Value != 100 || got.ObservationCount != 1 || got.Confidence == nil || *got.Confidence != 0.9 {
+		t.Fatalf("unexpected first estimate: %+v", got)
+	}
+}
+
+func TestTraitEstimator_StableProfileResistsOutliers(t *testing.T) {
+	current := &domain.Trait{Value: 80, Confidence: floatPtr(0.9), ObservationCount: 9}
+
+	stable := NewTraitEstimator(1).Estimate(current, 20, floatPtr(0.9))
+	// alpha = 0.9/10 = 0.09 => 80 + 0.09*(-60) = 74.6
+	if stable.Value != 75 {
+		t.Fatalf("expected stable profile to barely move, got %d", stable.Value)
+	}
+	if stable.ObservationCount != 10 {
+		t.Fatalf("expected observation count 10, got %d", stable.ObservationCount)
+	}
+
+	free := NewTraitEstimator(0).Estimate(current, 20, floatPtr(0.9))
+	// alpha = 0.9 => 80 + 0.9*(-60) = 26
+	if free.Value != 26 {
+		t.Fatalf("expected free profile to follow the observation, got %d", free.Value)
+	}
+}
+
+func TestTraitEstimator_LowConfidenceMovesLess(t *testing.T) {
+	est := NewTraitEstimator(domain.DefaultTraitStability)
+	current := &domain.Trait{Value: 50, Confidence: floatPtr(0.8), ObservationCount: 1}
+
+	high := est.Estimate(current, 90, floatPtr(1))
+	low := est.Estimate(current, 90, floatPtr(0.1))
+	if !(high.Value > low.Value && low.Value > 50) {
+		t.Fatalf("expected confidence to scale the step: high=%d low=%d", high.Value, low.Value)
+	}
+	// Confianza agregada: (0.8*1 + 0.1) / 2 = 0.45
+	if low.Confidence == nil || *low.Confidence < 0.449 || *low.Confidence > 0.451 {
+		t.Fatalf("unexpected aggregated confidence: %v", low.Confidence)
+	}
+}
+
+func TestAnalysisServiceBlendsWithExistingTrait(t *testing.T) {
+	llmClient := &llm.MockClient{
+		Response: `{"traits": [{"trait": "extraversion", "value": 10, "confidence": 0.5}]}`,
+	}
+	profileRepo := &mockProfileRepo{profile: domain.CloneProfile{ID: "profile-5", TraitStability: 1}}
+	traitRepo := &mockTraitRepo{existing: []domain.Trait{{
+		ID:               "trait-1",
+		ProfileID:        "profile-5",
+		Category:         domain.TraitCategoryBigFive,
+		Trait:            "extraversion",
+		Value:            90,
+		Confidence:       floatPtr(0.9),
+		ObservationCount: 4,
+	}}}
+
+	svc := NewAnalysisService(llmClient, traitRepo, profileRepo, zap.NewNop())
+	if err := svc.AnalyzeAndPersist(context.Background(), "user-5", "hoy no tengo ganas de ver a nadie"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// alpha = 0.5/5 = 0.1 => 90 + 0.1*(-80) = 82
+	if traitRepo.lastTrait.Value != 82 || traitRepo.lastTrait.ObservationCount != 5 || traitRepo.lastTrait.ID != "trait-1" {
+		t.Fatalf("expected blended estimate, got %+v", traitRepo.lastTrait)
+	}
+	if len(traitRepo.observations) != 1 {
+		t.Fatalf("expected one observation stored, got %d", len(traitRepo.observations))
+	}
+	obs := traitRepo.observations[0]
+	if obs.Value != 10 || obs.EstimateAfter != 82 {
+		t.Fatalf("unexpected observation: %+v", obs)
+	}
+}